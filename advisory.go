@@ -0,0 +1,211 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// TMDB release types, see https://developer.themoviedb.org/reference/movie-release-dates
+const (
+	releasePremiere = iota + 1
+	releaseTheatricalLimited
+	releaseTheatrical
+	releaseDigital
+	releasePhysical
+	releaseTV
+)
+
+// ReleaseDatesResponse holds a movie's releases grouped by country.
+type ReleaseDatesResponse struct {
+	Results []CountryReleases `json:"results"`
+}
+
+// CountryReleases lists a movie's releases in one country.
+type CountryReleases struct {
+	Country      string           `json:"iso_3166_1"`
+	ReleaseDates []ReleaseDateRow `json:"release_dates"`
+}
+
+// ReleaseDateRow is a single release, with its certification if it has one.
+type ReleaseDateRow struct {
+	Certification string `json:"certification"`
+	ReleaseDate   string `json:"release_date"`
+	Type          int    `json:"type"`
+	Note          string `json:"note"`
+}
+
+// KeywordsResponse holds the keywords TMDB tags a movie with.
+type KeywordsResponse struct {
+	Keywords []Keyword `json:"keywords"`
+}
+
+// Keyword is a single TMDB keyword.
+type Keyword struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ContentAdvisory summarises what parents may want to know about a movie.
+type ContentAdvisory struct {
+	Region        string
+	Certification string
+	Runtime       int
+	Advisories    []string
+}
+
+// keywordAdvisories maps TMDB keywords to the advisory shown for them. The
+// mapping is a heuristic: add a row (or keywords to an existing row) to
+// extend it. Keywords are matched case-insensitively.
+var keywordAdvisories = []struct {
+	Advisory string
+	Keywords []string
+}{
+	{"Graphic violence or gore", []string{"gore", "splatter", "blood", "dismemberment", "torture", "brutality"}},
+	{"Drug use", []string{"drug abuse", "drug addiction", "drugs", "cocaine", "heroin", "overdose", "drug dealer"}},
+	{"Sexual content", []string{"sex", "sexuality", "nudity", "erotic", "sex scene"}},
+	{"Sexual violence", []string{"rape", "sexual abuse", "sexual assault"}},
+	{"Self-harm or suicide", []string{"suicide", "self-harm", "suicide attempt"}},
+	{"Animal harm", []string{"animal cruelty", "animal abuse", "death of a pet"}},
+	{"Strong language", []string{"profanity", "swearing"}},
+	{"Frightening scenes", []string{"jump scare", "body horror", "possession", "demonic possession"}},
+}
+
+// buildContentAdvisory combines the certification for a region, the runtime
+// and keyword-derived advisories. Missing inputs are simply skipped; nil is
+// returned when there is nothing at all to show.
+func buildContentAdvisory(region string, runtime int, releases *ReleaseDatesResponse, keywords *KeywordsResponse) *ContentAdvisory {
+	advisory := &ContentAdvisory{Region: region, Runtime: runtime}
+	if releases != nil {
+		advisory.Certification = releases.Certification(region)
+	}
+	if keywords != nil {
+		advisory.Advisories = keywordAdvisoriesFor(keywords.Keywords)
+	}
+
+	if advisory.Certification == "" && advisory.Runtime == 0 && len(advisory.Advisories) == 0 {
+		return nil
+	}
+	return advisory
+}
+
+// keywordAdvisoriesFor returns the advisories triggered by the keywords, in
+// table order and without duplicates.
+func keywordAdvisoriesFor(keywords []Keyword) []string {
+	names := make(map[string]bool, len(keywords))
+	for _, k := range keywords {
+		names[strings.ToLower(k.Name)] = true
+	}
+
+	var advisories []string
+	for _, row := range keywordAdvisories {
+		for _, k := range row.Keywords {
+			if names[k] {
+				advisories = append(advisories, row.Advisory)
+				break
+			}
+		}
+	}
+	return advisories
+}
+
+// Certification returns the movie's rating in a country, preferring the
+// theatrical release's certification over other release types. It is safe
+// to call on a nil response.
+func (r *ReleaseDatesResponse) Certification(country string) string {
+	if r == nil {
+		return ""
+	}
+	var fallback string
+	for _, c := range r.Results {
+		if c.Country != country {
+			continue
+		}
+		for _, rd := range c.ReleaseDates {
+			if rd.Certification == "" {
+				continue
+			}
+			if rd.Type == releaseTheatrical {
+				return rd.Certification
+			}
+			if fallback == "" {
+				fallback = rd.Certification
+			}
+		}
+	}
+	return fallback
+}
+
+// releaseTypeLabels names TMDB's release types for the release timeline.
+var releaseTypeLabels = map[int]string{
+	releasePremiere:          "Premiere",
+	releaseTheatricalLimited: "Theatrical (limited)",
+	releaseTheatrical:        "Theatrical",
+	releaseDigital:           "Digital",
+	releasePhysical:          "Physical",
+	releaseTV:                "TV",
+}
+
+// timelineFallbackRegion is used when TMDB has no release dates for the
+// configured region.
+const timelineFallbackRegion = "US"
+
+// ReleaseTimeline is a movie's releases in one region, oldest first.
+type ReleaseTimeline struct {
+	Region    string // region the events are for
+	Requested string // configured region, differs from Region after a fallback
+	Fallback  bool
+	Events    []ReleaseEvent
+}
+
+// ReleaseEvent is one entry of a ReleaseTimeline.
+type ReleaseEvent struct {
+	Date          time.Time
+	Label         string
+	Certification string
+	Note          string
+}
+
+// buildReleaseTimeline lists the releases for region, falling back to the
+// US when the region has none. Rows with unparseable dates are skipped. It
+// returns nil when neither region has any releases.
+func buildReleaseTimeline(releases *ReleaseDatesResponse, region string) *ReleaseTimeline {
+	if releases == nil {
+		return nil
+	}
+
+	timeline := &ReleaseTimeline{Region: region, Requested: region}
+	timeline.Events = releases.events(region)
+	if len(timeline.Events) == 0 && region != timelineFallbackRegion {
+		timeline.Region = timelineFallbackRegion
+		timeline.Fallback = true
+		timeline.Events = releases.events(timelineFallbackRegion)
+	}
+	if len(timeline.Events) == 0 {
+		return nil
+	}
+	return timeline
+}
+
+// events returns the dated releases for a country, sorted by date.
+func (r *ReleaseDatesResponse) events(country string) []ReleaseEvent {
+	var events []ReleaseEvent
+	for _, c := range r.Results {
+		if c.Country != country {
+			continue
+		}
+		for _, rd := range c.ReleaseDates {
+			date, err := time.Parse(time.RFC3339, rd.ReleaseDate)
+			if err != nil {
+				continue
+			}
+			label, ok := releaseTypeLabels[rd.Type]
+			if !ok {
+				label = "Release"
+			}
+			events = append(events, ReleaseEvent{Date: date, Label: label, Certification: rd.Certification, Note: rd.Note})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Date.Before(events[j].Date) })
+	return events
+}
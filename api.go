@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiSearchResults is the /api/search response body.
+type apiSearchResults struct {
+	Results []apiMovie `json:"results"`
+}
+
+// apiMovie is a search result with the release year next to the full
+// release_date; year is "" when TMDB doesn't know the date.
+type apiMovie struct {
+	Movie
+	Year string `json:"year"`
+}
+
+// apiSearchHandler serves GET /api/search?query=...&include_adult=true|false.
+//
+// Whether adult titles are included is decided in this order:
+//  1. ADULT_CONTENT_LOCKED=true always excludes them, whatever the request says.
+//  2. Otherwise the request's include_adult parameter wins when present.
+//  3. Otherwise the server default INCLUDE_ADULT applies.
+func apiSearchHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	if rejectDuplicateAPIParams(w, r, "query", "include_adult") {
+		return
+	}
+	r, cancel := withDeadline(r, config.SearchTimeout)
+	defer cancel()
+	query := strings.TrimSpace(r.URL.Query().Get("query"))
+	if query == "" {
+		writeAPIError(w, http.StatusBadRequest, apiBadRequest, "Missing query parameter")
+		return
+	}
+
+	includeAdult := config.IncludeAdult
+	// Only the two documented spellings, not everything strconv.ParseBool
+	// takes, so clients can't come to rely on "1" or "T".
+	switch r.URL.Query().Get("include_adult") {
+	case "":
+	case "true":
+		includeAdult = true
+	case "false":
+		includeAdult = false
+	default:
+		writeAPIError(w, http.StatusBadRequest, apiBadRequest, "include_adult must be true or false")
+		return
+	}
+	if config.AdultContentLocked {
+		includeAdult = false
+	}
+
+	start := time.Now()
+	results, err := searchMovies(r.Context(), query, config.TMDB, includeAdult)
+	RecordTiming(r.Context(), "tmdb_search", start)
+	if err != nil {
+		log.Printf("Error searching movies: %v", err)
+		tmdbAPIFailure(w, err, "Failed to search movies")
+		return
+	}
+
+	if config.ExcludeVideos {
+		*results = results.FilterBy(notVideo)
+	}
+	movies := results.Results
+
+	w.Header().Set("Content-Type", "application/json")
+	setCacheControl(w, config, apiResponse)
+	setSurrogateKeys(w, moviesSurrogateKeys(searchSurrogateKey(query), movies)...)
+	response := apiSearchResults{Results: make([]apiMovie, len(movies))}
+	for i, movie := range movies {
+		response.Results[i] = apiMovie{Movie: movie, Year: movie.ReleaseDate.Year()}
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding search results: %v", err)
+	}
+}
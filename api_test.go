@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAPISearchIncludeAdult(t *testing.T) {
+	tests := []struct {
+		param  string // the include_adult value, "" to leave it out
+		locked bool
+		status int
+		want   string // include_adult sent to TMDB
+	}{
+		{"", false, http.StatusOK, "false"},
+		{"true", false, http.StatusOK, "true"},
+		{"false", false, http.StatusOK, "false"},
+		{"true", true, http.StatusOK, "false"},
+		{"1", false, http.StatusBadRequest, ""},
+		{"0", false, http.StatusBadRequest, ""},
+		{"t", false, http.StatusBadRequest, ""},
+		{"TRUE", false, http.StatusBadRequest, ""},
+		{"True", false, http.StatusBadRequest, ""},
+		{"yes", false, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q locked=%t", tt.param, tt.locked), func(t *testing.T) {
+			if tt.locked {
+				t.Setenv("ADULT_CONTENT_LOCKED", "true")
+			}
+			fake := newFixtureTMDB(t)
+			app := newTestApp(t, fake)
+
+			target := "/api/search?query=matrix"
+			if tt.param != "" {
+				target += "&include_adult=" + tt.param
+			}
+			rec := get(app, target)
+			if rec.Code != tt.status {
+				t.Fatalf("%s: status %d, want %d", target, rec.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				var body apiErrorBody
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error.Code != apiBadRequest {
+					t.Errorf("%s: error code %q (%v), want %q", target, body.Error.Code, err, apiBadRequest)
+				}
+				if n := fake.calls("/search/movie"); n != 0 {
+					t.Errorf("%s: searched TMDB %d times", target, n)
+				}
+				return
+			}
+			if got := fake.query("/search/movie").Get("include_adult"); got != tt.want {
+				t.Errorf("%s (locked %t): include_adult=%q sent to TMDB, want %q", target, tt.locked, got, tt.want)
+			}
+		})
+	}
+}
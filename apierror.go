@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// Error codes of the JSON endpoints, stable for clients to match on.
+const (
+	apiBadRequest    = "bad_request"
+	apiNotFound      = "not_found"
+	apiInternalError = "internal_error"
+	apiUnauthorized  = "unauthorized"
+	apiPurgeFailed   = "purge_failed"
+)
+
+// apiErrorBody is the error envelope every JSON endpoint answers with:
+// {"error": {"code": "not_found", "message": "...", "request_id": "..."}}.
+type apiErrorBody struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeAPIError is http.Error for the JSON endpoints. The request ID is the
+// one RequestIDMiddleware already put on the response.
+func writeAPIError(w http.ResponseWriter, status int, code string, message string) {
+	body := apiErrorBody{Error: apiErrorDetail{Code: code, Message: message, RequestID: w.Header().Get("X-Request-ID")}}
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error encoding API error: %v", err)
+	}
+}
+
+// rejectDuplicateAPIParams is rejectDuplicateParams for the JSON endpoints.
+func rejectDuplicateAPIParams(w http.ResponseWriter, r *http.Request, names ...string) bool {
+	if name := duplicateParam(r, names...); name != "" {
+		writeAPIError(w, http.StatusBadRequest, apiBadRequest, fmt.Sprintf("Conflicting values for the %s parameter", name))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Badge names accepted in DETAIL_BADGES.
+const (
+	badgeCertification = "certification"
+	badgeLanguages     = "languages"
+	badgeTrailer       = "trailer"
+	badgeVideo         = "video"
+)
+
+// defaultDetailBadges is the badge row shown when DETAIL_BADGES is unset,
+// in display order.
+var defaultDetailBadges = []string{badgeCertification, badgeLanguages, badgeTrailer, badgeVideo}
+
+// SpokenLanguage is a language spoken in a movie.
+type SpokenLanguage struct {
+	ISO6391     string `json:"iso_639_1"`
+	EnglishName string `json:"english_name"`
+	Name        string `json:"name"`
+}
+
+// VideosResponse lists the trailers, teasers and clips TMDB has for a movie.
+type VideosResponse struct {
+	Results []Video `json:"results"`
+}
+
+// Video is a single trailer, teaser or clip hosted on an external site.
+type Video struct {
+	Key      string `json:"key"`
+	Site     string `json:"site"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Official bool   `json:"official"`
+}
+
+// Badge is a short label in the row under a movie's title. Title is an
+// optional tooltip with the details.
+type Badge struct {
+	Label string
+	Title string
+}
+
+// parseDetailBadges reads a comma-separated DETAIL_BADGES value. An empty
+// value means the default set and "none" turns the row off.
+func parseDetailBadges(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return defaultDetailBadges, nil
+	}
+	if strings.EqualFold(raw, "none") {
+		return nil, nil
+	}
+
+	var badges []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case badgeCertification, badgeLanguages, badgeTrailer, badgeVideo:
+			badges = append(badges, name)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown badge %q", name)
+		}
+	}
+	return badges, nil
+}
+
+// buildBadges returns the enabled badges in order, skipping any whose data
+// is missing. The certification and trailer come from the release_dates and
+// videos appended to the movie.
+func buildBadges(enabled []string, movie *MovieDetail, region string) []Badge {
+	var badges []Badge
+	for _, name := range enabled {
+		switch name {
+		case badgeCertification:
+			if certification := movie.ReleaseDates.Certification(region); certification != "" {
+				badges = append(badges, Badge{Label: certification, Title: "Rated " + certification + " in " + region})
+			}
+		case badgeLanguages:
+			if n := len(movie.SpokenLanguages); n > 0 {
+				badges = append(badges, Badge{Label: languageCount(n), Title: strings.Join(movie.languageNames(), ", ")})
+			}
+		case badgeTrailer:
+			if movie.Videos.HasTrailer() {
+				badges = append(badges, Badge{Label: "Trailer"})
+			}
+		case badgeVideo:
+			if movie.Video {
+				badges = append(badges, Badge{Label: "Video release", Title: "Released straight to video"})
+			}
+		}
+	}
+	return badges
+}
+
+func languageCount(n int) string {
+	if n == 1 {
+		return "1 language"
+	}
+	return fmt.Sprintf("%d languages", n)
+}
+
+// languageNames lists the spoken languages by their English names, falling
+// back to the native name or the language code.
+func (m *MovieDetail) languageNames() []string {
+	names := make([]string, 0, len(m.SpokenLanguages))
+	for _, l := range m.SpokenLanguages {
+		switch {
+		case l.EnglishName != "":
+			names = append(names, l.EnglishName)
+		case l.Name != "":
+			names = append(names, l.Name)
+		default:
+			names = append(names, l.ISO6391)
+		}
+	}
+	return names
+}
+
+// HasTrailer reports whether a trailer is available on YouTube or Vimeo.
+// It is safe to call on a nil response.
+func (v *VideosResponse) HasTrailer() bool {
+	if v == nil {
+		return false
+	}
+	for _, video := range v.Results {
+		if video.Type == "Trailer" && (video.Site == "YouTube" || video.Site == "Vimeo") {
+			return true
+		}
+	}
+	return false
+}
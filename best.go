@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	discoverEndpoint = "/discover/movie"
+
+	// bestMinYear is the earliest year with a "Best of" page.
+	bestMinYear = 1900
+	// bestMinRating is the vote average a movie needs to make the list.
+	bestMinRating = 7.0
+	// bestHomeLinks is how many past years the home page links to.
+	bestHomeLinks = 5
+)
+
+// BestPage is the data rendered by the "Best of {year}" template.
+type BestPage struct {
+	Year        int
+	Movies      []Movie
+	SpoilerFree bool
+	Meta        Meta
+	Theme       string
+	Preload     []string // poster paths of the first movies
+}
+
+var bestTmpl = pageTemplate("best", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>Best of {{.Year}}</title>
+    {{range .Preload}}{{posterPreload . 185}}
+    {{end}}
+</head>
+<body>
+    {{template "header" ""}}
+    <h1>Best of {{.Year}}</h1>
+    <p>The most-voted movies released in {{.Year}} with an average rating of 7 or more.</p>
+    {{block "movies" .}}
+    {{range .Movies}}
+    <article>
+        <a href="{{movieURL .ID .Title}}">{{posterImg .PosterPath 185 ""}}</a>
+        <h2 dir="auto"><a href="{{movieURL .ID .Title}}">{{.Title}}</a></h2>
+        <p>{{printf "%.1f" .VoteAverage}}/10 from {{.VoteCount}} votes</p>
+        {{if $.SpoilerFree}}<details><summary>Show overview (may contain spoilers)</summary><p dir="auto">{{.Overview}}</p></details>{{else}}<p dir="auto">{{.Overview}}</p>{{end}}
+    </article>
+    {{else}}
+    <p>No movies found.</p>
+    {{end}}
+    {{end}}
+    {{template "footer"}}
+</body>
+</html>
+`, `
+{{define "movies"}}
+<ol>
+    {{range .Movies}}
+    <li>
+        <a href="{{movieURL .ID .Title}}" dir="auto">{{.Title}}</a>, rated {{printf "%.1f" .VoteAverage}}/10 from {{.VoteCount}} votes
+        {{if $.SpoilerFree}}<details><summary>Show overview (may contain spoilers)</summary><p dir="auto">{{.Overview}}</p></details>{{else}}<p dir="auto">{{.Overview}}</p>{{end}}
+    </li>
+    {{else}}
+    <li>No movies found.</li>
+    {{end}}
+</ol>
+{{end}}
+`)
+
+// bestHandler serves GET /best/{year}.
+func bestHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	year, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/best/"))
+	if err != nil || year < bestMinYear || year > localNow(config).Year() {
+		http.Error(w, fmt.Sprintf("Year must be between %d and %d", bestMinYear, localNow(config).Year()), http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	results, err := fetchBestOfYear(r.Context(), year, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_discover", start)
+	if err != nil {
+		log.Printf("Error fetching best movies of %d: %v", year, err)
+		tmdbFailure(w, r, err, "Failed to fetch movies")
+		return
+	}
+
+	page, err := render(r, bestTmpl, BestPage{Year: year, Movies: results.Results, SpoilerFree: spoilerFree(r, config.SpoilerFreeDefault), Meta: pageMeta("/best/"), Theme: theme(r), Preload: preloadPosters(results.Results, config.PreloadPosters)})
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, editorialResponse)
+	page.WriteTo(w)
+}
+
+// bestYears lists the years the home page links to, most recent first.
+// The current year is left out until it is over.
+func bestYears(now time.Time) []int {
+	years := make([]int, bestHomeLinks)
+	for i := range years {
+		years[i] = now.Year() - 1 - i
+	}
+	return years
+}
+
+func fetchBestOfYear(ctx context.Context, year int, tmdb *TMDB) (*SearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s&primary_release_year=%d&sort_by=vote_count.desc&vote_average.gte=%.1f",
+		tmdb.BaseURL, discoverEndpoint, tmdb.APIKey, year, bestMinRating)
+	var results SearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Response types that each get their own Cache-Control policy.
+const (
+	staticResponse    = "static"
+	detailResponse    = "detail"
+	searchResponse    = "search"
+	apiResponse       = "api"
+	widgetResponse    = "widget"
+	editorialResponse = "editorial"
+)
+
+// defaultCachePolicies are the Cache-Control values used unless overridden
+// through CACHE_CONTROL_<TYPE> environment variables:
+//
+//   - static: fingerprinted assets never change, so cache them for a year.
+//   - detail: movie data changes rarely, but pages may be personalised, so
+//     only the browser may keep them, for five minutes.
+//   - search: results depend on a live query and are never stored.
+//   - api:    clients may keep JSON responses but must revalidate them.
+//   - widget: public feeds for dashboards; shared caches may keep them for an hour.
+//   - editorial: curated pages such as /best/{year} change slowly and are
+//     the same for everyone, so shared caches may keep them for a day.
+var defaultCachePolicies = map[string]string{
+	staticResponse:    "public, max-age=31536000, immutable",
+	detailResponse:    "private, max-age=300",
+	searchResponse:    "no-store",
+	apiResponse:       "no-cache",
+	widgetResponse:    "public, max-age=3600",
+	editorialResponse: "public, max-age=86400",
+}
+
+// loadCachePolicies returns the default policies with any environment
+// overrides applied, e.g. CACHE_CONTROL_DETAIL="public, max-age=60".
+func loadCachePolicies() map[string]string {
+	policies := make(map[string]string, len(defaultCachePolicies))
+	for responseType, policy := range defaultCachePolicies {
+		if override := os.Getenv("CACHE_CONTROL_" + strings.ToUpper(responseType)); override != "" {
+			policy = override
+		}
+		policies[responseType] = policy
+	}
+	return policies
+}
+
+// setCacheControl applies the configured policy for the given response type.
+func setCacheControl(w http.ResponseWriter, config Config, responseType string) {
+	if policy, ok := config.CachePolicies[responseType]; ok {
+		w.Header().Set("Cache-Control", policy)
+	}
+}
+
+// setSurrogateKeys tags a shareable response with the entities it shows,
+// so a CDN in front of the app can purge everything about one movie or
+// search at once. The keys go out sorted and de-duplicated, both as
+// Surrogate-Key (space-separated, Fastly and others) and Cache-Tag
+// (comma-separated, Cloudflare). Call it after setCacheControl: responses
+// marked private or no-store aren't kept by a CDN and get no keys.
+func setSurrogateKeys(w http.ResponseWriter, keys ...string) {
+	policy := w.Header().Get("Cache-Control")
+	if strings.Contains(policy, "private") || strings.Contains(policy, "no-store") {
+		return
+	}
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+	w.Header().Set("Surrogate-Key", strings.Join(keys, " "))
+	w.Header().Set("Cache-Tag", strings.Join(keys, ","))
+}
+
+// movieSurrogateKey is the surrogate key of every response showing a
+// movie: purge "movie-603" to drop them all.
+func movieSurrogateKey(id int) string {
+	return fmt.Sprintf("movie-%d", id)
+}
+
+// moviesSurrogateKeys returns listKey followed by the key of each movie.
+func moviesSurrogateKeys(listKey string, movies []Movie) []string {
+	keys := []string{listKey}
+	for _, movie := range movies {
+		keys = append(keys, movieSurrogateKey(movie.ID))
+	}
+	return keys
+}
+
+// maxSearchKeyLength caps the escaped query in a search surrogate key. CDNs
+// limit the length of each key and of the whole header, and a pasted
+// paragraph would blow through both.
+const maxSearchKeyLength = 100
+
+// searchSurrogateKey is the surrogate key of a search, e.g. "search-dune".
+// Queries differing only in case or spacing share a key; anything outside
+// ASCII letters and digits is escaped, so the key is a single header
+// token. Queries escaping to more than maxSearchKeyLength characters get
+// "search-sha256-" and the first 16 hex digits of the normalized query's
+// SHA-256 instead.
+func searchSurrogateKey(query string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(query)), "-")
+	if escaped := url.QueryEscape(normalized); len(escaped) <= maxSearchKeyLength {
+		return "search-" + escaped
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return "search-sha256-" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSetSurrogateKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		keys      []string
+		wantKeys  string
+		wantTags  string
+		untouched bool
+	}{
+		{"sorted and de-duplicated", "public, max-age=60", []string{"movie-604", "search-dune", "movie-603", "movie-604"}, "movie-603 movie-604 search-dune", "movie-603,movie-604,search-dune", false},
+		{"no policy", "", []string{"movie-603"}, "movie-603", "movie-603", false},
+		{"private", "private, max-age=300", []string{"movie-603"}, "", "", true},
+		{"no-store", "no-store", []string{"movie-603"}, "", "", true},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		if tt.policy != "" {
+			rec.Header().Set("Cache-Control", tt.policy)
+		}
+		keys := slices.Clone(tt.keys)
+		setSurrogateKeys(rec, keys...)
+		if got := rec.Header().Get("Surrogate-Key"); got != tt.wantKeys {
+			t.Errorf("%s: Surrogate-Key = %q, want %q", tt.name, got, tt.wantKeys)
+		}
+		if got := rec.Header().Get("Cache-Tag"); got != tt.wantTags {
+			t.Errorf("%s: Cache-Tag = %q, want %q", tt.name, got, tt.wantTags)
+		}
+		if _, ok := rec.Header()["Surrogate-Key"]; ok == tt.untouched {
+			t.Errorf("%s: Surrogate-Key set = %v", tt.name, ok)
+		}
+		if !slices.Equal(keys, tt.keys) {
+			t.Errorf("%s: setSurrogateKeys reordered its argument to %q", tt.name, keys)
+		}
+	}
+}
+
+func TestSearchSurrogateKey(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"dune", "search-dune"},
+		{"Dune", "search-dune"},
+		{"  the   Dark Knight ", "search-the-dark-knight"},
+		{"Amélie", "search-am%C3%A9lie"},
+		{"ocean's 11", "search-ocean%27s-11"},
+		{"a,b c", "search-a%2Cb-c"},
+		{"", "search-"},
+		{strings.Repeat("a", maxSearchKeyLength), "search-" + strings.Repeat("a", maxSearchKeyLength)},
+		{strings.Repeat("a", maxSearchKeyLength+1), "search-sha256-9d0793397991b57a"},
+		{strings.Repeat("A", maxSearchKeyLength+1), "search-sha256-9d0793397991b57a"},
+	}
+	for _, tt := range tests {
+		if got := searchSurrogateKey(tt.query); got != tt.want {
+			t.Errorf("searchSurrogateKey(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	// The cap applies to the escaped query: 34 "é" escape to 204 characters.
+	long := searchSurrogateKey(strings.Repeat("é", 34))
+	if !strings.HasPrefix(long, "search-sha256-") || len(long) != len("search-sha256-")+16 {
+		t.Errorf("searchSurrogateKey of 34 é = %q, want a hashed key", long)
+	}
+	if other := searchSurrogateKey(strings.Repeat("é", 35)); other == long {
+		t.Errorf("different long queries share the key %q", long)
+	}
+}
+
+func TestMoviesSurrogateKeys(t *testing.T) {
+	got := moviesSurrogateKeys("search-matrix", []Movie{{ID: 604}, {ID: 603}})
+	if want := []string{"search-matrix", "movie-604", "movie-603"}; !slices.Equal(got, want) {
+		t.Errorf("moviesSurrogateKeys = %q, want %q", got, want)
+	}
+	if got := moviesSurrogateKeys("trending-day", nil); !slices.Equal(got, []string{"trending-day"}) {
+		t.Errorf("no movies: %q", got)
+	}
+}
+
+func TestAPISearchSurrogateKeys(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{"/search/movie": searchMatrixJSON})
+
+	t.Setenv("CACHE_CONTROL_API", "public, max-age=60")
+	app := newTestApp(t, fake)
+	var first string
+	for _, target := range []string{"/api/search?query=The+Matrix", "/api/search?query=the%20%20matrix"} {
+		rec := get(app, target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d", target, rec.Code)
+		}
+		got := rec.Header().Get("Surrogate-Key")
+		if want := "movie-603 movie-604 search-the-matrix"; got != want {
+			t.Errorf("GET %s: Surrogate-Key = %q, want %q", target, got, want)
+		}
+		if first == "" {
+			first = got
+		} else if got != first {
+			t.Errorf("equivalent queries got keys %q and %q", first, got)
+		}
+	}
+
+	t.Setenv("CACHE_CONTROL_API", "private, no-cache")
+	app = newTestApp(t, fake)
+	rec := get(app, "/api/search?query=The+Matrix")
+	if keys := rec.Header().Get("Surrogate-Key"); keys != "" {
+		t.Errorf("private response carries surrogate keys %q", keys)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// theatricalReleaseTypes are TMDB's limited (2) and theatrical (3) release
+// types, OR'ed together for discover's with_release_type.
+const theatricalReleaseTypes = "2|3"
+
+// dateWindow is an inclusive range of calendar days.
+type dateWindow struct {
+	From, To time.Time
+}
+
+// CinemaSection is one labeled row of the cinema page.
+type CinemaSection struct {
+	Title  string
+	Movies []Movie
+}
+
+// CinemaPage is the data rendered by the cinema template.
+type CinemaPage struct {
+	Region   string
+	Sections []CinemaSection // only sections with movies
+	Meta     Meta
+	Theme    string
+}
+
+var cinemaTmpl = pageTemplate("cinema", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>In cinemas</title>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1>In cinemas ({{.Region}})</h1>
+    {{range .Sections}}
+    <h2>{{.Title}}</h2>
+    {{template "strip" .Movies}}
+    {{else}}
+    <p>No recent cinema releases found for {{.Region}}.</p>
+    {{end}}
+    {{template "footer"}}
+</body>
+</html>
+`, "")
+
+// cinemaCache keeps the sections for one region and day; a new day or a
+// different region replaces them.
+type cinemaCache struct {
+	mu       sync.Mutex
+	key      string
+	sections []CinemaSection
+}
+
+// cinemaHandler serves GET /cinema: what opened in theaters this week and
+// what opened four to eight weeks ago and may be leaving soon.
+func cinemaHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	sections, err := cinemaSections(r.Context(), config.Region, localNow(config), config.TMDB)
+	if err != nil {
+		log.Printf("Error fetching cinema releases: %v", err)
+		tmdbFailure(w, r, err, "Failed to fetch cinema releases")
+		return
+	}
+
+	page, err := render(r, cinemaTmpl, CinemaPage{Region: config.Region, Sections: sections, Meta: pageMeta("/cinema"), Theme: theme(r)})
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, editorialResponse)
+	page.WriteTo(w)
+}
+
+// cinemaSections returns the non-empty cinema sections for region, fetching
+// them at most once per region and day. While TMDB is down for maintenance,
+// the region's sections from an earlier day are returned instead.
+func cinemaSections(ctx context.Context, region string, now time.Time, tmdb *TMDB) ([]CinemaSection, error) {
+	openedThisWeek, lastChance := cinemaWindows(now)
+	key := region + "/" + openedThisWeek.To.Format(releaseDateLayout)
+
+	tmdb.cinema.mu.Lock()
+	defer tmdb.cinema.mu.Unlock()
+	if tmdb.cinema.key == key {
+		return tmdb.cinema.sections, nil
+	}
+
+	var sections []CinemaSection
+	for _, section := range []struct {
+		title  string
+		window dateWindow
+	}{
+		{"Opened this week", openedThisWeek},
+		{"Last chance", lastChance},
+	} {
+		start := time.Now()
+		results, err := fetchTheatricalReleases(ctx, region, section.window, tmdb)
+		RecordTiming(ctx, "tmdb_discover", start)
+		if err != nil {
+			// During TMDB maintenance an earlier day's sections beat none.
+			if inMaintenance(err) && strings.HasPrefix(tmdb.cinema.key, region+"/") {
+				return tmdb.cinema.sections, nil
+			}
+			return nil, err
+		}
+		// Regions with sparse data skip the section instead of showing an
+		// empty heading.
+		if len(results.Results) > 0 {
+			sections = append(sections, CinemaSection{Title: section.title, Movies: results.Results})
+		}
+	}
+
+	tmdb.cinema.key = key
+	tmdb.cinema.sections = sections
+	return sections, nil
+}
+
+// cinemaWindows returns the days of the last week, today included, and the
+// days four to eight weeks back, for movies that may be leaving theaters.
+// Days are calendar days in now's location, so the windows move at local
+// midnight and keep their length across daylight saving changes.
+func cinemaWindows(now time.Time) (openedThisWeek, lastChance dateWindow) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	openedThisWeek = dateWindow{From: today.AddDate(0, 0, -6), To: today}
+	lastChance = dateWindow{From: today.AddDate(0, 0, -8*7), To: today.AddDate(0, 0, -4*7)}
+	return openedThisWeek, lastChance
+}
+
+func fetchTheatricalReleases(ctx context.Context, region string, window dateWindow, tmdb *TMDB) (*SearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s&region=%s&with_release_type=%s&release_date.gte=%s&release_date.lte=%s&sort_by=popularity.desc",
+		tmdb.BaseURL, discoverEndpoint, tmdb.APIKey, region, url.QueryEscape(theatricalReleaseTypes),
+		window.From.Format(releaseDateLayout), window.To.Format(releaseDateLayout))
+	var results SearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
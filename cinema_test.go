@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCinemaWindows(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		// Windows as "from..to", inclusive calendar days.
+		thisWeek, lastChance string
+	}{
+		{"midweek", time.Date(2024, 5, 15, 14, 30, 0, 0, time.UTC), "2024-05-09..2024-05-15", "2024-03-20..2024-04-17"},
+		{"at midnight", time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), "2024-05-09..2024-05-15", "2024-03-20..2024-04-17"},
+		{"just before midnight", time.Date(2024, 5, 14, 23, 59, 59, 999, time.UTC), "2024-05-08..2024-05-14", "2024-03-19..2024-04-16"},
+		{"across new year", time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC), "2023-12-28..2024-01-03", "2023-11-08..2023-12-06"},
+		{"leap day", time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC), "2024-02-28..2024-03-05", "2024-01-09..2024-02-06"},
+		{"week after spring forward", time.Date(2024, 3, 12, 0, 30, 0, 0, newYork), "2024-03-06..2024-03-12", "2024-01-16..2024-02-13"},
+		{"week after fall back", time.Date(2024, 11, 5, 23, 30, 0, 0, newYork), "2024-10-30..2024-11-05", "2024-09-10..2024-10-08"},
+		{"local day ahead of UTC", time.Date(2024, 5, 15, 8, 0, 0, 0, auckland), "2024-05-09..2024-05-15", "2024-03-20..2024-04-17"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thisWeek, lastChance := cinemaWindows(tt.now)
+			for _, w := range []struct {
+				name   string
+				window dateWindow
+				want   string
+			}{{"opened this week", thisWeek, tt.thisWeek}, {"last chance", lastChance, tt.lastChance}} {
+				got := w.window.From.Format(releaseDateLayout) + ".." + w.window.To.Format(releaseDateLayout)
+				if got != w.want {
+					t.Errorf("%s = %s, want %s", w.name, got, w.want)
+				}
+				if w.window.From.Hour() != 0 || w.window.To.Hour() != 0 || w.window.To.Location() != tt.now.Location() {
+					t.Errorf("%s is not local midnight to midnight: %s..%s", w.name, w.window.From, w.window.To)
+				}
+			}
+		})
+	}
+}
+
+func TestCinemaPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		discover string
+		want     []string
+		unwanted []string
+	}{
+		{
+			name:     "releases",
+			discover: searchMatrixJSON,
+			want:     []string{"Opened this week", "Last chance", "The Matrix"},
+			unwanted: []string{"No recent cinema releases"},
+		},
+		{
+			name:     "no releases",
+			discover: `{"page":1,"total_pages":0,"total_results":0,"results":[]}`,
+			want:     []string{"No recent cinema releases found for US"},
+			unwanted: []string{"Opened this week", "Last chance"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeTMDB(t, map[string]string{"/discover/movie": tt.discover})
+			rec := get(newTestApp(t, fake), "/cinema")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET /cinema = %d", rec.Code)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(rec.Body.String(), s) {
+					t.Errorf("page is missing %q", s)
+				}
+			}
+			for _, s := range tt.unwanted {
+				if strings.Contains(rec.Body.String(), s) {
+					t.Errorf("page shows %q", s)
+				}
+			}
+			if n := fake.calls("/discover/movie"); n != 2 {
+				t.Errorf("%d discover calls, want one per window", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := parseTrustedProxies("10.0.0.0/8, 192.168.1.1, fd00::/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{"no proxy", "203.0.113.7:51234", nil, "203.0.113.7"},
+		{"untrusted remote ignores XFF", "203.0.113.7:51234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.2:443", []string{"198.51.100.1, 192.168.1.1, 10.1.2.3"}, "198.51.100.1"},
+		{"spoofed prefix", "10.0.0.2:443", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"spoofed trusted address before the client", "10.0.0.2:443", []string{"10.9.9.9, 198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy without XFF", "10.0.0.2:443", nil, "10.0.0.2"},
+		{"empty XFF", "10.0.0.2:443", []string{""}, "10.0.0.2"},
+		{"several XFF headers", "10.0.0.2:443", []string{"1.1.1.1, 198.51.100.1", "192.168.1.1"}, "198.51.100.1"},
+		{"several XFF headers, spoofed first", "10.0.0.2:443", []string{"1.1.1.1", "198.51.100.1"}, "198.51.100.1"},
+		{"malformed hop stops the walk", "10.0.0.2:443", []string{"198.51.100.1, not-an-ip"}, "10.0.0.2"},
+		{"malformed hop behind the client", "10.0.0.2:443", []string{"garbage, 198.51.100.1"}, "198.51.100.1"},
+		{"hop with port", "10.0.0.2:443", []string{"198.51.100.1:8080"}, "198.51.100.1"},
+		{"hop with spaces", "10.0.0.2:443", []string{"  198.51.100.1  "}, "198.51.100.1"},
+		{"IPv6 hop", "10.0.0.2:443", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"bracketed IPv6 hop", "10.0.0.2:443", []string{"[2001:db8::1]"}, "2001:db8::1"},
+		{"bracketed IPv6 hop with port", "10.0.0.2:443", []string{"[2001:db8::1]:8080"}, "2001:db8::1"},
+		{"IPv4-mapped hop", "10.0.0.2:443", []string{"::ffff:198.51.100.1"}, "198.51.100.1"},
+		{"IPv6 trusted remote", "[fd00::5]:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"IPv6 untrusted remote", "[2001:db8::9]:443", []string{"198.51.100.1"}, "2001:db8::9"},
+		{"IPv4-mapped trusted remote", "[::ffff:10.0.0.2]:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"remote without port", "10.0.0.2", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"unparseable remote", "pipe", []string{"198.51.100.1"}, "pipe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.xff {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(r, trusted); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPNoTrustedProxies(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.2:443"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := clientIP(r, nil); got != "10.0.0.2" {
+		t.Errorf("clientIP = %q, want RemoteAddr", got)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{" , ", nil, false},
+		{"10.0.0.0/8", []string{"10.0.0.0/8"}, false},
+		{"10.1.2.3/8", []string{"10.0.0.0/8"}, false},
+		{"192.168.1.1", []string{"192.168.1.1/32"}, false},
+		{"::ffff:192.168.1.1", []string{"192.168.1.1/32"}, false},
+		{"fd00::/8, 2001:db8::1", []string{"fd00::/8", "2001:db8::1/128"}, false},
+		{"10.0.0.0/33", nil, true},
+		{"proxy.internal", nil, true},
+	}
+	for _, tt := range tests {
+		prefixes, err := parseTrustedProxies(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTrustedProxies(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		var got []string
+		for _, prefix := range prefixes {
+			got = append(got, prefix.String())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseTrustedProxies(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Command helm-render renders the Kubernetes manifests in
+// deploy/helm/templates with values derived from the application's
+// resource profile:
+//
+//	go run ./cmd/helm-render/ -image ghcr.io/you/movie-finder:1.0.0 -out deploy/rendered
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"module/internal/envvars"
+)
+
+// Values are the inputs available to every manifest template.
+type Values struct {
+	Name      string
+	Namespace string
+	Image     string
+	Port      int
+
+	// Replica bounds and resources follow the app's resource profile below.
+	MinReplicas    int
+	MaxReplicas    int
+	TargetCPU      int
+	MinAvailable   int
+	CPURequest     string
+	CPULimit       string
+	MemoryRequest  string
+	MemoryLimit    string
+	ServiceMonitor bool
+
+	// Config holds the non-secret environment variables read by the app.
+	Config map[string]string
+	// Secrets lists the environment variables that must come from the Secret.
+	Secrets []string
+	// SecretValues pre-fills the Secret entries the renderer can supply.
+	SecretValues map[string]string
+}
+
+// The resource profile is derived from BenchmarkHomeSearch and
+// BenchmarkDetail in the app's server_test.go, run against the fixture
+// TMDB on one core:
+//
+//	BenchmarkHomeSearch   ~0.2 ms/op    85 KB/op
+//	BenchmarkDetail       ~0.4 ms/op   139 KB/op
+//
+// CPU: a detail page, the heavier of the two, costs about 0.4 ms, so the
+// 50m request covers about 100 pages a second and the 500m limit about
+// 1,000 in a burst. Scaling out at 70% keeps a pod below 100 pages a
+// second of sustained load.
+//
+// Memory: the app isn't stateless. Besides each request's working set it
+// keeps the TMDB caches (IMDb ID mappings, runtimes, runtime shelves, the
+// popular strip, /cinema and /collections) and the negative caches, each
+// capped at 10,000 entries (about 1 MB), plus view counts when
+// VIEW_COUNTS_FILE is set. Together with the Go runtime that came to about
+// 13 MB after the benchmarks, which the 32Mi request covers. Allowing for
+// the garbage collector letting the heap grow to twice what is live, the
+// 128Mi limit leaves room for about 400 requests in flight; set
+// MAX_CONCURRENT_REQUESTS below that on busy deployments.
+const (
+	profileMinReplicas   = 2 // keep one pod serving during rollouts and node drains
+	profileMaxReplicas   = 6
+	profileTargetCPU     = 70
+	profileCPURequest    = "50m"
+	profileCPULimit      = "500m"
+	profileMemoryRequest = "32Mi"
+	profileMemoryLimit   = "128Mi"
+)
+
+// configEnv returns the settings from envvars.All that go in the ConfigMap,
+// each with the default the app applies when it is unset, and the names
+// that go in the Secret.
+func configEnv() (config map[string]string, secrets []string) {
+	config = map[string]string{}
+	for _, v := range envvars.All {
+		if v.Secret {
+			secrets = append(secrets, v.Name)
+		} else {
+			config[v.Name] = v.Default
+		}
+	}
+	return config, secrets
+}
+
+// cookieSecretSize is the number of random bytes in a generated
+// COOKIE_SECRET, hex encoded to the 64 characters the app expects.
+const cookieSecretSize = 32
+
+// cookieSecret returns raw if it is a valid COOKIE_SECRET, or a new random
+// one if raw is empty. Every replica must share the key, or a quiz cookie
+// set by one pod can't be read by the next, so the Secret never leaves it
+// blank.
+func cookieSecret(raw string) (string, error) {
+	if raw == "" {
+		key := make([]byte, cookieSecretSize)
+		if _, err := rand.Read(key); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(key), nil
+	}
+	if key, err := hex.DecodeString(raw); err != nil || len(key) != cookieSecretSize {
+		return "", fmt.Errorf("must be %d hex characters", cookieSecretSize*2)
+	}
+	return raw, nil
+}
+
+func main() {
+	config, secrets := configEnv()
+	values := Values{
+		Port:          8080,
+		MinReplicas:   profileMinReplicas,
+		MaxReplicas:   profileMaxReplicas,
+		TargetCPU:     profileTargetCPU,
+		MinAvailable:  profileMinReplicas - 1,
+		CPURequest:    profileCPURequest,
+		CPULimit:      profileCPULimit,
+		MemoryRequest: profileMemoryRequest,
+		MemoryLimit:   profileMemoryLimit,
+		Config:        config,
+		Secrets:       secrets,
+	}
+
+	templatesDir := flag.String("templates", "deploy/helm/templates", "directory containing the manifest templates")
+	outDir := flag.String("out", "", "directory to write rendered manifests to (default: stdout)")
+	flag.StringVar(&values.Name, "name", "movie-finder", "name used for all Kubernetes objects")
+	flag.StringVar(&values.Namespace, "namespace", "default", "namespace to deploy into")
+	flag.StringVar(&values.Image, "image", "movie-finder:latest", "container image to deploy")
+	flag.IntVar(&values.MaxReplicas, "max-replicas", values.MaxReplicas, "upper bound for the HorizontalPodAutoscaler")
+	flag.BoolVar(&values.ServiceMonitor, "service-monitor", false, "also render a Prometheus ServiceMonitor")
+	secret := flag.String("cookie-secret", "", "COOKIE_SECRET shared by all replicas, 64 hex characters (default: generate one)")
+	flag.Parse()
+
+	cookieKey, err := cookieSecret(*secret)
+	if err != nil {
+		log.Fatalf("Invalid -cookie-secret: %v", err)
+	}
+	values.SecretValues = map[string]string{"COOKIE_SECRET": cookieKey}
+
+	paths, err := filepath.Glob(filepath.Join(*templatesDir, "*.yaml"))
+	if err != nil || len(paths) == 0 {
+		log.Fatalf("No templates found in %s", *templatesDir)
+	}
+
+	tmpl := template.Must(template.ParseFiles(paths...))
+
+	if *outDir != "" {
+		if err := os.MkdirAll(*outDir, 0o755); err != nil {
+			log.Fatalf("Failed to create %s: %v", *outDir, err)
+		}
+	}
+
+	for i, path := range paths {
+		name := filepath.Base(path)
+		var out *os.File
+		if *outDir == "" {
+			out = os.Stdout
+			if i > 0 {
+				os.Stdout.WriteString("---\n")
+			}
+		} else {
+			out, err = os.Create(filepath.Join(*outDir, name))
+			if err != nil {
+				log.Fatalf("Failed to create manifest: %v", err)
+			}
+		}
+
+		if err := tmpl.ExecuteTemplate(out, name, values); err != nil {
+			log.Fatalf("Failed to render %s: %v", name, err)
+		}
+
+		if out != os.Stdout {
+			out.Close()
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Command seed-testdata regenerates the TMDB test fixtures in testdata/tmdb
+// from the real API. It needs a valid TMDB_API_KEY and is meant to be run
+// by hand every so often, with the resulting files committed:
+//
+//	go run ./cmd/seed-testdata/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	baseURL   = "https://api.themoviedb.org/3"
+	outputDir = "testdata/tmdb"
+	redacted  = "REDACTED"
+)
+
+// fixture describes one TMDB request and the file its response is written to.
+type fixture struct {
+	Name   string
+	Path   string
+	Params url.Values
+}
+
+// fixtures lists every TMDB endpoint the application talks to.
+// Keep it in sync when new endpoints are added.
+var fixtures = []fixture{
+	{Name: "search_movie", Path: "/search/movie", Params: url.Values{"query": {"The Matrix"}}},
+	{Name: "movie_detail", Path: "/movie/603", Params: url.Values{"append_to_response": {"credits,videos,external_ids,release_dates,keywords,recommendations,watch/providers"}}},
+	{Name: "movie_images", Path: "/movie/603/images", Params: url.Values{"include_image_language": {"en,null"}}},
+	{Name: "movie_watch_providers", Path: "/movie/603", Params: url.Values{"append_to_response": {"watch/providers"}}},
+	{Name: "find_imdb", Path: "/find/tt0133093", Params: url.Values{"external_source": {"imdb_id"}}},
+	{Name: "search_person", Path: "/search/person", Params: url.Values{"query": {"Wachowski"}}},
+	{Name: "person", Path: "/person/6384"},
+	{Name: "person_combined_credits", Path: "/person/6384/combined_credits"},
+	{Name: "trending_movie_week", Path: "/trending/movie/week"},
+	{Name: "movie_popular", Path: "/movie/popular"},
+	{Name: "discover_cinema", Path: "/discover/movie", Params: url.Values{"region": {"US"}, "with_release_type": {"2|3"}, "release_date.gte": {"2024-03-01"}, "release_date.lte": {"2024-03-07"}, "sort_by": {"popularity.desc"}}},
+	{Name: "collection", Path: "/collection/2344"},
+	{Name: "company", Path: "/company/174"},
+	{Name: "discover_company", Path: "/discover/movie", Params: url.Values{"with_companies": {"174"}, "sort_by": {"release_date.desc"}, "page": {"1"}}},
+	{Name: "discover_genre_decade", Path: "/discover/movie", Params: url.Values{"with_genres": {"878"}, "primary_release_date.gte": {"1990-01-01"}, "primary_release_date.lte": {"1999-12-31"}, "sort_by": {"popularity.desc"}}},
+	{Name: "discover_genre_runtime", Path: "/discover/movie", Params: url.Values{"with_genres": {"878"}, "with_runtime.gte": {"116"}, "with_runtime.lte": {"156"}, "sort_by": {"popularity.desc"}}},
+	{Name: "movie_top_rated", Path: "/movie/top_rated", Params: url.Values{"page": {"1"}}},
+	{Name: "discover_best_1999", Path: "/discover/movie", Params: url.Values{"primary_release_year": {"1999"}, "sort_by": {"vote_count.desc"}, "vote_average.gte": {"7.0"}}},
+	{Name: "configuration", Path: "/configuration"},
+}
+
+// piiKeys are JSON keys whose values are blanked out if they ever show up
+// in a response (e.g. review author details).
+var piiKeys = map[string]bool{
+	"email":         true,
+	"username":      true,
+	"gravatar_hash": true,
+	"avatar_path":   true,
+}
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	apiKey := os.Getenv("TMDB_API_KEY")
+	if apiKey == "" {
+		log.Fatal("API key not set in TMDB_API_KEY environment variable")
+	}
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		log.Fatalf("Failed to create %s: %v", outputDir, err)
+	}
+
+	for _, f := range fixtures {
+		body, err := fetch(f, apiKey)
+		if err != nil {
+			log.Fatalf("Failed to fetch %s: %v", f.Name, err)
+		}
+
+		normalized, err := normalize(body, apiKey)
+		if err != nil {
+			log.Fatalf("Failed to normalize %s: %v", f.Name, err)
+		}
+
+		path := filepath.Join(outputDir, f.Name+".json")
+		if err := os.WriteFile(path, normalized, 0o644); err != nil {
+			log.Fatalf("Failed to write %s: %v", path, err)
+		}
+		log.Printf("Wrote %s", path)
+	}
+}
+
+func fetch(f fixture, apiKey string) ([]byte, error) {
+	params := url.Values{}
+	for k, v := range f.Params {
+		params[k] = v
+	}
+	params.Set("api_key", apiKey)
+
+	resp, err := http.Get(baseURL + f.Path + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// normalize strips the API key and any PII from a response and re-encodes
+// it with stable indentation so fixture diffs stay readable.
+func normalize(body []byte, apiKey string) ([]byte, error) {
+	body = bytes.ReplaceAll(body, []byte(apiKey), []byte(redacted))
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	data = redact(data)
+
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if piiKeys[strings.ToLower(k)] && child != nil {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redact(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redact(child)
+		}
+	}
+	return v
+}
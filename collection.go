@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const collectionEndpoint = "/collection/"
+
+// Collection is a TMDB collection, a franchise such as "The Matrix
+// Collection", with its movies.
+type Collection struct {
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	Overview   string  `json:"overview"`
+	PosterPath string  `json:"poster_path"`
+	Parts      []Movie `json:"parts"`
+}
+
+// CollectionPage is the data rendered by the collection template.
+type CollectionPage struct {
+	*Collection
+	Meta  Meta
+	Theme string
+}
+
+var collectionTmpl = pageTemplate("collection", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>{{.Name}}</title>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1 dir="auto">{{.Name}}</h1>
+    {{posterImg .PosterPath 185 .Name}}
+    {{with .Overview}}<p dir="auto">{{.}}</p>{{end}}
+    {{block "parts" .}}
+    {{range .Parts}}
+    <p><a href="{{movieURL .ID .Title}}">{{posterImg .PosterPath 46 ""}} <span dir="auto">{{.Title}}</span> ({{.ReleaseDate.DisplayYear}})</a></p>
+    {{end}}
+    {{end}}
+    <p><a href="/collection/{{.ID}}/export.ics">Subscribe to upcoming releases (iCalendar)</a></p>
+    {{template "footer"}}
+</body>
+</html>
+`, `
+{{define "parts"}}
+<ol>
+    {{range .Parts}}<li><a href="{{movieURL .ID .Title}}" dir="auto">{{.Title}}</a> ({{.ReleaseDate.DisplayYear}})</li>
+    {{end}}
+</ol>
+{{end}}
+`)
+
+// collectionHandler dispatches /collection/{id} and /collection/{id}/...
+// requests.
+func collectionHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.Atoi(pathParts[2])
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(pathParts) == 3:
+		collectionPageHandler(w, r, config, id)
+	case len(pathParts) == 4 && pathParts[3] == "export.ics":
+		collectionCalendarHandler(w, r, config, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// collectionPageHandler serves GET /collection/{id}: the franchise with its
+// movies in release order.
+func collectionPageHandler(w http.ResponseWriter, r *http.Request, config Config, id int) {
+	start := time.Now()
+	collection, err := fetchCollection(r.Context(), id, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_collection", start)
+	if err != nil {
+		log.Printf("Error fetching collection %d: %v", id, err)
+		tmdbFailure(w, r, err, "Failed to fetch collection")
+		return
+	}
+	// TMDB answers unknown IDs with an error object, which decodes to an
+	// empty collection.
+	if collection.ID == 0 {
+		http.Error(w, "Collection not found", http.StatusNotFound)
+		return
+	}
+	sortByReleaseDate(collection.Parts)
+
+	body, err := render(r, collectionTmpl, CollectionPage{Collection: collection, Meta: pageMeta("/collection/"), Theme: theme(r)})
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, detailResponse)
+	body.WriteTo(w)
+}
+
+// sortByReleaseDate orders movies from the earliest release to the latest,
+// with movies lacking a full release date last. sortMovies only sorts newest
+// first, and reversing that would put the undated parts of a collection,
+// usually the ones still being made, at the start.
+func sortByReleaseDate(movies []Movie) {
+	slices.SortStableFunc(movies, func(a, b Movie) int {
+		ta, tb := a.ReleaseDate.Time(), b.ReleaseDate.Time()
+		switch {
+		case ta.IsZero() && tb.IsZero():
+			return 0
+		case ta.IsZero():
+			return 1
+		case tb.IsZero():
+			return -1
+		}
+		return ta.Compare(tb)
+	})
+}
+
+func fetchCollection(ctx context.Context, id int, tmdb *TMDB) (*Collection, error) {
+	requestURL := fmt.Sprintf("%s%s%d?api_key=%s", tmdb.BaseURL, collectionEndpoint, id, tmdb.APIKey)
+	var collection Collection
+	if err := tmdbGet(ctx, tmdb, requestURL, &collection); err != nil {
+		return nil, err
+	}
+
+	return &collection, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// collectionIndexTTL is how long the fetched collections on /collections
+// are reused. Franchises gain a movie a few times a year at most.
+const collectionIndexTTL = 24 * time.Hour
+
+// defaultFeaturedCollections are listed on /collections unless
+// COLLECTIONS_FILE names others: Star Wars, Harry Potter, The Lord of the
+// Rings, The Matrix, The Dark Knight, Mission: Impossible, James Bond,
+// Jurassic Park, Indiana Jones, Back to the Future, Alien and Toy Story.
+var defaultFeaturedCollections = []int{10, 1241, 119, 2344, 263, 87359, 645, 328, 84, 264, 8091, 10194}
+
+// CollectionIndexPage is the data rendered by the collection index template.
+type CollectionIndexPage struct {
+	Collections []Collection
+	Meta        Meta
+	Theme       string
+}
+
+var collectionIndexTmpl = pageTemplate("collections", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>Collections</title>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1>Collections</h1>
+    {{block "index" .}}
+    <div style="display: flex; flex-wrap: wrap; gap: 1em;">
+        {{range .Collections}}
+        <a href="/collection/{{.ID}}" style="width: 185px;">{{posterImg .PosterPath 185 ""}}<br><span dir="auto">{{.Name}}</span></a>
+        {{else}}
+        <p>No collections found.</p>
+        {{end}}
+    </div>
+    {{end}}
+    {{template "footer"}}
+</body>
+</html>
+`, `
+{{define "index"}}
+<ol>
+    {{range .Collections}}<li><a href="/collection/{{.ID}}" dir="auto">{{.Name}}</a></li>
+    {{else}}
+    <li>No collections found.</li>
+    {{end}}
+</ol>
+{{end}}
+`)
+
+// collectionIndexCache holds the featured collections shared by all
+// visitors.
+type collectionIndexCache struct {
+	mu          sync.Mutex
+	collections []Collection
+	expires     time.Time
+}
+
+// collectionIndexHandler serves GET /collections, the featured franchises.
+func collectionIndexHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	collections := featuredCollections(r.Context(), config.FeaturedCollections, config.TMDB)
+
+	page, err := render(r, collectionIndexTmpl, CollectionIndexPage{Collections: collections, Meta: pageMeta("/collections"), Theme: theme(r)})
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, editorialResponse)
+	page.WriteTo(w)
+}
+
+// featuredCollections fetches the collections with the given IDs, at most
+// once per collectionIndexTTL. IDs TMDB no longer knows are skipped, as are
+// collections that fail to load; a failure keeps the result from being
+// cached, so the next request tries again. While TMDB is down for
+// maintenance, the expired list is returned instead.
+func featuredCollections(ctx context.Context, ids []int, tmdb *TMDB) []Collection {
+	tmdb.collectionIndex.mu.Lock()
+	defer tmdb.collectionIndex.mu.Unlock()
+	if time.Now().Before(tmdb.collectionIndex.expires) {
+		return tmdb.collectionIndex.collections
+	}
+
+	var collections []Collection
+	complete := true
+	for _, id := range ids {
+		start := time.Now()
+		collection, err := fetchCollection(ctx, id, tmdb)
+		RecordTiming(ctx, "tmdb_collection", start)
+		if err != nil {
+			log.Printf("Error fetching collection %d: %v", id, err)
+			if inMaintenance(err) && tmdb.collectionIndex.collections != nil {
+				return tmdb.collectionIndex.collections
+			}
+			complete = false
+			continue
+		}
+		if collection.ID == 0 {
+			log.Printf("Skipping collection %d: not found", id)
+			continue
+		}
+		// The index only shows the name and poster.
+		collection.Parts = nil
+		collections = append(collections, *collection)
+	}
+
+	if complete {
+		tmdb.collectionIndex.collections = collections
+		tmdb.collectionIndex.expires = time.Now().Add(collectionIndexTTL)
+	}
+	return collections
+}
+
+// loadFeaturedCollections reads the collection IDs listed in the file at
+// path, one per line in display order. Blank lines and lines starting with
+// # are ignored. An empty path selects defaultFeaturedCollections.
+func loadFeaturedCollections(path string) ([]int, error) {
+	if path == "" {
+		return defaultFeaturedCollections, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ids []int
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := strconv.Atoi(line)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%s:%d: %q is not a collection ID", path, n, line)
+		}
+		ids = append(ids, id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	companyEndpoint = "/company/"
+
+	// tmdbMaxPage is the last results page TMDB serves for list endpoints
+	// such as discover.
+	tmdbMaxPage = 500
+)
+
+// Company is a production company's profile.
+type Company struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	LogoPath          string `json:"logo_path"`
+	OriginCountry     string `json:"origin_country"`
+	ParentCompanyID   int    `json:"-"` // 0 when the company has no parent
+	ParentCompanyName string `json:"-"`
+}
+
+// CompanyPage is the data rendered by the company template.
+type CompanyPage struct {
+	*Company
+	Movies   []Movie
+	PrevPage int // 0 on the first page
+	NextPage int // 0 on the last page
+	Meta     Meta
+	Theme    string
+}
+
+var companyTmpl = pageTemplate("company", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>{{.Name}}</title>
+</head>
+<body>
+    {{template "header" ""}}
+    {{block "logo" .}}{{with .LogoPath}}<img src="{{imageURL "w185" .}}" alt="" width="185">{{end}}{{end}}
+    <h1 dir="auto">{{.Name}}</h1>
+    {{with .OriginCountry}}<p>Country of origin: {{.}}</p>{{end}}
+    {{if .ParentCompanyID}}<p>Part of <a href="/company/{{.ParentCompanyID}}" dir="auto">{{.ParentCompanyName}}</a></p>{{end}}
+    {{with .Description}}<p dir="auto">{{.}}</p>{{end}}
+    <h2>Movies</h2>
+    {{block "movies" .}}
+    <div style="display: flex; flex-wrap: wrap; gap: 1em;">
+        {{range .Movies}}
+        <a href="{{movieURL .ID .Title}}" style="width: 154px;">{{posterImg .PosterPath 154 ""}}<br><span dir="auto">{{.Title}}</span> ({{.ReleaseDate.DisplayYear}})</a>
+        {{else}}
+        <p>No movies found.</p>
+        {{end}}
+    </div>
+    {{end}}
+    <p>
+        {{with .PrevPage}}<a href="?page={{.}}" rel="prev">Newer</a>{{end}}
+        {{with .NextPage}}<a href="?page={{.}}" rel="next">Older</a>{{end}}
+    </p>
+    {{template "footer"}}
+</body>
+</html>
+`, `
+{{define "logo"}}{{end}}
+{{define "movies"}}
+<ol>
+    {{range .Movies}}<li><a href="{{movieURL .ID .Title}}" dir="auto">{{.Title}}</a> ({{.ReleaseDate.DisplayYear}})</li>
+    {{else}}
+    <li>No movies found.</li>
+    {{end}}
+</ol>
+{{end}}
+`)
+
+// companyHandler serves GET /company/{id}?page=N: the company's profile and
+// its movies, newest first.
+func companyHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	if rejectDuplicateParams(w, r, "page") {
+		return
+	}
+	r, cancel := withDeadline(r, config.DetailTimeout)
+	defer cancel()
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/company/"))
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid company ID", http.StatusBadRequest)
+		return
+	}
+	page := 1
+	if raw := r.URL.Query().Get("page"); raw != "" {
+		page, err = strconv.Atoi(raw)
+		if err != nil || page < 1 || page > tmdbMaxPage {
+			http.Error(w, fmt.Sprintf("page must be between 1 and %d", tmdbMaxPage), http.StatusBadRequest)
+			return
+		}
+	}
+
+	start := time.Now()
+	company, err := fetchCompany(r.Context(), id, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_company", start)
+	if err != nil {
+		log.Printf("Error fetching company %d: %v", id, err)
+		tmdbFailure(w, r, err, "Failed to fetch company")
+		return
+	}
+	// TMDB answers unknown IDs with an error object, which decodes to an
+	// empty company.
+	if company.ID == 0 {
+		http.Error(w, "Company not found", http.StatusNotFound)
+		return
+	}
+
+	start = time.Now()
+	results, err := fetchCompanyMovies(r.Context(), id, page, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_discover", start)
+	if err != nil {
+		log.Printf("Error fetching movies of company %d: %v", id, err)
+		tmdbFailure(w, r, err, "Failed to fetch company movies")
+		return
+	}
+
+	data := CompanyPage{Company: company, Movies: results.Results, Meta: pageMeta("/company/"), Theme: theme(r)}
+	if page > 1 {
+		data.PrevPage = page - 1
+	}
+	if page < min(results.TotalPages, tmdbMaxPage) {
+		data.NextPage = page + 1
+	}
+
+	body, err := render(r, companyTmpl, data)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, detailResponse)
+	body.WriteTo(w)
+}
+
+func fetchCompany(ctx context.Context, id int, tmdb *TMDB) (*Company, error) {
+	requestURL := fmt.Sprintf("%s%s%d?api_key=%s", tmdb.BaseURL, companyEndpoint, id, tmdb.APIKey)
+	var response struct {
+		Company
+		ParentCompany *struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"parent_company"`
+	}
+	if err := tmdbGet(ctx, tmdb, requestURL, &response); err != nil {
+		return nil, err
+	}
+
+	company := response.Company
+	if parent := response.ParentCompany; parent != nil {
+		company.ParentCompanyID = parent.ID
+		company.ParentCompanyName = parent.Name
+	}
+	return &company, nil
+}
+
+func fetchCompanyMovies(ctx context.Context, id int, page int, tmdb *TMDB) (*SearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s&with_companies=%d&sort_by=release_date.desc&page=%d",
+		tmdb.BaseURL, discoverEndpoint, tmdb.APIKey, id, page)
+	var results SearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
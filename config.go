@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"module/internal/envvars"
+)
+
+// LoadConfig reads the configuration from the environment, applies the
+// defaults documented in the README and validates it. main loads .env files
+// before calling it.
+func LoadConfig() (Config, error) {
+	var env envReader
+	config := Config{
+		APIKey:          getenv("TMDB_API_KEY"),
+		TMDBBaseURL:     strings.TrimSuffix(envString("TMDB_BASE_URL", defaultBaseURL), "/"),
+		Region:          strings.ToUpper(envString("WATCH_REGION", "US")),
+		CachePolicies:   loadCachePolicies(),
+		ErrorWebhookURL: getenv("ERROR_WEBHOOK_URL"),
+		MaxTitleLength:  env.int("TITLE_MAX_LENGTH", defaultMaxTitleLength),
+	}
+	if config.APIKey == "" {
+		return Config{}, errors.New("API key not set in TMDB_API_KEY environment variable")
+	}
+	if config.MaxTitleLength < 0 || config.MaxTitleLength == 1 {
+		return Config{}, fmt.Errorf("invalid TITLE_MAX_LENGTH %d: must be 0 to disable shortening, or at least 2", config.MaxTitleLength)
+	}
+	var err error
+	config.URLs, err = NewURLBuilder(envString("BASE_URL", "http://localhost:8080"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid BASE_URL: %w", err)
+	}
+
+	config.IncludeAdult = env.bool("INCLUDE_ADULT", false)
+	config.AdultContentLocked = env.bool("ADULT_CONTENT_LOCKED", false)
+	config.ExcludeVideos = env.bool("EXCLUDE_VIDEOS", false)
+	config.SearchAutoRedirect = env.bool("SEARCH_AUTO_REDIRECT", false)
+	config.SpoilerFreeDefault = env.bool("SPOILER_FREE_DEFAULT", false)
+	config.Timezone, err = loadTimezone(getenv("TIMEZONE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TIMEZONE: %w", err)
+	}
+	if sort := getenv("RESULT_SORT"); sort != "" && sort != "relevance" {
+		if _, ok := resultSorts[sort]; !ok {
+			return Config{}, fmt.Errorf("invalid RESULT_SORT %q: must be relevance, year, title or rating", sort)
+		}
+		config.ResultSort = sort
+	}
+	config.WidgetCORSOrigin = envString("WIDGET_CORS_ORIGIN", "*")
+	config.ContentAdvisory = env.bool("CONTENT_ADVISORY", true)
+	config.MaxConcurrentRequests = env.int("MAX_CONCURRENT_REQUESTS", 0)
+	config.RequestQueueDepth = env.int("REQUEST_QUEUE_DEPTH", 100)
+	config.RequestQueueTimeout = env.duration("REQUEST_QUEUE_TIMEOUT", 5*time.Second)
+	if config.MaxConcurrentRequests < 0 {
+		return Config{}, fmt.Errorf("invalid MAX_CONCURRENT_REQUESTS %d: must not be negative", config.MaxConcurrentRequests)
+	}
+	if config.RequestQueueDepth < 0 {
+		return Config{}, fmt.Errorf("invalid REQUEST_QUEUE_DEPTH %d: must not be negative", config.RequestQueueDepth)
+	}
+	if config.RequestQueueTimeout <= 0 {
+		return Config{}, fmt.Errorf("invalid REQUEST_QUEUE_TIMEOUT %s: must be positive", config.RequestQueueTimeout)
+	}
+	config.TMDBTimeout = env.duration("TMDB_TIMEOUT", defaultTMDBTimeout)
+	config.SearchTimeout = env.duration("SEARCH_TIMEOUT", config.TMDBTimeout)
+	config.DetailTimeout = env.duration("DETAIL_TIMEOUT", config.TMDBTimeout)
+
+	config.TMDBAuditLog = getenv("TMDB_AUDIT_LOG")
+	if raw := getenv("TMDB_AUDIT_LOG_LEVEL"); raw != "" {
+		if err := config.TMDBAuditLevel.UnmarshalText([]byte(raw)); err != nil {
+			return Config{}, fmt.Errorf("invalid TMDB_AUDIT_LOG_LEVEL %q: must be debug, info, warn or error", raw)
+		}
+	}
+	config.LogSampleRate = 1.0
+	if raw := getenv("LOG_SAMPLE_RATE"); raw != "" {
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return Config{}, fmt.Errorf("invalid LOG_SAMPLE_RATE %q: must be between 0.0 and 1.0", raw)
+		}
+		config.LogSampleRate = rate
+	}
+	if raw := getenv("LOG_LEVEL"); raw != "" {
+		if err := config.LogLevel.UnmarshalText([]byte(raw)); err != nil {
+			return Config{}, fmt.Errorf("invalid LOG_LEVEL %q: must be debug, info, warn or error", raw)
+		}
+	}
+
+	config.DetailBadges, err = parseDetailBadges(getenv("DETAIL_BADGES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid DETAIL_BADGES: %w", err)
+	}
+	config.RelatedSearches = env.int("RELATED_SEARCHES", 5)
+	config.PreloadPosters = env.int("PRELOAD_POSTERS", 4)
+	config.PosterMinWidth = env.int("POSTER_MIN_WIDTH", 0)
+	config.RecommendationsCount = env.int("RECOMMENDATIONS_COUNT", defaultRecommendationsCount)
+	config.RuntimeShelfCount = env.int("RUNTIME_SHELF_COUNT", defaultRuntimeShelfCount)
+	config.RuntimeShelfBand = env.int("RUNTIME_SHELF_BAND", defaultRuntimeShelfBand)
+	if config.RuntimeShelfBand < 0 {
+		return Config{}, fmt.Errorf("invalid RUNTIME_SHELF_BAND %d: must not be negative", config.RuntimeShelfBand)
+	}
+	config.HomeModules, err = parseHomeModules(getenv("HOME_MODULES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid HOME_MODULES: %w", err)
+	}
+	config.HomepageMovieCount = env.int("HOMEPAGE_MOVIE_COUNT", defaultHomepageMovieCount)
+	if config.HomepageMovieCount < 0 || config.HomepageMovieCount > maxHomepageMovieCount {
+		return Config{}, fmt.Errorf("invalid HOMEPAGE_MOVIE_COUNT %d: must be between 0 and %d", config.HomepageMovieCount, maxHomepageMovieCount)
+	}
+
+	config.TrustedProxies, err = parseTrustedProxies(getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	config.TLSCertFile = getenv("TLS_CERT_FILE")
+	config.TLSKeyFile = getenv("TLS_KEY_FILE")
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return Config{}, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	config.TLSConfig, err = newTLSConfig(getenv("TLS_MIN_VERSION"), getenv("TLS_CIPHER_SUITES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TLS settings: %w", err)
+	}
+
+	config.FeaturedCollections, err = loadFeaturedCollections(getenv("COLLECTIONS_FILE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid COLLECTIONS_FILE: %w", err)
+	}
+	config.CookieKey, err = parseCookieKey(getenv("COOKIE_SECRET"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid COOKIE_SECRET: %w", err)
+	}
+	config.AdminToken = getenv("ADMIN_TOKEN")
+	config.PurgeWebhookURL = getenv("CDN_PURGE_WEBHOOK_URL")
+	if raw := config.PurgeWebhookURL; raw != "" {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid CDN_PURGE_WEBHOOK_URL %q: must be an http or https URL", raw)
+		}
+	}
+	config.ViewCountsFile = getenv("VIEW_COUNTS_FILE")
+	config.ViewCountsFlushInterval = env.duration("VIEW_COUNTS_FLUSH_INTERVAL", defaultViewCountsFlushInterval)
+	if config.ViewCountsFlushInterval <= 0 {
+		return Config{}, fmt.Errorf("invalid VIEW_COUNTS_FLUSH_INTERVAL %s: must be positive", config.ViewCountsFlushInterval)
+	}
+
+	if env.err != nil {
+		return Config{}, env.err
+	}
+	return config, nil
+}
+
+// getenv reads an environment variable listed in envvars.All. Reading one
+// that isn't listed panics, so a new setting can't be added without also
+// reaching the Kubernetes manifests.
+func getenv(name string) string {
+	if !envvars.Listed(name) {
+		panic("environment variable " + name + " is missing from envvars.All")
+	}
+	return os.Getenv(name)
+}
+
+// envString reads a string environment variable, falling back to def when unset.
+func envString(name string, def string) string {
+	if value := getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// envReader reads typed environment variables, falling back to a default
+// when one is unset. It keeps the first malformed value in err, so
+// LoadConfig can read them all and check once.
+type envReader struct {
+	err error
+}
+
+// envParse reads name with parse unless it is unset, recording a parse
+// error in e.
+func envParse[T any](e *envReader, name string, def T, parse func(string) (T, error)) T {
+	raw := getenv(name)
+	if raw == "" {
+		return def
+	}
+	value, err := parse(raw)
+	if err != nil {
+		if e.err == nil {
+			e.err = fmt.Errorf("invalid %s %q: %w", name, raw, err)
+		}
+		return def
+	}
+	return value
+}
+
+func (e *envReader) bool(name string, def bool) bool {
+	return envParse(e, name, def, strconv.ParseBool)
+}
+
+func (e *envReader) int(name string, def int) int {
+	return envParse(e, name, def, strconv.Atoi)
+}
+
+func (e *envReader) duration(name string, def time.Duration) time.Duration {
+	return envParse(e, name, def, time.ParseDuration)
+}
+
+// tmdbAuditLogger opens the TMDB_AUDIT_LOG file for appending and returns a
+// JSON logger writing to it at TMDB_AUDIT_LOG_LEVEL.
+func tmdbAuditLogger(config Config) (*slog.Logger, error) {
+	auditLog, err := os.OpenFile(config.TMDBAuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(slog.NewJSONHandler(auditLog, &slog.HandlerOptions{Level: config.TMDBAuditLevel})), nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/tls"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"module/internal/envvars"
+)
+
+// loadTestConfig runs LoadConfig with only TMDB_API_KEY and env set.
+func loadTestConfig(t *testing.T, env map[string]string) (Config, error) {
+	t.Helper()
+	for _, v := range envvars.All {
+		t.Setenv(v.Name, "")
+	}
+	t.Setenv("TMDB_API_KEY", "test-key")
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	return LoadConfig()
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	config, err := loadTestConfig(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.APIKey != "test-key" || config.TMDBBaseURL != defaultBaseURL || config.Region != "US" {
+		t.Errorf("key %q, TMDB %q, region %q", config.APIKey, config.TMDBBaseURL, config.Region)
+	}
+	if config.MaxTitleLength != defaultMaxTitleLength || config.TMDBTimeout != defaultTMDBTimeout ||
+		config.SearchTimeout != defaultTMDBTimeout || config.DetailTimeout != defaultTMDBTimeout ||
+		config.ViewCountsFlushInterval != defaultViewCountsFlushInterval || config.LogSampleRate != 1 {
+		t.Errorf("unexpected defaults: %+v", config)
+	}
+	if config.IncludeAdult || config.ExcludeVideos || !config.ContentAdvisory || len(config.CookieKey) != cookieKeySize {
+		t.Errorf("unexpected defaults: %+v", config)
+	}
+	if config.TLSConfig.MinVersion != tls.VersionTLS12 || config.TLSConfig.CipherSuites != nil {
+		t.Errorf("TLS defaults to version %x, suites %x", config.TLSConfig.MinVersion, config.TLSConfig.CipherSuites)
+	}
+	if got := config.URLs.Absolute("/"); got != "http://localhost:8080/" {
+		t.Errorf("BASE_URL defaults to %q", got)
+	}
+}
+
+// TestEnvVarDefaults checks that setting a variable to its default from
+// envvars.All, as the rendered ConfigMap does, changes nothing.
+func TestEnvVarDefaults(t *testing.T) {
+	for _, v := range envvars.All {
+		if v.Default == "" {
+			continue
+		}
+		t.Run(v.Name, func(t *testing.T) {
+			unset, err := loadTestConfig(t, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set, err := loadTestConfig(t, map[string]string{v.Name: v.Default})
+			if err != nil {
+				t.Fatal(err)
+			}
+			unset.CookieKey, set.CookieKey = nil, nil // random when unset
+			if !reflect.DeepEqual(set, unset) {
+				t.Errorf("%s=%s gives %+v, want the unset %+v", v.Name, v.Default, set, unset)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(Config) bool // nil when LoadConfig must fail
+		wantErr string
+	}{
+		{"no API key", map[string]string{"TMDB_API_KEY": ""}, nil, "TMDB_API_KEY"},
+
+		{"title length", map[string]string{"TITLE_MAX_LENGTH": "40"}, func(c Config) bool { return c.MaxTitleLength == 40 }, ""},
+		{"title length disabled", map[string]string{"TITLE_MAX_LENGTH": "0"}, func(c Config) bool { return c.MaxTitleLength == 0 }, ""},
+		{"shortest title length", map[string]string{"TITLE_MAX_LENGTH": "2"}, func(c Config) bool { return c.MaxTitleLength == 2 }, ""},
+		{"title length 1", map[string]string{"TITLE_MAX_LENGTH": "1"}, nil, "TITLE_MAX_LENGTH"},
+		{"negative title length", map[string]string{"TITLE_MAX_LENGTH": "-5"}, nil, "TITLE_MAX_LENGTH"},
+		{"malformed title length", map[string]string{"TITLE_MAX_LENGTH": "sixty"}, nil, "TITLE_MAX_LENGTH"},
+
+		{"flush interval", map[string]string{"VIEW_COUNTS_FLUSH_INTERVAL": "30s"}, func(c Config) bool { return c.ViewCountsFlushInterval == 30*time.Second }, ""},
+		{"zero flush interval", map[string]string{"VIEW_COUNTS_FLUSH_INTERVAL": "0s"}, nil, "VIEW_COUNTS_FLUSH_INTERVAL"},
+		{"negative flush interval", map[string]string{"VIEW_COUNTS_FLUSH_INTERVAL": "-1m"}, nil, "VIEW_COUNTS_FLUSH_INTERVAL"},
+		{"flush interval without unit", map[string]string{"VIEW_COUNTS_FLUSH_INTERVAL": "60"}, nil, "VIEW_COUNTS_FLUSH_INTERVAL"},
+
+		{"TMDB base URL trailing slash", map[string]string{"TMDB_BASE_URL": "http://tmdb.test/3/"}, func(c Config) bool { return c.TMDBBaseURL == "http://tmdb.test/3" }, ""},
+		{"region is upper-cased", map[string]string{"WATCH_REGION": "gb"}, func(c Config) bool { return c.Region == "GB" }, ""},
+		{"timeouts follow TMDB_TIMEOUT", map[string]string{"TMDB_TIMEOUT": "3s", "DETAIL_TIMEOUT": "1s"},
+			func(c Config) bool { return c.SearchTimeout == 3*time.Second && c.DetailTimeout == time.Second }, ""},
+		{"invalid BASE_URL", map[string]string{"BASE_URL": "movies.example"}, nil, "BASE_URL"},
+		{"invalid TIMEZONE", map[string]string{"TIMEZONE": "Mars/Olympus_Mons"}, nil, "TIMEZONE"},
+		{"invalid RESULT_SORT", map[string]string{"RESULT_SORT": "budget"}, nil, "RESULT_SORT"},
+		{"sample rate above 1", map[string]string{"LOG_SAMPLE_RATE": "1.5"}, nil, "LOG_SAMPLE_RATE"},
+		{"invalid LOG_LEVEL", map[string]string{"LOG_LEVEL": "loud"}, nil, "LOG_LEVEL"},
+		{"queue without waiting", map[string]string{"MAX_CONCURRENT_REQUESTS": "10", "REQUEST_QUEUE_DEPTH": "0"},
+			func(c Config) bool { return c.MaxConcurrentRequests == 10 && c.RequestQueueDepth == 0 }, ""},
+		{"negative concurrent requests", map[string]string{"MAX_CONCURRENT_REQUESTS": "-1"}, nil, "MAX_CONCURRENT_REQUESTS"},
+		{"negative queue depth", map[string]string{"MAX_CONCURRENT_REQUESTS": "10", "REQUEST_QUEUE_DEPTH": "-1"}, nil, "REQUEST_QUEUE_DEPTH"},
+		{"zero queue timeout", map[string]string{"REQUEST_QUEUE_TIMEOUT": "0s"}, nil, "REQUEST_QUEUE_TIMEOUT"},
+		{"negative queue timeout", map[string]string{"REQUEST_QUEUE_TIMEOUT": "-5s"}, nil, "REQUEST_QUEUE_TIMEOUT"},
+		{"negative runtime band", map[string]string{"RUNTIME_SHELF_BAND": "-1"}, nil, "RUNTIME_SHELF_BAND"},
+		{"too many homepage movies", map[string]string{"HOMEPAGE_MOVIE_COUNT": "1000"}, nil, "HOMEPAGE_MOVIE_COUNT"},
+		{"TLS cert without key", map[string]string{"TLS_CERT_FILE": "cert.pem"}, nil, "TLS_KEY_FILE"},
+		{"TLS 1.3 only", map[string]string{"TLS_MIN_VERSION": "1.3"}, func(c Config) bool { return c.TLSConfig.MinVersion == tls.VersionTLS13 }, ""},
+		{"TLS 1.1", map[string]string{"TLS_MIN_VERSION": "1.1"}, nil, "TLS version"},
+		{"TLS cipher suites", map[string]string{"TLS_CIPHER_SUITES": "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+			func(c Config) bool {
+				return slices.Equal(c.TLSConfig.CipherSuites, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384})
+			}, ""},
+		{"unknown TLS cipher suite", map[string]string{"TLS_CIPHER_SUITES": "TLS_RSA_WITH_RC4_128_SHA"}, nil, "cipher suite"},
+		{"short COOKIE_SECRET", map[string]string{"COOKIE_SECRET": "abcd"}, nil, "COOKIE_SECRET"},
+		{"purge webhook", map[string]string{"CDN_PURGE_WEBHOOK_URL": "https://cdn.example/purge"},
+			func(c Config) bool { return c.PurgeWebhookURL == "https://cdn.example/purge" }, ""},
+		{"relative purge webhook", map[string]string{"CDN_PURGE_WEBHOOK_URL": "/purge"}, nil, "CDN_PURGE_WEBHOOK_URL"},
+		{"purge webhook without http", map[string]string{"CDN_PURGE_WEBHOOK_URL": "ftp://cdn.example/purge"}, nil, "CDN_PURGE_WEBHOOK_URL"},
+		{"invalid TRUSTED_PROXIES", map[string]string{"TRUSTED_PROXIES": "proxy"}, nil, "TRUSTED_PROXIES"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadTestConfig(t, tt.env)
+			if tt.check == nil {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig error = %v, want one about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if !tt.check(config) {
+				t.Errorf("LoadConfig = %+v", config)
+			}
+		})
+	}
+}
+
+func TestEnvReader(t *testing.T) {
+	tests := []struct {
+		raw     string
+		read    func(*envReader) any
+		want    any
+		wantErr bool
+	}{
+		{"", func(e *envReader) any { return e.int("RELATED_SEARCHES", 7) }, 7, false},
+		{"42", func(e *envReader) any { return e.int("RELATED_SEARCHES", 7) }, 42, false},
+		{"-3", func(e *envReader) any { return e.int("RELATED_SEARCHES", 7) }, -3, false},
+		{" 42", func(e *envReader) any { return e.int("RELATED_SEARCHES", 7) }, 7, true},
+		{"4.2", func(e *envReader) any { return e.int("RELATED_SEARCHES", 7) }, 7, true},
+		{"99999999999999999999", func(e *envReader) any { return e.int("RELATED_SEARCHES", 7) }, 7, true},
+		{"", func(e *envReader) any { return e.bool("RELATED_SEARCHES", true) }, true, false},
+		{"false", func(e *envReader) any { return e.bool("RELATED_SEARCHES", true) }, false, false},
+		{"1", func(e *envReader) any { return e.bool("RELATED_SEARCHES", false) }, true, false},
+		{"TRUE", func(e *envReader) any { return e.bool("RELATED_SEARCHES", false) }, true, false},
+		{"yes", func(e *envReader) any { return e.bool("RELATED_SEARCHES", false) }, false, true},
+		{"", func(e *envReader) any { return e.duration("RELATED_SEARCHES", time.Minute) }, time.Minute, false},
+		{"1h30m", func(e *envReader) any { return e.duration("RELATED_SEARCHES", time.Minute) }, 90 * time.Minute, false},
+		{"0", func(e *envReader) any { return e.duration("RELATED_SEARCHES", time.Minute) }, time.Duration(0), false},
+		{"10", func(e *envReader) any { return e.duration("RELATED_SEARCHES", time.Minute) }, time.Minute, true},
+		{"soon", func(e *envReader) any { return e.duration("RELATED_SEARCHES", time.Minute) }, time.Minute, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("RELATED_SEARCHES", tt.raw)
+		var env envReader
+		if got := tt.read(&env); got != tt.want {
+			t.Errorf("RELATED_SEARCHES=%q: read %v, want %v", tt.raw, got, tt.want)
+		}
+		if (env.err != nil) != tt.wantErr {
+			t.Errorf("RELATED_SEARCHES=%q: error %v, want error %v", tt.raw, env.err, tt.wantErr)
+		}
+		if env.err != nil && !strings.Contains(env.err.Error(), `invalid RELATED_SEARCHES "`+tt.raw+`"`) {
+			t.Errorf("RELATED_SEARCHES=%q: error %q does not name the variable and value", tt.raw, env.err)
+		}
+	}
+}
+
+func TestEnvReaderKeepsFirstError(t *testing.T) {
+	t.Setenv("RELATED_SEARCHES", "one")
+	t.Setenv("PRELOAD_POSTERS", "two")
+	var env envReader
+	env.int("RELATED_SEARCHES", 0)
+	env.int("PRELOAD_POSTERS", 0)
+	if env.err == nil || !strings.Contains(env.err.Error(), "RELATED_SEARCHES") {
+		t.Errorf("error = %v, want the one about RELATED_SEARCHES", env.err)
+	}
+}
+
+func TestGetenvUnlisted(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("reading a variable missing from envvars.All didn't panic")
+		}
+	}()
+	getenv("NOT_A_SETTING")
+}
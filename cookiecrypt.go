@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// cookieKeySize is the AES-256 key length COOKIE_SECRET must decode to.
+const cookieKeySize = 32
+
+// parseCookieKey decodes COOKIE_SECRET, 64 hex characters. When it is empty
+// a random key is generated, so encrypted cookies only survive until the
+// next restart and aren't shared between replicas.
+func parseCookieKey(raw string) ([]byte, error) {
+	if raw == "" {
+		key := make([]byte, cookieKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	key, err := hex.DecodeString(raw)
+	if err != nil || len(key) != cookieKeySize {
+		return nil, fmt.Errorf("must be %d hex characters", cookieKeySize*2)
+	}
+	return key, nil
+}
+
+// sealCookie encrypts and authenticates plaintext with AES-GCM and returns
+// it in a form that is safe to use as a cookie value.
+func sealCookie(key, plaintext []byte) (string, error) {
+	aead, err := newCookieAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// openCookie reverses sealCookie. It fails for values that were tampered
+// with or sealed under another key.
+func openCookie(key []byte, value string) ([]byte, error) {
+	aead, err := newCookieAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("cookie too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func newCookieAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const testCookieSecret = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestParseCookieKey(t *testing.T) {
+	key, err := parseCookieKey(testCookieSecret)
+	if err != nil || len(key) != cookieKeySize || key[1] != 1 || key[31] != 0x1f {
+		t.Errorf("parseCookieKey(%q) = %x, %v", testCookieSecret, key, err)
+	}
+
+	first, err := parseCookieKey("")
+	if err != nil || len(first) != cookieKeySize {
+		t.Fatalf(`parseCookieKey("") = %x, %v`, first, err)
+	}
+	if second, _ := parseCookieKey(""); bytes.Equal(first, second) {
+		t.Error("generated the same key twice")
+	}
+
+	for name, raw := range map[string]string{
+		"16 bytes":   testCookieSecret[:32],
+		"33 bytes":   testCookieSecret + "20",
+		"odd length": testCookieSecret[:63],
+		"not hex":    strings.Repeat("zz", cookieKeySize),
+		"base64":     base64.StdEncoding.EncodeToString(make([]byte, cookieKeySize)),
+	} {
+		if key, err := parseCookieKey(raw); err == nil {
+			t.Errorf("%s: parseCookieKey(%q) = %x, want an error", name, raw, key)
+		}
+	}
+}
+
+func TestSealCookieRoundTrip(t *testing.T) {
+	key, _ := parseCookieKey(testCookieSecret)
+	for _, plaintext := range []string{"", "on", strings.Repeat("watchlist:603,", 100)} {
+		sealed, err := sealCookie(key, []byte(plaintext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.ContainsAny(sealed, "+/=;, ") {
+			t.Errorf("sealed %q isn't cookie safe", sealed)
+		}
+		if plaintext != "" && strings.Contains(sealed, plaintext) {
+			t.Errorf("sealed %q shows the plaintext", sealed)
+		}
+		got, err := openCookie(key, sealed)
+		if err != nil || string(got) != plaintext {
+			t.Errorf("openCookie(sealCookie(%q)) = %q, %v", plaintext, got, err)
+		}
+	}
+
+	first, _ := sealCookie(key, []byte("on"))
+	second, _ := sealCookie(key, []byte("on"))
+	if first == second {
+		t.Error("sealing twice gave the same value")
+	}
+}
+
+func TestOpenCookieRejects(t *testing.T) {
+	key, _ := parseCookieKey(testCookieSecret)
+	otherKey, _ := parseCookieKey(strings.Repeat("ff", cookieKeySize))
+	sealed, err := sealCookie(key, []byte("watchlist:603"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(sealed)
+
+	tampered := bytes.Clone(raw)
+	tampered[len(tampered)-1] ^= 1
+	tamperedNonce := bytes.Clone(raw)
+	tamperedNonce[0] ^= 1
+
+	tests := []struct {
+		name  string
+		key   []byte
+		value string
+	}{
+		{"different key", otherKey, sealed},
+		{"tampered ciphertext", key, base64.RawURLEncoding.EncodeToString(tampered)},
+		{"tampered nonce", key, base64.RawURLEncoding.EncodeToString(tamperedNonce)},
+		{"truncated", key, sealed[:len(sealed)-4]},
+		{"shorter than a nonce", key, base64.RawURLEncoding.EncodeToString(raw[:8])},
+		{"empty", key, ""},
+		{"not base64", key, "not*base64!"},
+		{"padded base64", key, base64.URLEncoding.EncodeToString(raw)},
+		{"invalid key length", key[:10], sealed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := openCookie(tt.key, tt.value); err == nil {
+				t.Errorf("openCookie(%q) = %q, want an error", tt.value, got)
+			}
+		})
+	}
+}
+
+func TestSealCookieInvalidKey(t *testing.T) {
+	for _, size := range []int{0, 10, 31, 33} {
+		if sealed, err := sealCookie(make([]byte, size), []byte("on")); err == nil {
+			t.Errorf("sealCookie with a %d byte key = %q, want an error", size, sealed)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+// keyCrewJobs are the crew jobs highlighted on the detail page, in display
+// order, with the label shown for each.
+var keyCrewJobs = []struct {
+	Job   string
+	Label string
+}{
+	{"Director", "Director"},
+	{"Screenplay", "Screenplay"},
+	{"Writer", "Writer"},
+	{"Director of Photography", "Cinematographer"},
+	{"Original Music Composer", "Composer"},
+}
+
+// MovieCredits holds the cast and crew of a movie.
+type MovieCredits struct {
+	Cast []CastMember `json:"cast"`
+	Crew []CrewMember `json:"crew"`
+}
+
+// CastMember is an actor in a movie, ordered by billing.
+type CastMember struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Character string `json:"character"`
+	Order     int    `json:"order"`
+}
+
+// CrewMember is one job a person did on a movie. People with several jobs
+// appear once per job.
+type CrewMember struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Job        string `json:"job"`
+	Department string `json:"department"`
+}
+
+// KeyCrewEntry is a person in the crew section with all of their key jobs.
+type KeyCrewEntry struct {
+	ID    int
+	Name  string
+	Roles []string
+}
+
+// keyCrew picks the key jobs out of the crew. Each person appears once, at
+// the position of their first key job, with every key job they held; a
+// director who also wrote the screenplay is listed as "Director, Screenplay".
+func keyCrew(crew []CrewMember) []KeyCrewEntry {
+	var entries []KeyCrewEntry
+	index := map[int]int{}
+	for _, job := range keyCrewJobs {
+		for _, member := range crew {
+			if member.Job != job.Job {
+				continue
+			}
+			i, ok := index[member.ID]
+			if !ok {
+				i = len(entries)
+				index[member.ID] = i
+				entries = append(entries, KeyCrewEntry{ID: member.ID, Name: member.Name})
+			}
+			if roles := entries[i].Roles; len(roles) == 0 || roles[len(roles)-1] != job.Label {
+				entries[i].Roles = append(entries[i].Roles, job.Label)
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// pageETag returns the ETag of a rendered page: a hash of its body, so
+// anything that changes the page, from TMDB's data to the view count or
+// the visitor's theme, changes the tag.
+func pageETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// etagMatches reports whether r's If-None-Match lists etag, meaning the
+// browser's copy is current. Weak tags compare by their value, as
+// If-None-Match requires.
+func etagMatches(r *http.Request, etag string) bool {
+	for _, header := range r.Header.Values("If-None-Match") {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"0123456789abcdef"`
+	tests := []struct {
+		header []string
+		want   bool
+	}{
+		{nil, false},
+		{[]string{etag}, true},
+		{[]string{`W/` + etag}, true},
+		{[]string{`"other", ` + etag}, true},
+		{[]string{`"other"`, etag}, true},
+		{[]string{"*"}, true},
+		{[]string{`"other"`}, false},
+		{[]string{`0123456789abcdef`}, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, value := range tt.header {
+			r.Header.Add("If-None-Match", value)
+		}
+		if got := etagMatches(r, etag); got != tt.want {
+			t.Errorf("If-None-Match %q: etagMatches = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestDetailPageETag(t *testing.T) {
+	fake := newFixtureTMDB(t)
+	app := newTestApp(t, fake)
+
+	// revisit requests the page with the ETag of an earlier response and,
+	// when set, a theme cookie.
+	revisit := func(etag, theme string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/movie/the-matrix-603", nil)
+		req.Header.Set("If-None-Match", etag)
+		if theme != "" {
+			req.AddCookie(&http.Cookie{Name: themeCookie, Value: theme})
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get(app, "/movie/the-matrix-603")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first visit = %d with ETag %q", first.Code, etag)
+	}
+	if first.Header().Get("Last-Modified") != "" {
+		t.Error("Last-Modified is sent, but the page isn't dated by anything")
+	}
+
+	unchanged := revisit(etag, "")
+	if unchanged.Code != http.StatusNotModified || unchanged.Body.Len() != 0 {
+		t.Fatalf("revisit = %d with a %d byte body, want 304 and none", unchanged.Code, unchanged.Body.Len())
+	}
+	if unchanged.Header().Get("ETag") != etag || unchanged.Header().Get("Vary") != "Cookie" || unchanged.Header().Get("Cache-Control") != first.Header().Get("Cache-Control") {
+		t.Errorf("304 headers %v differ from the page's %v", unchanged.Header(), first.Header())
+	}
+
+	// The theme changes the page, and so the tag.
+	if themed := revisit(etag, "dark"); themed.Code != http.StatusOK || themed.Header().Get("ETag") == etag {
+		t.Errorf("revisit with another theme = %d with ETag %s, want 200 and a new tag", themed.Code, themed.Header().Get("ETag"))
+	}
+
+	// So does new data from TMDB.
+	fake.handle("/movie/603", fakeResponse{Body: strings.Replace(movieMatrixJSON, `"runtime": 136`, `"runtime": 137`, 1)})
+	app = newTestApp(t, fake)
+	if changed := revisit(etag, ""); changed.Code != http.StatusOK {
+		t.Errorf("revisit after TMDB changed = %d, want 200", changed.Code)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// homeModulesCookie holds the visitor's home page modules, in display order,
+// or "none" when they turned them all off.
+const homeModulesCookie = "home_modules"
+
+// homeModule is one optional section of the home page. render fetches its
+// own data and returns the section's HTML, or "" when it has nothing to
+// show.
+type homeModule struct {
+	Title  string // name shown in the settings
+	render func(ctx context.Context, config Config, textOnly bool) (template.HTML, error)
+}
+
+// homeModules are the modules the home page can show, by name.
+var homeModules = map[string]homeModule{
+	"popular": {Title: "Popular now", render: func(ctx context.Context, config Config, textOnly bool) (template.HTML, error) {
+		if config.HomepageMovieCount == 0 {
+			return "", nil
+		}
+		return popularStrip(ctx, config.HomepageMovieCount, config.TMDB, textOnly)
+	}},
+	"trending": {Title: "Trending this week", render: func(ctx context.Context, config Config, textOnly bool) (template.HTML, error) {
+		start := time.Now()
+		results, err := fetchTrending(ctx, "week", config.TMDB)
+		RecordTiming(ctx, "tmdb_trending", start)
+		if err != nil {
+			return "", err
+		}
+		return renderModuleStrip("Trending this week", results.Results, config.HomepageMovieCount, textOnly)
+	}},
+	"cinema": {Title: "In cinemas", render: func(ctx context.Context, config Config, textOnly bool) (template.HTML, error) {
+		sections, err := cinemaSections(ctx, config.Region, localNow(config), config.TMDB)
+		if err != nil || len(sections) == 0 {
+			return "", err
+		}
+		return renderModuleStrip(sections[0].Title+" in cinemas", sections[0].Movies, config.HomepageMovieCount, textOnly)
+	}},
+}
+
+// defaultHomeModules are shown unless HOME_MODULES says otherwise.
+var defaultHomeModules = []string{"popular"}
+
+var moduleStripTmpl = pageTemplate("module", `
+<section>
+    <h2>{{.Title}}</h2>
+    {{template "strip" .Movies}}
+</section>
+`, "")
+
+// renderModuleStrip renders the first count movies as a titled strip, or
+// nothing when there are none to show.
+func renderModuleStrip(title string, movies []Movie, count int, textOnly bool) (template.HTML, error) {
+	movies = movies[:min(len(movies), count)]
+	if len(movies) == 0 {
+		return "", nil
+	}
+	var body bytes.Buffer
+	data := struct {
+		Title  string
+		Movies []Movie
+	}{title, movies}
+	if err := moduleStripTmpl.variant(textOnly).Execute(&body, data); err != nil {
+		return "", err
+	}
+	return template.HTML(body.String()), nil
+}
+
+// renderHomeModules renders the named modules concurrently, all bounded by
+// ctx's deadline, and returns their sections in order. A module that fails
+// is logged and left out, so it never breaks the page.
+func renderHomeModules(ctx context.Context, names []string, config Config, textOnly bool) []template.HTML {
+	rendered := make([]template.HTML, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		module, ok := homeModules[name]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			section, err := module.render(ctx, config, textOnly)
+			if err != nil {
+				log.Printf("Error rendering home module %s: %v", name, err)
+				return
+			}
+			rendered[i] = section
+		}()
+	}
+	wg.Wait()
+	return slices.DeleteFunc(rendered, func(section template.HTML) bool { return section == "" })
+}
+
+// parseHomeModules parses a comma-separated list of module names, such as
+// HOME_MODULES. "none" selects no modules; an empty list the defaults.
+func parseHomeModules(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	switch raw {
+	case "":
+		return defaultHomeModules, nil
+	case "none":
+		return []string{}, nil
+	}
+	var names []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := homeModules[name]; !ok {
+			return nil, fmt.Errorf("unknown home module %q", name)
+		}
+		if slices.Contains(names, name) {
+			return nil, fmt.Errorf("home module %q listed twice", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// chosenHomeModules returns the modules the visitor picked at /settings, or
+// byDefault (HOME_MODULES) when they haven't picked or the cookie can't be
+// read. Pages that depend on it must send Vary: Cookie.
+func chosenHomeModules(r *http.Request, byDefault []string) []string {
+	cookie, err := r.Cookie(homeModulesCookie)
+	if err != nil {
+		return byDefault
+	}
+	names, err := parseHomeModules(cookie.Value)
+	if err != nil || cookie.Value == "" {
+		return byDefault
+	}
+	return names
+}
+
+// HomeModuleSetting is one module's row in the settings form.
+type HomeModuleSetting struct {
+	Name     string
+	Title    string
+	Enabled  bool
+	Position int // 1-based
+}
+
+// homeModuleSettings lists every module for the settings form: the enabled
+// ones in order, then the rest by name.
+func homeModuleSettings(enabled []string) []HomeModuleSetting {
+	var settings []HomeModuleSetting
+	for _, name := range enabled {
+		settings = append(settings, HomeModuleSetting{Name: name, Title: homeModules[name].Title, Enabled: true})
+	}
+	var disabled []string
+	for name := range homeModules {
+		if !slices.Contains(enabled, name) {
+			disabled = append(disabled, name)
+		}
+	}
+	slices.Sort(disabled)
+	for _, name := range disabled {
+		settings = append(settings, HomeModuleSetting{Name: name, Title: homeModules[name].Title})
+	}
+	for i := range settings {
+		settings[i].Position = i + 1
+	}
+	return settings
+}
+
+// homeModulesFromForm returns the home_modules cookie value for the settings
+// form: the ticked home_module boxes, ordered by their home_position_{name}
+// fields. Ties and missing positions keep the form's order.
+func homeModulesFromForm(r *http.Request) (string, error) {
+	if err := r.ParseForm(); err != nil {
+		return "", err
+	}
+	enabled := slices.Clone(r.PostForm["home_module"])
+	if len(enabled) == 0 {
+		return "none", nil
+	}
+	position := func(name string) int {
+		p, err := strconv.Atoi(r.PostFormValue("home_position_" + name))
+		if err != nil {
+			return math.MaxInt
+		}
+		return p
+	}
+	slices.SortStableFunc(enabled, func(a, b string) int { return cmp.Compare(position(a), position(b)) })
+	value := strings.Join(enabled, ",")
+	if _, err := parseHomeModules(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
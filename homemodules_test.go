@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// moduleTestConfig returns the config of a new app calling fake, with
+// empty caches.
+func moduleTestConfig(t *testing.T, fake *fakeTMDB) Config {
+	t.Helper()
+	return newTestApp(t, fake).config
+}
+
+// setHomeModules replaces the registry until the test ends.
+func setHomeModules(t *testing.T, modules map[string]homeModule) {
+	t.Helper()
+	saved := homeModules
+	homeModules = modules
+	t.Cleanup(func() { homeModules = saved })
+}
+
+func TestHomeModules(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/movie/popular":       searchMatrixJSON,
+		"/trending/movie/week": searchMatrixJSON,
+		"/discover/movie":      searchMatrixJSON,
+	})
+	for name, module := range homeModules {
+		t.Run(name, func(t *testing.T) {
+			section, err := module.render(context.Background(), moduleTestConfig(t, fake), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(section), `href="/movie/the-matrix-603"`) || !strings.Contains(string(section), "<img") {
+				t.Errorf("section doesn't show the movies with posters:\n%s", section)
+			}
+
+			text, err := module.render(context.Background(), moduleTestConfig(t, fake), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(text), "The Matrix") || strings.Contains(string(text), "<img") {
+				t.Errorf("text-only section:\n%s", text)
+			}
+		})
+	}
+}
+
+func TestHomeModulesWhenTMDBIsDown(t *testing.T) {
+	fake := newFakeTMDB(t, nil)
+	for _, path := range []string{"/movie/popular", "/trending/movie/week", "/discover/movie"} {
+		fake.handle(path, fakeResponse{Status: http.StatusServiceUnavailable, Body: `{"status_code":9}`})
+	}
+	app := newTestApp(t, fake)
+
+	for name, module := range homeModules {
+		if section, err := module.render(context.Background(), app.config, false); err == nil {
+			t.Errorf("%s: rendered %q with TMDB down", name, section)
+		}
+	}
+	// The home page itself still renders, without the sections.
+	if rec := get(app, "/"); rec.Code != http.StatusOK {
+		t.Errorf("home page = %d with every module failing", rec.Code)
+	}
+}
+
+func TestRenderHomeModules(t *testing.T) {
+	section := func(html template.HTML, err error) homeModule {
+		return homeModule{Title: string(html), render: func(context.Context, Config, bool) (template.HTML, error) { return html, err }}
+	}
+	setHomeModules(t, map[string]homeModule{
+		"a":      section("<p>a</p>", nil),
+		"b":      section("<p>b</p>", nil),
+		"empty":  section("", nil),
+		"broken": section("<p>partial</p>", errors.New("TMDB timed out")),
+	})
+
+	got := renderHomeModules(context.Background(), []string{"b", "broken", "empty", "unknown", "a"}, Config{}, false)
+	if want := []template.HTML{"<p>b</p>", "<p>a</p>"}; !slices.Equal(got, want) {
+		t.Errorf("renderHomeModules = %q, want %q", got, want)
+	}
+	if got := renderHomeModules(context.Background(), nil, Config{}, false); len(got) != 0 {
+		t.Errorf("no modules rendered %q", got)
+	}
+}
+
+func TestRenderHomeModulesSharesDeadline(t *testing.T) {
+	setHomeModules(t, map[string]homeModule{
+		"slow": {render: func(ctx context.Context, _ Config, _ bool) (template.HTML, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}},
+		"fast": {render: func(context.Context, Config, bool) (template.HTML, error) { return "<p>fast</p>", nil }},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got := renderHomeModules(ctx, []string{"slow", "fast"}, Config{}, false)
+	if want := []template.HTML{"<p>fast</p>"}; !slices.Equal(got, want) {
+		t.Errorf("renderHomeModules = %q, want %q", got, want)
+	}
+}
+
+func TestParseHomeModules(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{"", defaultHomeModules, false},
+		{"  ", defaultHomeModules, false},
+		{"none", []string{}, false},
+		{"trending", []string{"trending"}, false},
+		{"cinema, popular ,trending", []string{"cinema", "popular", "trending"}, false},
+		{"popular,unknown", nil, true},
+		{"popular,popular", nil, true},
+		{"popular,", nil, true},
+		{"Popular", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseHomeModules(tt.raw)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("parseHomeModules(%q) = %q, %v; want %q, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestChosenHomeModules(t *testing.T) {
+	byDefault := []string{"popular"}
+	tests := []struct {
+		cookie string // "" for no cookie
+		want   []string
+	}{
+		{"", byDefault},
+		{"trending,cinema", []string{"trending", "cinema"}},
+		{"none", []string{}},
+		{"bogus", byDefault},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: homeModulesCookie, Value: tt.cookie})
+		}
+		if got := chosenHomeModules(req, byDefault); !slices.Equal(got, tt.want) {
+			t.Errorf("cookie %q: chosenHomeModules = %q, want %q", tt.cookie, got, tt.want)
+		}
+	}
+}
+
+func TestHomeModuleSettings(t *testing.T) {
+	got := homeModuleSettings([]string{"trending", "popular"})
+	want := []HomeModuleSetting{
+		{Name: "trending", Title: "Trending this week", Enabled: true, Position: 1},
+		{Name: "popular", Title: "Popular now", Enabled: true, Position: 2},
+		{Name: "cinema", Title: "In cinemas", Position: 3},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("homeModuleSettings = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestHomeModulesFromForm(t *testing.T) {
+	tests := []struct {
+		form    url.Values
+		want    string
+		wantErr bool
+	}{
+		{url.Values{}, "none", false},
+		{url.Values{"home_module": {"popular", "cinema"}}, "popular,cinema", false},
+		{url.Values{
+			"home_module":            {"popular", "cinema", "trending"},
+			"home_position_popular":  {"3"},
+			"home_position_cinema":   {"1"},
+			"home_position_trending": {"2"},
+		}, "cinema,trending,popular", false},
+		{url.Values{
+			"home_module":           {"popular", "cinema", "trending"},
+			"home_position_popular": {"1"},
+			"home_position_cinema":  {"x"},
+		}, "popular,cinema,trending", false},
+		{url.Values{"home_module": {"popular", "unknown"}}, "", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/settings", strings.NewReader(tt.form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		got, err := homeModulesFromForm(req)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("form %v: homeModulesFromForm = %q, %v; want %q, error %v", tt.form, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// icsEventStart is the local time of day release events start at. TMDB
+	// only knows the day, so events sit in the evening, when a premiere
+	// screening is most likely.
+	icsEventStart = 19 * time.Hour
+	// icsEventDuration approximates a movie's running time.
+	icsEventDuration = 2 * time.Hour
+	// icsLineLimit is the longest content line RFC 5545 allows, in octets.
+	icsLineLimit = 75
+)
+
+// collectionCalendarHandler serves GET /collection/{id}/export.ics: an
+// iCalendar file with an event for every movie of the collection that has a
+// release date still to come.
+func collectionCalendarHandler(w http.ResponseWriter, r *http.Request, config Config, id int) {
+	start := time.Now()
+	collection, err := fetchCollection(r.Context(), id, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_collection", start)
+	if err != nil {
+		log.Printf("Error fetching collection %d: %v", id, err)
+		tmdbFailure(w, r, err, "Failed to fetch collection")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ics"`, Slug(collection.Name, collection.ID)))
+	setCacheControl(w, config, widgetResponse)
+	writeCollectionCalendar(w, collection, config.URLs, localNow(config))
+}
+
+// writeCollectionCalendar encodes the collection's upcoming releases as a
+// VCALENDAR. Movies without a full release date, or released before today
+// in now's location, are left out.
+func writeCollectionCalendar(w io.Writer, collection *Collection, urls URLBuilder, now time.Time) {
+	var cal bytes.Buffer
+	line := func(name, value string) { writeICSLine(&cal, name, value) }
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//movie-finder//collection releases//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", escapeICSText(collection.Name))
+	for _, movie := range collection.Parts {
+		release := movie.ReleaseDate.Time()
+		if release.IsZero() || release.Before(today) {
+			continue
+		}
+		tmdbLink := fmt.Sprintf("https://www.themoviedb.org/movie/%d", movie.ID)
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("movie-%d@movie-finder", movie.ID))
+		line("DTSTAMP", now.UTC().Format("20060102T150405Z"))
+		// A floating time, so the event is in the evening wherever the
+		// subscriber is.
+		line("DTSTART", release.Add(icsEventStart).Format("20060102T150405"))
+		line("DURATION", fmt.Sprintf("PT%dH", int(icsEventDuration.Hours())))
+		line("SUMMARY", escapeICSText(movie.Title))
+		line("DESCRIPTION", escapeICSText(fmt.Sprintf("%s is released.\nTMDB: %s", movie.Title, tmdbLink)))
+		line("URL", urls.Movie(movie.ID, movie.Title))
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	cal.WriteTo(w)
+}
+
+// writeICSLine writes a CRLF-terminated content line, folding it after
+// icsLineLimit octets without splitting a UTF-8 sequence.
+func writeICSLine(b *bytes.Buffer, name, value string) {
+	content := name + ":" + value
+	limit := icsLineLimit
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		limit = icsLineLimit - 1 // continuation lines start with a space
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+}
+
+// isRuneStart reports whether c can begin a UTF-8 sequence.
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+// icsTextEscaper escapes the characters RFC 5545 reserves in TEXT values.
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escapeICSText escapes a TEXT property value such as SUMMARY.
+func escapeICSText(s string) string {
+	return icsTextEscaper.Replace(s)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// imageBaseURL is TMDB's image CDN. A size (e.g. "w92") and the image's
+// file path are appended to it.
+const imageBaseURL = "https://image.tmdb.org/t/p/"
+
+const configurationEndpoint = "/configuration"
+
+// imageURL builds a CDN URL for an image path at the given size, or returns
+// "" when the path is empty.
+func imageURL(size string, path string) string {
+	if path == "" {
+		return ""
+	}
+	return imageBaseURL + size + path
+}
+
+// posterWidths are the fixed-width poster sizes TMDB serves, smallest first.
+// main replaces them with configurePosterWidths at startup.
+var posterWidths = []int{92, 154, 185, 342, 500, 780}
+
+// configurePosterWidths sets the poster sizes srcsets offer to widths, or
+// the built-in ones when widths is empty, leaving out those narrower than
+// minWidth (POSTER_MIN_WIDTH). The widest size is always kept.
+func configurePosterWidths(widths []int, minWidth int) {
+	if len(widths) == 0 {
+		widths = posterWidths
+	}
+	var kept []int
+	for _, w := range widths {
+		if w >= minWidth {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == 0 {
+		kept = widths[len(widths)-1:]
+	}
+	posterWidths = kept
+}
+
+// fetchPosterWidths returns the fixed-width poster sizes listed in TMDB's
+// image configuration, smallest first. Sizes other than wN, such as
+// "original", are skipped.
+func fetchPosterWidths(ctx context.Context, tmdb *TMDB) ([]int, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s", tmdb.BaseURL, configurationEndpoint, tmdb.APIKey)
+	var configuration struct {
+		Images struct {
+			PosterSizes []string `json:"poster_sizes"`
+		} `json:"images"`
+	}
+	if err := tmdbGet(ctx, tmdb, requestURL, &configuration); err != nil {
+		return nil, err
+	}
+
+	var widths []int
+	for _, size := range configuration.Images.PosterSizes {
+		if w, err := strconv.Atoi(strings.TrimPrefix(size, "w")); err == nil && strings.HasPrefix(size, "w") && w > 0 {
+			widths = append(widths, w)
+		}
+	}
+	if len(widths) == 0 {
+		return nil, fmt.Errorf("no poster sizes in the TMDB configuration")
+	}
+	slices.Sort(widths)
+	return widths, nil
+}
+
+// posterPlaceholder is shown in place of a missing poster. It is a plain
+// grey box in the poster's 2:3 ratio, so the layout doesn't change.
+const posterPlaceholder = `data:image/svg+xml,%3Csvg xmlns='http://www.w3.org/2000/svg' viewBox='0 0 2 3'%3E%3Crect width='2' height='3' fill='%23ccc'/%3E%3C/svg%3E`
+
+// posterImg renders a lazily loaded poster <img> for display at width CSS
+// pixels. The srcset offers every TMDB size up to the first one covering
+// twice that width, for high-density screens, and the explicit width and
+// height reserve the space before the image loads.
+func posterImg(path string, width int, alt string) template.HTML {
+	height := width * 3 / 2
+	if path == "" {
+		return template.HTML(fmt.Sprintf(`<img src="%s" width="%d" height="%d" alt="%s">`,
+			posterPlaceholder, width, height, template.HTMLEscapeString(alt)))
+	}
+
+	src, srcset := posterSources(path, width)
+	return template.HTML(fmt.Sprintf(`<img src="%s" srcset="%s" sizes="%dpx" width="%d" height="%d" loading="lazy" alt="%s">`,
+		src, srcset, width, width, height, template.HTMLEscapeString(alt)))
+}
+
+// posterPreload renders a <link rel="preload"> for a poster shown with
+// posterImg at the same width. It offers the same srcset, so the browser
+// preloads exactly the file the <img> will ask for. Missing posters need
+// no preloading.
+func posterPreload(path string, width int) template.HTML {
+	if path == "" {
+		return ""
+	}
+	src, srcset := posterSources(path, width)
+	return template.HTML(fmt.Sprintf(`<link rel="preload" as="image" href="%s" imagesrcset="%s" imagesizes="%dpx">`,
+		src, srcset, width))
+}
+
+// posterSources returns the HTML-escaped src and srcset for a poster shown
+// at width CSS pixels.
+func posterSources(path string, width int) (src, srcset string) {
+	var sources []string
+	for _, w := range posterWidths {
+		url := template.HTMLEscapeString(imageURL(fmt.Sprintf("w%d", w), path))
+		sources = append(sources, fmt.Sprintf("%s %dw", url, w))
+		if src == "" && w >= width {
+			src = url
+		}
+		if w >= 2*width {
+			break
+		}
+	}
+	if src == "" {
+		src = template.HTMLEscapeString(imageURL(fmt.Sprintf("w%d", posterWidths[len(posterWidths)-1]), path))
+	}
+	return src, strings.Join(sources, ", ")
+}
+
+// logoImg renders a company logo at text height.
+func logoImg(path string) template.HTML {
+	return template.HTML(fmt.Sprintf(`<img src="%s" alt="" height="24">`, template.HTMLEscapeString(imageURL("w92", path))))
+}
+
+// preloadPosters returns the poster paths of the first n movies that have a
+// poster, for posterPreload.
+func preloadPosters(movies []Movie, n int) []string {
+	var paths []string
+	for _, movie := range movies {
+		if len(paths) >= n {
+			break
+		}
+		if movie.PosterPath != "" {
+			paths = append(paths, movie.PosterPath)
+		}
+	}
+	return paths
+}
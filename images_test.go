@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// setPosterWidths makes srcsets offer widths until the test ends.
+func setPosterWidths(t *testing.T, widths []int) {
+	t.Helper()
+	saved := posterWidths
+	posterWidths = widths
+	t.Cleanup(func() { posterWidths = saved })
+}
+
+func TestPosterImg(t *testing.T) {
+	setPosterWidths(t, []int{92, 154, 185, 342, 500, 780})
+
+	tests := []struct {
+		name  string
+		path  string
+		width int
+		alt   string
+		want  string
+	}{
+		{
+			"search result thumbnail",
+			"/matrix.jpg", 46, "",
+			`<img src="https://image.tmdb.org/t/p/w92/matrix.jpg" srcset="https://image.tmdb.org/t/p/w92/matrix.jpg 92w" sizes="46px" width="46" height="69" loading="lazy" alt="">`,
+		},
+		{
+			"detail page poster",
+			"/matrix.jpg", 300, "The Matrix",
+			`<img src="https://image.tmdb.org/t/p/w342/matrix.jpg" srcset="https://image.tmdb.org/t/p/w92/matrix.jpg 92w, https://image.tmdb.org/t/p/w154/matrix.jpg 154w, https://image.tmdb.org/t/p/w185/matrix.jpg 185w, https://image.tmdb.org/t/p/w342/matrix.jpg 342w, https://image.tmdb.org/t/p/w500/matrix.jpg 500w, https://image.tmdb.org/t/p/w780/matrix.jpg 780w" sizes="300px" width="300" height="450" loading="lazy" alt="The Matrix">`,
+		},
+		{
+			"wider than every size",
+			"/matrix.jpg", 1000, "",
+			`<img src="https://image.tmdb.org/t/p/w780/matrix.jpg" srcset="https://image.tmdb.org/t/p/w92/matrix.jpg 92w, https://image.tmdb.org/t/p/w154/matrix.jpg 154w, https://image.tmdb.org/t/p/w185/matrix.jpg 185w, https://image.tmdb.org/t/p/w342/matrix.jpg 342w, https://image.tmdb.org/t/p/w500/matrix.jpg 500w, https://image.tmdb.org/t/p/w780/matrix.jpg 780w" sizes="1000px" width="1000" height="1500" loading="lazy" alt="">`,
+		},
+		{
+			"escaping",
+			`/a&b".jpg`, 46, `Tom & Jerry "The Movie"`,
+			`<img src="https://image.tmdb.org/t/p/w92/a&amp;b&#34;.jpg" srcset="https://image.tmdb.org/t/p/w92/a&amp;b&#34;.jpg 92w" sizes="46px" width="46" height="69" loading="lazy" alt="Tom &amp; Jerry &#34;The Movie&#34;">`,
+		},
+		{
+			"missing poster",
+			"", 300, "<Untitled>",
+			`<img src="` + posterPlaceholder + `" width="300" height="450" alt="&lt;Untitled&gt;">`,
+		},
+	}
+	for _, tt := range tests {
+		if got := string(posterImg(tt.path, tt.width, tt.alt)); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPosterPreloadMatchesImg(t *testing.T) {
+	setPosterWidths(t, []int{92, 185, 342})
+
+	want := `<link rel="preload" as="image" href="https://image.tmdb.org/t/p/w185/dune.jpg" imagesrcset="https://image.tmdb.org/t/p/w92/dune.jpg 92w, https://image.tmdb.org/t/p/w185/dune.jpg 185w, https://image.tmdb.org/t/p/w342/dune.jpg 342w" imagesizes="150px">`
+	if got := string(posterPreload("/dune.jpg", 150)); got != want {
+		t.Errorf("posterPreload:\n got %s\nwant %s", got, want)
+	}
+	if got := posterPreload("", 150); got != "" {
+		t.Errorf("missing poster is preloaded: %s", got)
+	}
+}
+
+func TestConfigurePosterWidths(t *testing.T) {
+	tests := []struct {
+		name     string
+		widths   []int
+		minWidth int
+		want     []int
+	}{
+		{"TMDB sizes", []int{92, 154, 185, 342, 500, 780}, 0, []int{92, 154, 185, 342, 500, 780}},
+		{"minimum width", []int{92, 154, 185, 342, 500, 780}, 185, []int{185, 342, 500, 780}},
+		{"minimum above every size keeps the widest", []int{92, 154}, 300, []int{154}},
+		{"built-in sizes", nil, 500, []int{500, 780}},
+	}
+	for _, tt := range tests {
+		setPosterWidths(t, []int{92, 154, 185, 342, 500, 780})
+		configurePosterWidths(tt.widths, tt.minWidth)
+		if !slices.Equal(posterWidths, tt.want) {
+			t.Errorf("%s: posterWidths = %v, want %v", tt.name, posterWidths, tt.want)
+		}
+	}
+}
+
+func TestFetchPosterWidths(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/configuration": `{"images":{"poster_sizes":["w500","w92","original","w154","h632","wide"]}}`,
+	})
+	widths, err := fetchPosterWidths(context.Background(), fake.tmdb())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{92, 154, 500}; !slices.Equal(widths, want) {
+		t.Errorf("fetchPosterWidths = %v, want %v", widths, want)
+	}
+
+	fake.handle("/configuration", fakeResponse{Body: `{"images":{"poster_sizes":["original"]}}`})
+	if _, err := fetchPosterWidths(context.Background(), fake.tmdb()); err == nil {
+		t.Error("a configuration without fixed-width sizes is accepted")
+	}
+}
+
+func TestImageURL(t *testing.T) {
+	tests := []struct {
+		size, path string
+		want       string
+	}{
+		{"w500", "/matrix.jpg", "https://image.tmdb.org/t/p/w500/matrix.jpg"},
+		{"original", "/matrix.jpg", "https://image.tmdb.org/t/p/original/matrix.jpg"},
+		{"w500", "", ""},
+	}
+	for _, tt := range tests {
+		if got := imageURL(tt.size, tt.path); got != tt.want {
+			t.Errorf("imageURL(%q, %q) = %q, want %q", tt.size, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOEmbedPosterWidth(t *testing.T) {
+	setPosterWidths(t, []int{92, 154, 185, 342})
+
+	tests := []struct {
+		maxWidth, maxHeight int
+		want                int
+	}{
+		{342, 513, 342},
+		{300, 1000, 185}, // no 300 size: the next one down
+		{1000, 300, 185}, // the height bounds it too
+		{91, 1000, 0},    // even the smallest doesn't fit
+		{1000, 1000, 342},
+	}
+	for _, tt := range tests {
+		if got := oEmbedPosterWidth(tt.maxWidth, tt.maxHeight); got != tt.want {
+			t.Errorf("oEmbedPosterWidth(%d, %d) = %d, want %d", tt.maxWidth, tt.maxHeight, got, tt.want)
+		}
+	}
+}
+
+func TestJSONLDImage(t *testing.T) {
+	withPoster := movieJSONLD(&MovieDetail{ID: 603, Title: "The Matrix", PosterPath: "/matrix.jpg"}, URLBuilder{})
+	if withPoster.Image != "https://image.tmdb.org/t/p/w500/matrix.jpg" {
+		t.Errorf("image = %q", withPoster.Image)
+	}
+	data, err := json.Marshal(movieJSONLD(&MovieDetail{ID: 999, Title: "Lost Reel"}, URLBuilder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"image"`) {
+		t.Errorf("movie without a poster has an image: %s", data)
+	}
+}
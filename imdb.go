@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// errIMDbNotMovie means TMDB knows the IMDb ID, but as a TV show or
+	// episode rather than a movie.
+	errIMDbNotMovie = errors.New("IMDb ID is not a movie")
+	// errIMDbUnknown means TMDB doesn't know the IMDb ID at all.
+	errIMDbUnknown = errors.New("unknown IMDb ID")
+)
+
+// imdbCache remembers which movie each IMDb ID resolved to. The mapping
+// never changes, so entries are kept for the life of the app. Failed
+// lookups aren't cached: TMDB may learn the ID later.
+type imdbCache struct {
+	mu     sync.Mutex
+	movies map[string]Movie
+}
+
+// resolveIMDbID returns the movie TMDB has for an IMDb title ID such as
+// tt0133093, or errIMDbNotMovie or errIMDbUnknown when it has none.
+func resolveIMDbID(ctx context.Context, imdbID string, tmdb *TMDB) (Movie, error) {
+	imdbID = strings.ToLower(strings.TrimSpace(imdbID))
+	tmdb.imdb.mu.Lock()
+	movie, ok := tmdb.imdb.movies[imdbID]
+	tmdb.imdb.mu.Unlock()
+	if ok {
+		return movie, nil
+	}
+
+	found, err := findByIMDbID(ctx, imdbID, tmdb)
+	if err != nil {
+		return Movie{}, err
+	}
+	switch {
+	case len(found.MovieResults) > 0:
+		movie = found.MovieResults[0]
+	case len(found.TVResults) > 0 || len(found.TVEpisodeResults) > 0:
+		return Movie{}, errIMDbNotMovie
+	default:
+		return Movie{}, errIMDbUnknown
+	}
+
+	tmdb.imdb.mu.Lock()
+	if tmdb.imdb.movies == nil {
+		tmdb.imdb.movies = map[string]Movie{}
+	}
+	tmdb.imdb.movies[imdbID] = movie
+	tmdb.imdb.mu.Unlock()
+	return movie, nil
+}
+
+// imdbRedirect answers /movie/{imdbID} and /movie/{imdbID}/{rest} with a
+// permanent redirect to the same URL under the movie's canonical slug. IDs
+// that aren't movies are a 404, as JSON for the streaming-availability
+// endpoint.
+func imdbRedirect(w http.ResponseWriter, r *http.Request, config Config, imdbID, rest string) {
+	r, cancel := withDeadline(r, config.DetailTimeout)
+	defer cancel()
+	api := rest == "streaming-availability"
+
+	start := time.Now()
+	movie, err := resolveIMDbID(r.Context(), imdbID, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_find", start)
+	if err != nil {
+		message := "No movie with IMDb ID " + imdbID
+		if errors.Is(err, errIMDbNotMovie) {
+			message = imdbID + " is a TV title on IMDb, not a movie"
+		} else if !errors.Is(err, errIMDbUnknown) {
+			log.Printf("Error looking up IMDb ID: %v", err)
+			if api {
+				tmdbAPIFailure(w, err, "Failed to look up IMDb ID")
+			} else {
+				tmdbFailure(w, r, err, "Failed to look up IMDb ID")
+			}
+			return
+		}
+		if api {
+			writeAPIError(w, http.StatusNotFound, apiNotFound, message)
+		} else {
+			http.Error(w, message, http.StatusNotFound)
+		}
+		return
+	}
+
+	target := movieURL(movie.ID, movie.Title)
+	if rest != "" {
+		target += "/" + rest
+	}
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
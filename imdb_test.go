@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsIMDbID(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"tt0133093", true},
+		{"TT123", true},
+		{"  tt0133093 ", true},
+		{"tt", false},
+		{"tt12a", false},
+		{"0133093", false},
+		{"nm0000206", false},
+		{"the matrix", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isIMDbID(tt.query); got != tt.want {
+			t.Errorf("isIMDbID(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchByIMDbID(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/find/tt0133093": `{"movie_results":[{"id":603,"title":"The Matrix","release_date":"1999-03-30"}],"tv_results":[]}`,
+		"/find/tt0903747": `{"movie_results":[],"tv_results":[{"id":1396}]}`,
+		"/find/tt0000001": `{"movie_results":[],"tv_results":[]}`,
+		"/search/movie":   searchMatrixJSON,
+	})
+	fake.handle("/find/tt9999999", fakeResponse{Status: http.StatusServiceUnavailable, Body: cdnErrorPage})
+	app := newTestApp(t, fake)
+
+	tests := []struct {
+		keyword  string
+		status   int
+		location string
+		find     string // the /find path looked up, "" for a title search
+		lookups  int    // how many times find is called
+	}{
+		{"tt0133093", http.StatusFound, "/movie/the-matrix-603", "/find/tt0133093", 1},
+		// The IMDb ID resolves to the same movie forever, so it isn't
+		// looked up again.
+		{"TT0133093", http.StatusFound, "/movie/the-matrix-603", "/find/tt0133093", 0},
+		{"tt0903747", http.StatusOK, "", "/find/tt0903747", 1},
+		{"tt0000001", http.StatusOK, "", "/find/tt0000001", 1},
+		{"tt9999999", http.StatusServiceUnavailable, "", "/find/tt9999999", 1},
+		{"tt", http.StatusOK, "", "", 0},
+		{"tt12a", http.StatusOK, "", "", 0},
+	}
+	for _, tt := range tests {
+		finds, searches := fake.calls(tt.find), fake.calls("/search/movie")
+		rec := get(app, "/?keyword="+tt.keyword)
+		if rec.Code != tt.status || rec.Header().Get("Location") != tt.location {
+			t.Errorf("keyword %q: %d to %q, want %d to %q", tt.keyword, rec.Code, rec.Header().Get("Location"), tt.status, tt.location)
+		}
+		if tt.find != "" && fake.calls(tt.find) != finds+tt.lookups {
+			t.Errorf("keyword %q: %s looked up %d times, want %d", tt.keyword, tt.find, fake.calls(tt.find)-finds, tt.lookups)
+		}
+		if tt.find == "" && fake.calls("/search/movie") != searches+1 {
+			t.Errorf("keyword %q: no title search", tt.keyword)
+		}
+	}
+
+	// The movie URL shares the mapping the search resolved.
+	if rec := get(app, "/movie/tt0133093"); rec.Code != http.StatusMovedPermanently || fake.calls("/find/tt0133093") != 1 {
+		t.Errorf("GET /movie/tt0133093 after searching for it = %d with %d lookups, want 301 and 1", rec.Code, fake.calls("/find/tt0133093"))
+	}
+}
+
+func TestMovieURLWithIMDbID(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/find/tt0133093": `{"movie_results":[{"id":603,"title":"The Matrix"}]}`,
+		"/find/tt0903747": `{"movie_results":[],"tv_results":[{"id":1396}]}`,
+	})
+	app := newTestApp(t, fake)
+
+	tests := []struct {
+		path     string
+		status   int
+		location string
+	}{
+		{"/movie/tt0133093", http.StatusMovedPermanently, "/movie/the-matrix-603"},
+		{"/movie/tt0133093?from_search=matrix", http.StatusMovedPermanently, "/movie/the-matrix-603?from_search=matrix"},
+		{"/movie/tt0133093/streaming-availability", http.StatusMovedPermanently, "/movie/the-matrix-603/streaming-availability"},
+		{"/movie/tt0903747", http.StatusNotFound, ""},
+		{"/movie/tt0000001", http.StatusNotFound, ""},
+		{"/movie/tt0000001/streaming-availability", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := get(app, tt.path)
+		if rec.Code != tt.status || rec.Header().Get("Location") != tt.location {
+			t.Errorf("GET %s = %d to %q, want %d to %q", tt.path, rec.Code, rec.Header().Get("Location"), tt.status, tt.location)
+		}
+	}
+	// The mapping never changes, so it is only looked up once.
+	if n := fake.calls("/find/tt0133093"); n != 1 {
+		t.Errorf("tt0133093 looked up %d times, want 1", n)
+	}
+}
@@ -0,0 +1,77 @@
+// Package envvars lists the environment variables movie-finder reads. The
+// app's LoadConfig only reads variables listed here, and cmd/helm-render
+// renders the list into the ConfigMap and Secret, so the two can't drift
+// apart. The CACHE_CONTROL_<TYPE> overrides are the exception: they are
+// named after the app's response types and have no fixed list.
+package envvars
+
+// Var is one environment variable.
+type Var struct {
+	Name string
+	// Default is the value the app behaves as if the variable were set to
+	// when it is unset. It is "" for variables that are off, empty or
+	// derived from another variable unless set.
+	Default string
+	// Secret variables belong in a Kubernetes Secret rather than the
+	// ConfigMap.
+	Secret bool
+}
+
+// All lists every variable, sorted by name.
+var All = []Var{
+	{Name: "ADMIN_TOKEN", Secret: true},
+	{Name: "ADULT_CONTENT_LOCKED", Default: "false"},
+	{Name: "BASE_URL", Default: "http://localhost:8080"},
+	{Name: "CDN_PURGE_WEBHOOK_URL", Secret: true},
+	{Name: "COLLECTIONS_FILE"},
+	{Name: "CONTENT_ADVISORY", Default: "true"},
+	{Name: "COOKIE_SECRET", Secret: true},
+	{Name: "DETAIL_BADGES", Default: "certification,languages,trailer,video"},
+	{Name: "DETAIL_TIMEOUT"}, // TMDB_TIMEOUT
+	{Name: "ERROR_WEBHOOK_URL", Secret: true},
+	{Name: "EXCLUDE_VIDEOS", Default: "false"},
+	{Name: "HOMEPAGE_MOVIE_COUNT", Default: "6"},
+	{Name: "HOME_MODULES", Default: "popular"},
+	{Name: "INCLUDE_ADULT", Default: "false"},
+	{Name: "LOG_LEVEL", Default: "info"},
+	{Name: "LOG_SAMPLE_RATE", Default: "1.0"},
+	{Name: "MAX_CONCURRENT_REQUESTS", Default: "0"},
+	{Name: "POSTER_MIN_WIDTH", Default: "0"},
+	{Name: "PRELOAD_POSTERS", Default: "4"},
+	{Name: "RECOMMENDATIONS_COUNT", Default: "6"},
+	{Name: "RELATED_SEARCHES", Default: "5"},
+	{Name: "REQUEST_QUEUE_DEPTH", Default: "100"},
+	{Name: "REQUEST_QUEUE_TIMEOUT", Default: "5s"},
+	{Name: "RESULT_SORT", Default: "relevance"},
+	{Name: "RUNTIME_SHELF_BAND", Default: "20"},
+	{Name: "RUNTIME_SHELF_COUNT", Default: "6"},
+	{Name: "SEARCH_AUTO_REDIRECT", Default: "false"},
+	{Name: "SEARCH_TIMEOUT"}, // TMDB_TIMEOUT
+	{Name: "SPOILER_FREE_DEFAULT", Default: "false"},
+	{Name: "TIMEZONE"}, // the server's local time zone
+	{Name: "TITLE_MAX_LENGTH", Default: "60"},
+	{Name: "TLS_CERT_FILE"},
+	{Name: "TLS_CIPHER_SUITES"},
+	{Name: "TLS_KEY_FILE"},
+	{Name: "TLS_MIN_VERSION", Default: "1.2"},
+	{Name: "TMDB_API_KEY", Secret: true},
+	{Name: "TMDB_AUDIT_LOG"},
+	{Name: "TMDB_AUDIT_LOG_LEVEL", Default: "info"},
+	{Name: "TMDB_BASE_URL", Default: "https://api.themoviedb.org/3"},
+	{Name: "TMDB_TIMEOUT", Default: "10s"},
+	{Name: "TRUSTED_PROXIES"},
+	{Name: "VIEW_COUNTS_FILE"},
+	{Name: "VIEW_COUNTS_FLUSH_INTERVAL", Default: "1m"},
+	{Name: "WATCH_REGION", Default: "US"},
+	{Name: "WIDGET_CORS_ORIGIN", Default: "*"},
+}
+
+// Listed reports whether name is in All.
+func Listed(name string) bool {
+	for _, v := range All {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
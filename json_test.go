@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestJSONShape pins the field names and omitempty behaviour of the
+// structs the JSON API encodes. A change here changes the API.
+func TestJSONShape(t *testing.T) {
+	full := Movie{
+		ID: 603, Title: "The Matrix", Overview: "A hacker.", ReleaseDate: parseReleaseDate("1999-03-30"),
+		Popularity: 80.5, VoteAverage: 8.2, VoteCount: 25000, PosterPath: "/matrix.jpg",
+		GenreIDs: []int{28, 878}, Adult: true, Video: true, PopularityPercentile: 95,
+	}
+
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{
+			"zero movie keeps only the required fields",
+			Movie{},
+			`{"id":0,"title":"","release_date":""}`,
+		},
+		{
+			"full movie",
+			full,
+			`{"id":603,"title":"The Matrix","overview":"A hacker.","release_date":"1999-03-30","popularity":80.5,"vote_average":8.2,"vote_count":25000,"poster_path":"/matrix.jpg","genre_ids":[28,878],"adult":true,"video":true}`,
+		},
+		{
+			"year-only release date",
+			Movie{ID: 1, Title: "Dune", ReleaseDate: parseReleaseDate("2026")},
+			`{"id":1,"title":"Dune","release_date":"2026"}`,
+		},
+		{
+			"empty genre list is left out",
+			Movie{ID: 1, Title: "X", GenreIDs: []int{}},
+			`{"id":1,"title":"X","release_date":""}`,
+		},
+		{
+			"zero movie detail keeps only the required fields",
+			MovieDetail{},
+			`{"id":0,"title":"","release_date":""}`,
+		},
+		{
+			"movie detail",
+			MovieDetail{
+				ID: 603, Title: "The Matrix", Overview: "A hacker.", OriginalLanguage: "en", PosterPath: "/matrix.jpg",
+				ReleaseDate: parseReleaseDate("1999-03-30"), Runtime: 136, VoteAverage: 8.2, VoteCount: 25000,
+				Genres:          []Genre{{ID: 28, Name: "Action"}},
+				SpokenLanguages: []SpokenLanguage{{ISO6391: "en", EnglishName: "English", Name: "English"}},
+			},
+			`{"id":603,"title":"The Matrix","overview":"A hacker.","original_language":"en","poster_path":"/matrix.jpg","release_date":"1999-03-30","runtime":136,"vote_average":8.2,"vote_count":25000,"genres":[{"id":28,"name":"Action"}],"spoken_languages":[{"iso_639_1":"en","english_name":"English","name":"English"}]}`,
+		},
+		{
+			"API movie adds the year",
+			apiMovie{Movie: Movie{ID: 603, Title: "The Matrix", ReleaseDate: parseReleaseDate("1999-03-30")}, Year: "1999"},
+			`{"id":603,"title":"The Matrix","release_date":"1999-03-30","year":"1999"}`,
+		},
+		{
+			"API movie without a date",
+			apiMovie{Movie: Movie{ID: 1, Title: "Untitled"}},
+			`{"id":1,"title":"Untitled","release_date":"","year":""}`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(tt.value)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
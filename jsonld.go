@@ -0,0 +1,72 @@
+package main
+
+import "fmt"
+
+// MovieJSONLD is schema.org Movie structured data for search engines. Empty
+// fields are omitted so the block stays valid.
+type MovieJSONLD struct {
+	Context         string           `json:"@context"`
+	Type            string           `json:"@type"`
+	URL             string           `json:"url,omitempty"`
+	Name            string           `json:"name"`
+	Description     string           `json:"description,omitempty"`
+	Image           string           `json:"image,omitempty"`
+	DatePublished   string           `json:"datePublished,omitempty"`
+	Genre           []string         `json:"genre,omitempty"`
+	Duration        string           `json:"duration,omitempty"`
+	AggregateRating *AggregateRating `json:"aggregateRating,omitempty"`
+}
+
+// AggregateRating is the schema.org rating summary, on TMDB's 0-10 scale.
+type AggregateRating struct {
+	Type        string  `json:"@type"`
+	RatingValue float64 `json:"ratingValue"`
+	RatingCount int     `json:"ratingCount"`
+	BestRating  int     `json:"bestRating"`
+	WorstRating int     `json:"worstRating"`
+}
+
+// movieJSONLD builds the structured data for a detail page. Unrated movies
+// get no aggregateRating, since schema.org requires a non-zero count.
+func movieJSONLD(movie *MovieDetail, urls URLBuilder) MovieJSONLD {
+	ld := MovieJSONLD{
+		Context:       "https://schema.org",
+		Type:          "Movie",
+		URL:           urls.Movie(movie.ID, movie.Title),
+		Name:          movie.Title,
+		Description:   movie.Overview,
+		Image:         imageURL("w500", movie.PosterPath),
+		DatePublished: movie.ReleaseDate.String(),
+		Duration:      isoDuration(movie.Runtime),
+	}
+	for _, g := range movie.Genres {
+		ld.Genre = append(ld.Genre, g.Name)
+	}
+	if movie.VoteCount > 0 {
+		ld.AggregateRating = &AggregateRating{
+			Type:        "AggregateRating",
+			RatingValue: movie.VoteAverage,
+			RatingCount: movie.VoteCount,
+			BestRating:  10,
+			WorstRating: 0,
+		}
+	}
+	return ld
+}
+
+// isoDuration formats a runtime in minutes as an ISO 8601 duration such as
+// "PT2H16M", or "" when it is unknown.
+func isoDuration(minutes int) string {
+	if minutes <= 0 {
+		return ""
+	}
+	hours, minutes := minutes/60, minutes%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("PT%dM", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("PT%dH", hours)
+	default:
+		return fmt.Sprintf("PT%dH%dM", hours, minutes)
+	}
+}
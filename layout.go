@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// viewCookie holds the visitor's display preference, "standard" or "text".
+const viewCookie = "view"
+
+// headerPartial is the navigation and search box at the top of every page.
+// Pages include it with {{template "header" $keyword}}, passing the query to
+// prefill the search box with, or "".
+const headerPartial = `{{define "header"}}
+<header>
+    <nav><a href="/">Movie Finder</a> &middot; <a href="/cinema">In cinemas</a> &middot; <a href="/collections">Collections</a> &middot; <a href="/planner/generate">Movie night</a> &middot; <a href="/tonight">Tonight</a> &middot; <a href="/quiz">Quiz</a> &middot; <a href="/settings">Settings</a></nav>
+    <form action="/" method="GET" role="search">
+        <input type="search" name="keyword" value="{{.}}" placeholder="Search movies" aria-label="Search movies" dir="auto" required>
+        <button type="submit">Search</button>
+    </form>
+</header>
+{{end}}`
+
+// footerPartial credits TMDB, as its terms of use require of every app using
+// its API. Pages include it with {{template "footer"}}.
+const footerPartial = `{{define "footer"}}
+<footer>
+    <p><small>Movie data and images from <a href="https://www.themoviedb.org/">TMDB</a>. This product uses the TMDB API but is not endorsed or certified by TMDB.</small></p>
+</footer>
+{{end}}`
+
+// stripPartial renders a []Movie as a row of poster thumbnails.
+const stripPartial = `{{define "strip"}}
+<div style="display: flex; gap: .75em; overflow-x: auto;">
+    {{range .}}
+    <a href="{{movieURL .ID .Title}}" title="{{.Title}}" style="flex: none;">{{posterImg .PosterPath 92 .Title}}</a>
+    {{end}}
+</div>
+{{end}}`
+
+// textPartials replace the graphic partials in the text-only variant.
+const textPartials = `{{define "strip"}}
+<ol>
+    {{range .}}<li><a href="{{movieURL .ID .Title}}" dir="auto">{{.Title}}</a> ({{.ReleaseDate.DisplayYear}}){{if .VoteCount}}, rated {{printf "%.1f" .VoteAverage}}/10{{end}}</li>
+    {{end}}
+</ol>
+{{end}}`
+
+// textFuncs replace the image helpers in the text-only variant, so no page
+// renders an <img> there.
+var textFuncs = template.FuncMap{
+	"posterImg": func(string, int, string) template.HTML { return "" },
+	"logoImg":   func(string) template.HTML { return "" },
+	// Text-only pages load no posters, so there is nothing to preload.
+	"posterPreload": func(string, int) template.HTML { return "" },
+}
+
+// Page is a page template in two variants: the standard one and a
+// text-only one for text browsers and screen readers.
+type Page struct {
+	standard *template.Template
+	text     *template.Template
+}
+
+// pageTemplate parses a page together with the shared partials and template
+// helpers. The text-only variant is the same page with textFuncs, the text
+// partials and the page's own textBlocks replacing the definitions of the
+// same name, typically {{block}}s wrapping image-heavy lists.
+func pageTemplate(name, text, textBlocks string) *Page {
+	standard := template.Must(template.New(name).Funcs(funcMap).Parse(headerPartial + footerPartial + stripPartial))
+	standard = template.Must(standard.Parse(text))
+	textOnly := template.Must(template.Must(standard.Clone()).Funcs(textFuncs).Parse(textPartials + textBlocks))
+	return &Page{standard: standard, text: textOnly}
+}
+
+// variant returns the text-only or the standard template.
+func (p *Page) variant(textOnly bool) *template.Template {
+	if textOnly {
+		return p.text
+	}
+	return p.standard
+}
+
+// render executes the variant of page the visitor asked for into a buffer,
+// recording the render time. Pages rendered through it must send
+// Vary: Cookie.
+func render(r *http.Request, page *Page, data any) (*bytes.Buffer, error) {
+	start := time.Now()
+	var body bytes.Buffer
+	if err := page.variant(textMode(r)).Execute(&body, data); err != nil {
+		return nil, err
+	}
+	RecordTiming(r.Context(), "template_render", start)
+	return &body, nil
+}
+
+// textMode reports whether to render the text-only variant: ?view=text or
+// ?view=standard decide for a single request, the view cookie otherwise.
+func textMode(r *http.Request) bool {
+	if view := r.URL.Query().Get("view"); view != "" {
+		return view == "text"
+	}
+	cookie, err := r.Cookie(viewCookie)
+	return err == nil && cookie.Value == "text"
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// posterlessJSON is a result list with a movie TMDB has no poster for.
+const posterlessJSON = `{"page":1,"total_pages":1,"total_results":2,"results":[
+	{"id":603,"title":"The Matrix","release_date":"1999-03-30","poster_path":"/matrix.jpg","vote_average":8.2,"vote_count":25000,"popularity":80},
+	{"id":999,"title":"Lost Reel","release_date":"1999-06-01","poster_path":null,"vote_average":6.0,"vote_count":10,"popularity":1}
+]}`
+
+func TestTextModeHasNoImages(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/search/movie":   posterlessJSON,
+		"/discover/movie": posterlessJSON,
+		"/movie/603":      movieMatrixJSON,
+		"/movie/999":      `{"id":999,"title":"Lost Reel","release_date":"1999-06-01","poster_path":null,"overview":"Missing."}`,
+	})
+	app := newTestApp(t, fake)
+
+	for _, path := range []string{"/?keyword=matrix", "/best/1999", "/cinema", "/movie/the-matrix-603", "/movie/lost-reel-999"} {
+		t.Run(path, func(t *testing.T) {
+			standard := get(app, path)
+			if standard.Code != http.StatusOK {
+				t.Fatalf("GET %s = %d", path, standard.Code)
+			}
+			if !strings.Contains(standard.Body.String(), "<img") {
+				t.Errorf("standard view of %s has no images; the test proves nothing", path)
+			}
+
+			sep := "?"
+			if strings.Contains(path, "?") {
+				sep = "&"
+			}
+			byQuery := get(app, path+sep+"view=text")
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.AddCookie(&http.Cookie{Name: viewCookie, Value: "text"})
+			byCookie := httptest.NewRecorder()
+			app.ServeHTTP(byCookie, req)
+
+			for name, rec := range map[string]*httptest.ResponseRecorder{"?view=text": byQuery, "cookie": byCookie} {
+				if rec.Code != http.StatusOK {
+					t.Fatalf("%s: GET %s = %d", name, path, rec.Code)
+				}
+				if body := rec.Body.String(); strings.Contains(body, "<img") || strings.Contains(body, "srcset") {
+					t.Errorf("%s: text view of %s has an image", name, path)
+				}
+			}
+		})
+	}
+}
+
+func TestMissingPosterPlaceholder(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{"/search/movie": posterlessJSON})
+	body := get(newTestApp(t, fake), "/?keyword=matrix").Body.String()
+
+	if n := strings.Count(body, posterPlaceholder); n != 1 {
+		t.Errorf("search results show the placeholder %d times, want once for the movie without a poster", n)
+	}
+	if strings.Contains(body, "/null") || strings.Contains(body, `src=""`) {
+		t.Error("missing poster rendered as a broken image URL")
+	}
+
+	text := get(newTestApp(t, fake), "/?keyword=matrix&view=text").Body.String()
+	if strings.Contains(text, posterPlaceholder) || strings.Contains(text, "<img") {
+		t.Error("text view shows a poster placeholder")
+	}
+	if !strings.Contains(text, "Lost Reel") {
+		t.Error("text view leaves out the movie without a poster")
+	}
+}
+
+func TestFooterOnEveryPage(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/search/movie":    searchMatrixJSON,
+		"/discover/movie":  searchMatrixJSON,
+		"/movie/603":       movieMatrixJSON,
+		"/person/6384":     `{"id":6384,"name":"Keanu Reeves"}`,
+		"/company/79":      `{"id":79,"name":"Village Roadshow Pictures"}`,
+		"/collection/2344": `{"id":2344,"name":"The Matrix Collection","parts":[{"id":603,"title":"The Matrix","release_date":"1999-03-30"}]}`,
+	})
+	fake.handle("/movie/604", fakeResponse{Status: http.StatusServiceUnavailable, Body: `{"status_code":9}`})
+	app := newTestApp(t, fake)
+
+	const attribution = "This product uses the TMDB API but is not endorsed or certified by TMDB."
+	pages := map[string]int{
+		"/":                              http.StatusOK,
+		"/?keyword=matrix":               http.StatusOK,
+		"/movie/the-matrix-603":          http.StatusOK,
+		"/movie/the-matrix-reloaded-604": http.StatusServiceUnavailable, // the maintenance page
+		"/person/6384":                   http.StatusOK,
+		"/people?query=keanu":            http.StatusOK,
+		"/company/79":                    http.StatusOK,
+		"/collection/2344":               http.StatusOK,
+		"/collections":                   http.StatusOK,
+		"/best/1999":                     http.StatusOK,
+		"/cinema":                        http.StatusOK,
+		"/tonight":                       http.StatusOK,
+		"/settings":                      http.StatusOK,
+	}
+	for path, status := range pages {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		for _, target := range []string{path, path + sep + "view=text"} {
+			rec := get(app, target)
+			if rec.Code != status {
+				t.Errorf("GET %s = %d, want %d", target, rec.Code, status)
+				continue
+			}
+			if n := strings.Count(rec.Body.String(), attribution); n != 1 {
+				t.Errorf("GET %s credits TMDB %d times, want once", target, n)
+			}
+		}
+	}
+}
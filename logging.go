@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"time"
+)
+
+// appLogger is the logger for the application's own records: text on
+// stderr, at config.LogLevel and above.
+func appLogger(config Config) *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: config.LogLevel}))
+}
+
+// samplingHandler passes every Warn and Error record through but keeps only
+// a random share of Info and Debug records.
+type samplingHandler struct {
+	next slog.Handler
+	rate float64
+}
+
+// SamplingLogger wraps logger so that Info and Debug entries are logged with
+// probability sampleRate (0.0-1.0). Warn and Error entries are never dropped.
+func SamplingLogger(logger *slog.Logger, sampleRate float64) *slog.Logger {
+	return slog.New(&samplingHandler{next: logger.Handler(), rate: sampleRate})
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level < slog.LevelWarn && rand.Float64() >= h.rate {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), rate: h.rate}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), rate: h.rate}
+}
+
+// accessLogMiddleware logs one line per request, including the TMDB and
+// render timings collected by timingMiddleware. Server errors are logged at
+// Error level so sampling never hides them.
+func accessLogMiddleware(next http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.String("request_id", requestIDFromContext(r.Context())),
+			slog.String("client_ip", clientIPFromContext(r.Context())),
+		}
+		if timings, ok := r.Context().Value(timingContextKey{}).(*TimingRecorder); ok {
+			if s := timings.String(); s != "" {
+				attrs = append(attrs, slog.String("timings", s))
+			}
+		}
+		logger.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
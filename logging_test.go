@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// countingHandler counts the records that reach it, by level.
+type countingHandler struct {
+	mu     sync.Mutex
+	counts map[slog.Level]int
+	last   slog.Record
+}
+
+func newCountingLogger() (*slog.Logger, *countingHandler) {
+	h := &countingHandler{counts: map[slog.Level]int{}}
+	return slog.New(h), h
+}
+
+func (h *countingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *countingHandler) Handle(_ context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[record.Level]++
+	h.last = record
+	return nil
+}
+
+func (h *countingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *countingHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *countingHandler) count(level slog.Level) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.counts[level]
+}
+
+func TestSamplingLogger(t *testing.T) {
+	const n = 10000
+	levels := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+	for _, rate := range []float64{0, 0.01, 0.1, 0.5, 0.9, 1} {
+		logger, counts := newCountingLogger()
+		sampled := SamplingLogger(logger, rate)
+		for i := 0; i < n; i++ {
+			for _, level := range levels {
+				sampled.Log(context.Background(), level, "event")
+			}
+		}
+
+		// Five standard deviations: a correct sampler fails about once in
+		// three million runs.
+		tolerance := 5 * math.Sqrt(n*rate*(1-rate))
+		for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo} {
+			if got := float64(counts.count(level)); math.Abs(got-n*rate) > tolerance {
+				t.Errorf("rate %v: kept %v of %d %s records, want %v ± %.0f", rate, got, n, level, n*rate, tolerance)
+			}
+		}
+		for _, level := range []slog.Level{slog.LevelWarn, slog.LevelError} {
+			if got := counts.count(level); got != n {
+				t.Errorf("rate %v: kept %d of %d %s records, want all", rate, got, n, level)
+			}
+		}
+	}
+}
+
+func TestSamplingLoggerKeepsAttrs(t *testing.T) {
+	logger, counts := newCountingLogger()
+	SamplingLogger(logger, 0).With("request_id", "abc").Error("boom")
+	if counts.count(slog.LevelError) != 1 {
+		t.Fatal("error record dropped after With")
+	}
+	SamplingLogger(logger, 0).WithGroup("g").Info("hello")
+	if counts.count(slog.LevelInfo) != 0 {
+		t.Error("info record kept at rate 0 after WithGroup")
+	}
+}
+
+func TestTimingTransportLogsThroughSampler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	for _, tt := range []struct {
+		rate float64
+		want int
+	}{{0, 0}, {1, 1}} {
+		logger, counts := newCountingLogger()
+		client := &http.Client{Transport: &TimingTransport{Log: SamplingLogger(logger, tt.rate)}}
+		resp, err := client.Get(server.URL + "/movie/603")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if got := counts.count(slog.LevelDebug); got != tt.want {
+			t.Errorf("rate %v: %d timing records, want %d", tt.rate, got, tt.want)
+		}
+		if tt.want > 0 && counts.last.Message != "tmdb request" {
+			t.Errorf("logged %q, want %q", counts.last.Message, "tmdb request")
+		}
+	}
+}
@@ -1,123 +1,603 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Constants for API endpoints
 const (
-	baseURL        = "https://api.themoviedb.org/3"
+	defaultBaseURL = "https://api.themoviedb.org/3"
 	searchEndpoint = "/search/movie"
 	movieEndpoint  = "/movie/"
+	findEndpoint   = "/find/"
 )
 
+// imdbIDPattern matches IMDb title IDs such as tt0133093.
+var imdbIDPattern = regexp.MustCompile(`^tt\d+$`)
+
 // Config struct to hold application configuration.
 // It's good practice to keep configuration separate from your code logic.
 type Config struct {
 	APIKey string
+	// TMDBBaseURL is the TMDB API root (TMDB_BASE_URL), overridden to point
+	// at a fake TMDB in tests.
+	TMDBBaseURL string
+
+	// URLs builds absolute links from the externally visible address of the app (BASE_URL).
+	URLs   URLBuilder
+	Region string // ISO 3166-1 country code used for regional data such as watch providers.
+	// Timezone decides where days and years start for "today" computations
+	// (TIMEZONE); nil means the server's local zone.
+	Timezone *time.Location
+
+	// CachePolicies maps response types to their Cache-Control header value.
+	CachePolicies map[string]string
+
+	// ErrorWebhookURL, when set, receives a JSON report for every 5xx response.
+	ErrorWebhookURL string
+	// AdminToken, when set, enables /admin/purge for requests carrying it
+	// as a bearer token.
+	AdminToken string
+	// PurgeWebhookURL, when set, is sent the surrogate keys /admin/purge
+	// answers with.
+	PurgeWebhookURL string
+
+	// MaxTitleLength is the rune length after which titles are shortened in result lists.
+	MaxTitleLength int
+
+	// IncludeAdult is the default for including adult titles in searches.
+	// AdultContentLocked excludes them everywhere, overriding any per-request choice.
+	IncludeAdult       bool
+	AdultContentLocked bool
+
+	// FeaturedCollections are the collection IDs listed on /collections, in order.
+	FeaturedCollections []int
+	// TMDB is the TMDB client and caches the handlers share. NewApp sets it.
+	TMDB *TMDB
+	// ViewCounts counts detail page views, or is nil when VIEW_COUNTS_FILE
+	// is unset. NewApp loads it from ViewCountsFile and saves it every
+	// ViewCountsFlushInterval and once more on Close.
+	ViewCounts              *ViewCounter
+	ViewCountsFile          string
+	ViewCountsFlushInterval time.Duration
+
+	// ExcludeVideos drops direct-to-video releases and shorts from search results.
+	ExcludeVideos bool
+
+	// SearchAutoRedirect sends searches with one obvious match straight to
+	// its detail page for visitors who haven't chosen at /settings.
+	SearchAutoRedirect bool
+	// SpoilerFreeDefault turns spoiler-free mode on for visitors who haven't
+	// chosen at /settings.
+	SpoilerFreeDefault bool
+	// ResultSort is the display sort of search results when the request
+	// doesn't pick one: "year", "title", "rating", or "" for TMDB's order.
+	ResultSort string
+
+	// WidgetCORSOrigin is the Access-Control-Allow-Origin value for /api/widgets/*.
+	WidgetCORSOrigin string
+
+	// ContentAdvisory shows the certification and keyword-based advisories on detail pages.
+	ContentAdvisory bool
+
+	// MaxConcurrentRequests caps requests handled at once (0 disables the limit).
+	// Up to RequestQueueDepth more wait for at most RequestQueueTimeout before getting a 503.
+	MaxConcurrentRequests int
+	RequestQueueDepth     int
+	RequestQueueTimeout   time.Duration
+
+	// RecommendationsCount is how many movies "You might also like" shows on detail pages (0 hides it).
+	RecommendationsCount int
+	// RuntimeShelfCount is how many movies "More like this length and vibe"
+	// shows on detail pages (0 hides it), picked from the movie's genre
+	// within RuntimeShelfBand minutes of its runtime.
+	RuntimeShelfCount int
+	RuntimeShelfBand  int
+
+	// RelatedSearches caps the follow-up queries suggested below search results (0 disables them).
+	RelatedSearches int
+
+	// PreloadPosters is how many result posters pages ask the browser to preload (0 disables).
+	PreloadPosters int
+	// PosterMinWidth leaves poster sizes narrower than this many pixels out
+	// of srcsets (0 offers them all).
+	PosterMinWidth int
+
+	// HomepageMovieCount is how many movies each home page strip shows (0 hides the strips).
+	HomepageMovieCount int
+	// HomeModules are the home page modules shown, in order, to visitors who
+	// haven't picked their own at /settings.
+	HomeModules []string
+
+	// DetailBadges lists the badges shown under the title on detail pages, in order.
+	DetailBadges []string
+
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For entries are believed.
+	TrustedProxies []netip.Prefix
+
+	// TLSCertFile and TLSKeyFile switch the server to HTTPS when both are set,
+	// using TLSConfig for the protocol version and cipher suites.
+	TLSCertFile string
+	TLSKeyFile  string
+	TLSConfig   *tls.Config
+
+	// CookieKey encrypts cookies that carry server state, such as a quiz in progress.
+	CookieKey []byte
+
+	// TMDBTimeout bounds each TMDB call. SearchTimeout and DetailTimeout bound
+	// all TMDB calls made by search and detail handlers together.
+	TMDBTimeout   time.Duration
+	SearchTimeout time.Duration
+	DetailTimeout time.Duration
+
+	// LogSampleRate is the share (0.0-1.0) of Info/Debug access log entries that are kept.
+	LogSampleRate float64
+	// LogLevel is the minimum level logged; at Debug, panic messages are
+	// also shown in the 500 response.
+	LogLevel slog.Level
+	// TMDBAuditLog is a file main appends one JSON line per TMDB call to,
+	// at TMDBAuditLevel, or "" for none.
+	TMDBAuditLog   string
+	TMDBAuditLevel slog.Level
 }
 
 // Movie represents the basic information about a movie to be listed.
+//
+// id, title and release_date are required: they are always encoded, the
+// release date as "" when unknown. The other fields are left out of the JSON
+// when zero, which for the counts and flags means none or false.
 type Movie struct {
-	ID    int    `json:"id"`
-	Title string `json:"title"`
-	Year  string `json:"release_date"`
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	Overview    string      `json:"overview,omitempty"`
+	ReleaseDate ReleaseDate `json:"release_date"`
+	Popularity  float64     `json:"popularity,omitempty"`
+	VoteAverage float64     `json:"vote_average,omitempty"`
+	VoteCount   int         `json:"vote_count,omitempty"`
+	PosterPath  string      `json:"poster_path,omitempty"`
+	GenreIDs    []int       `json:"genre_ids,omitempty"`
+	Adult       bool        `json:"adult,omitempty"`
+	Video       bool        `json:"video,omitempty"` // a direct-to-video release or short rather than a feature film
+
+	// PopularityPercentile is computed per result list by ComputePercentiles.
+	PopularityPercentile float64 `json:"-"`
 }
 
 // MovieDetail represents the detailed information about a movie for display.
+//
+// As with Movie, only id, title and release_date are always encoded; the
+// rest are left out when zero. TMDB uses 0 for an unknown runtime and never
+// averages a rated movie to 0, so no field needs a pointer to tell zero from
+// missing.
 type MovieDetail struct {
-	Title    string `json:"title"`
-	Overview string `json:"overview"`
+	ID                  int                 `json:"id"`
+	Title               string              `json:"title"`
+	Overview            string              `json:"overview,omitempty"`
+	OriginalLanguage    string              `json:"original_language,omitempty"`
+	PosterPath          string              `json:"poster_path,omitempty"`
+	ReleaseDate         ReleaseDate         `json:"release_date"`
+	Runtime             int                 `json:"runtime,omitempty"`
+	VoteAverage         float64             `json:"vote_average,omitempty"`
+	VoteCount           int                 `json:"vote_count,omitempty"`
+	Genres              []Genre             `json:"genres,omitempty"`
+	Adult               bool                `json:"adult,omitempty"`
+	Video               bool                `json:"video,omitempty"`
+	SpokenLanguages     []SpokenLanguage    `json:"spoken_languages,omitempty"`
+	ProductionCompanies []ProductionCompany `json:"production_companies,omitempty"`
 	// Add more fields as needed for detailed information.
+
+	// Sub-resources, only set when requested through append_to_response.
+	Credits         *MovieCredits           `json:"credits,omitempty"`
+	Videos          *VideosResponse         `json:"videos,omitempty"`
+	ExternalIDs     *ExternalIDs            `json:"external_ids,omitempty"`
+	ReleaseDates    *ReleaseDatesResponse   `json:"release_dates,omitempty"`
+	Keywords        *KeywordsResponse       `json:"keywords,omitempty"`
+	Recommendations *SearchResults          `json:"recommendations,omitempty"`
+	WatchProviders  *WatchProvidersResponse `json:"watch/providers,omitempty"`
+}
+
+// Genre is a TMDB movie genre.
+type Genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ProductionCompany is a company credited with producing a movie.
+type ProductionCompany struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	LogoPath string `json:"logo_path"`
 }
 
 // SearchResults wraps the list of movies returned by the API.
 type SearchResults struct {
-	Results []Movie `json:"results"`
+	Results      []Movie `json:"results"`
+	TotalPages   int     `json:"total_pages"`
+	TotalResults int     `json:"total_results"`
+}
+
+// FindResults holds the titles matched by an external ID lookup. TV matches
+// are only counted, to tell TV IDs from unknown ones.
+type FindResults struct {
+	MovieResults     []Movie    `json:"movie_results"`
+	TVResults        []struct{} `json:"tv_results"`
+	TVEpisodeResults []struct{} `json:"tv_episode_results"`
+}
+
+// HomePage is the data rendered by the home page template.
+type HomePage struct {
+	Keyword        string
+	Movies         []Movie
+	MaxTitleLength int
+	BestYears      []int
+	Related        []string        // follow-up queries taken from the result titles
+	Sort           string          // display sort of the results, "" for TMDB's order
+	Order          string          // "asc", "desc" or "" for the sort's natural order
+	Modules        []template.HTML // pre-rendered home page modules, only without a keyword
+	Meta           Meta
+	Theme          string   // "light" or "dark" from the theme cookie, "" to follow the system
+	Preload        []string // poster paths of the first results
+}
+
+// Template helpers shared by all pages.
+var funcMap = template.FuncMap{
+	"imageURL":             imageURL,
+	"displayTitle":         displayTitle,
+	"titleTruncated":       titleTruncated,
+	"highlight":            highlight,
+	"movieURL":             movieURL,
+	"join":                 strings.Join,
+	"posterImg":            posterImg,
+	"logoImg":              logoImg,
+	"posterPreload":        posterPreload,
+	"stylesheet":           stylesheet,
+	"justWatchAttribution": func() string { return justWatchAttribution },
+}
+
+var homeTmpl = pageTemplate("home", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>Movie Finder</title>
+    {{range .Preload}}{{posterPreload . 46}}
+    {{end}}
+</head>
+<body>
+    {{template "header" .Keyword}}
+    <h1>Search Movie Title</h1>
+    {{range .Modules}}{{.}}
+    {{end}}
+    {{if and .Keyword (not .Movies)}}<p>No movies found.</p>{{end}}
+    {{if .Movies}}
+    <form action="/" method="GET">
+        <input type="hidden" name="keyword" value="{{.Keyword}}">
+        <label>Sort by
+            <select name="sort">
+                <option value="relevance">Relevance</option>
+                <option value="year"{{if eq .Sort "year"}} selected{{end}}>Year</option>
+                <option value="title"{{if eq .Sort "title"}} selected{{end}}>Title</option>
+                <option value="rating"{{if eq .Sort "rating"}} selected{{end}}>Rating</option>
+            </select>
+        </label>
+        <select name="order" aria-label="Order">
+            <option value="">Default order</option>
+            <option value="asc"{{if eq .Order "asc"}} selected{{end}}>Ascending</option>
+            <option value="desc"{{if eq .Order "desc"}} selected{{end}}>Descending</option>
+        </select>
+        <button type="submit">Sort</button>
+        <small>Sorts the results on this page only.</small>
+    </form>
+    {{end}}
+    {{block "results" .}}
+    {{range .Movies}}
+    <p>
+        <a href="{{movieURL .ID .Title}}">{{posterImg .PosterPath 46 ""}} <span dir="auto"{{if titleTruncated .Title $.MaxTitleLength}} title="{{.Title}}"{{end}}>{{highlight (displayTitle .Title $.MaxTitleLength) $.Keyword}}</span> ({{.ReleaseDate.DisplayYear}})</a>
+        {{if .Video}}<small title="Released straight to video">Video</small>{{end}}
+        {{if .Adult}}<small>Adult</small>{{end}}
+        {{with .PopularityLabel}}<small>{{.}}</small>{{end}}
+    </p>
+    {{end}}
+    {{end}}
+    {{with .Keyword}}<p><a href="/people?query={{.}}">Search people named &ldquo;<span dir="auto">{{.}}</span>&rdquo;</a></p>{{end}}
+    {{with .Related}}<p>Related searches: {{range $i, $query := .}}{{if $i}}, {{end}}<a href="/?keyword={{$query}}" dir="auto">{{$query}}</a>{{end}}</p>{{end}}
+    {{with .BestYears}}<p>{{range $i, $year := .}}{{if $i}} &middot; {{end}}<a href="/best/{{$year}}">Best of {{$year}}</a>{{end}}</p>{{end}}
+    {{template "footer"}}
+</body>
+</html>
+`, `
+{{define "results"}}
+{{with .Movies}}
+<ol>
+    {{range .}}<li><a href="{{movieURL .ID .Title}}" dir="auto">{{highlight .Title $.Keyword}}</a> ({{.ReleaseDate.DisplayYear}}){{if .VoteCount}}, rated {{printf "%.1f" .VoteAverage}}/10{{end}}{{if .Video}}, video release{{end}}{{if .Adult}}, adult{{end}}{{with .PopularityLabel}} <small>{{.}}</small>{{end}}</li>
+    {{end}}
+</ol>
+{{end}}
+{{end}}
+`)
+
+// DetailPage is the data rendered by the movie detail template.
+type DetailPage struct {
+	*MovieDetail
+	FromSearch string           // keyword of the search that redirected here, if any
+	Advisory   *ContentAdvisory // nil when disabled or nothing is known
+	Badges     []Badge
+	Crew       []KeyCrewEntry
+	Releases   *ReleaseTimeline // nil when TMDB has no dates for the region or US
+	// Providers are where to stream, rent or buy the movie in the region,
+	// all linking through TMDB's ProvidersLink.
+	Providers     []ProviderGroup
+	ProvidersLink string
+	// Recommended are the "You might also like" movies.
+	Recommended []Movie
+	// SameRuntime are popular movies of the same genre and about the same
+	// length.
+	SameRuntime []Movie
+	// Views is how often the page was viewed on this site, counting this
+	// view; 0 when view counting is off.
+	Views int64
+
+	// SpoilerFree hides the overview behind a <details> toggle.
+	SpoilerFree bool
+	Theme       string // "light" or "dark" from the theme cookie, "" to follow the system
+
+	Meta   Meta
+	JSONLD MovieJSONLD // schema.org structured data
+	OEmbed string      // oEmbed discovery URL
 }
 
 // Initialize a template
-var tmpl = template.Must(template.New("movie").Parse(`
+var tmpl = pageTemplate("movie", `
 <!DOCTYPE html>
-<html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
 <head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
     <title>{{.Title}}</title>
+    <script type="application/ld+json">{{.JSONLD}}</script>
+    <link rel="alternate" type="application/json+oembed" href="{{.OEmbed}}" title="{{.Title}}">
 </head>
 <body>
-    <h1>{{.Title}}</h1>
-    <p>{{.Overview}}</p>
+    {{template "header" .FromSearch}}
+    {{with .FromSearch}}<p><a href="/?keyword={{.}}&no_redirect=1">&larr; All results for &ldquo;{{.}}&rdquo;</a></p>{{end}}
+    {{posterImg .PosterPath 300 (printf "Poster for %s" .Title)}}
+    <h1><span dir="auto">{{.Title}}</span> <small>({{.ReleaseDate.DisplayYear}})</small></h1>
+    <p class="rating rating-{{.RatingClass}}">{{if .VoteCount}}{{printf "%.1f" .AverageRating}}/10 from {{.VoteCount}} votes{{else}}Not rated yet{{end}}</p>
+    {{with .Views}}<p><small>Viewed {{.}} time{{if ne . 1}}s{{end}} on this site</small></p>{{end}}
+    {{with .Badges}}<p>{{range .}}<span class="badge"{{with .Title}} title="{{.}}"{{end}}>{{.Label}}</span> {{end}}</p>{{end}}
+    {{if .SpoilerFree}}<details><summary>Show overview (may contain spoilers)</summary><p dir="auto">{{.Overview}}</p></details>{{else}}<p dir="auto">{{.Overview}}</p>{{end}}
+    {{with .Advisory}}
+    <h2>Content advisory</h2>
+    <p>
+        {{if .Certification}}<strong>{{.Certification}}</strong> ({{.Region}}){{else}}Not rated in {{.Region}}{{end}}
+        {{if .Runtime}}&middot; {{.Runtime}} min{{end}}
+    </p>
+    {{if .Advisories}}
+    <p>May contain <small>(guessed from TMDB keywords, not an official rating)</small>:</p>
+    <ul>
+        {{range .Advisories}}<li>{{.}}</li>{{end}}
+    </ul>
+    {{end}}
+    {{end}}
+    {{with .Releases}}
+    <h2>Releases</h2>
+    {{if .Fallback}}<p><small>No release dates for {{.Requested}}, showing {{.Region}}.</small></p>{{end}}
+    <ul>
+        {{range .Events}}<li>{{.Date.Format "Jan 2, 2006"}} &ndash; {{.Label}}{{with .Certification}} ({{.}}){{end}}{{with .Note}} <small dir="auto">{{.}}</small>{{end}}</li>{{end}}
+    </ul>
+    {{end}}
+    {{with .Providers}}
+    <h2>Where to watch</h2>
+    {{range .}}
+    <p>{{.Label}}: {{range $i, $p := .Providers}}{{if $i}}, {{end}}<a href="{{$.ProvidersLink}}" rel="noopener">{{with $p.LogoPath}}{{logoImg .}} {{end}}<span dir="auto">{{$p.ProviderName}}</span></a>{{end}}</p>
+    {{end}}
+    <p><small>{{justWatchAttribution}}</small></p>
+    {{end}}
+    {{if .Crew}}
+    <h2>Crew</h2>
+    <ul>
+        {{range .Crew}}<li><a href="/person/{{.ID}}" dir="auto">{{.Name}}</a> &ndash; {{join .Roles ", "}}</li>{{end}}
+    </ul>
+    {{end}}
+    {{if .ProductionCompanies}}
+    <h2>Produced by</h2>
+    <ul>
+        {{range .ProductionCompanies}}
+        <li><a href="/company/{{.ID}}">{{with .LogoPath}}{{logoImg .}} {{end}}<span dir="auto">{{.Name}}</span></a></li>
+        {{end}}
+    </ul>
+    {{end}}
+    {{with .Recommended}}
+    <h2>You might also like</h2>
+    {{template "strip" .}}
+    {{end}}
+    {{with .SameRuntime}}
+    <h2>More like this length and vibe</h2>
+    {{template "strip" .}}
+    {{end}}
+    {{template "footer"}}
 </body>
 </html>
-`))
+`, "")
 
 func main() {
+	selfTest := flag.Bool("selftest", false, "check the API key and TMDB connectivity, print the results and exit")
+	flag.Parse()
+
 	// Securely manage the API key using environment variables.
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
 	}
 
-	apiKey := os.Getenv("TMDB_API_KEY")
-	if apiKey == "" {
-		log.Fatal("API key not set in TMDB_API_KEY environment variable")
+	config, err := LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *selfTest {
+		// The self-test serves nothing, so it has no views to count.
+		config.ViewCountsFile = ""
+	}
+	app, err := NewApp(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *selfTest {
+		if !runSelfTest(app.config, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Posters work with the built-in sizes too, so a failure only warns.
+	widths, err := fetchPosterWidths(context.Background(), app.config.TMDB)
+	if err != nil {
+		log.Printf("Using built-in poster sizes: %v", err)
 	}
-	config := Config{APIKey: apiKey}
+	configurePosterWidths(widths, config.PosterMinWidth)
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		homeHandler(w, r, config)
-	})
-	http.HandleFunc("/movie/", func(w http.ResponseWriter, r *http.Request) {
-		movieDetailsHandler(w, r, config) // Note the trailing slash for correct routing.
-	})
+	// SIGINT or SIGTERM, as sent for a deploy, stops the server gracefully
+	// and saves the view counts.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Println("Server is running on http://localhost:8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{Addr: ":8080", Handler: app, TLSConfig: config.TLSConfig}
+	listen := server.ListenAndServe
+	if config.TLSCertFile != "" {
+		log.Println("Server is running on https://localhost:8080")
+		listen = func() error { return server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile) }
+	} else {
+		log.Println("Server is running on http://localhost:8080")
 	}
+	err = serveUntil(ctx, server, listen, shutdownGracePeriod)
+	if err := app.Close(); err != nil {
+		log.Printf("Error saving view counts: %v", err)
+	}
+	if err != nil {
+		log.Fatalf("Server stopped: %v", err)
+	}
+	log.Println("Server stopped")
 }
 
 func homeHandler(w http.ResponseWriter, r *http.Request, config Config) {
-	// Set the Content-Type header to ensure correct rendering of HTML.
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-
-	fmt.Fprintf(w, `
-		<!DOCTYPE html>
-		<html>
-		<head>
-			<title>Movie Finder</title>
-		</head>
-		<body>
-			<h1>Search Movie Title</h1>
-			<form action="/" method="GET">
-				<input type="text" name="keyword" required>
-				<button type="submit">Search</button>
-			</form>
-	`)
+	if rejectDuplicateParams(w, r, "keyword", "no_redirect", "sort", "order") {
+		return
+	}
+	r, cancel := withDeadline(r, config.SearchTimeout)
+	defer cancel()
 
 	// Extract the keyword from the query parameters.
-	if keyword := r.URL.Query().Get("keyword"); keyword != "" {
-		movies, err := searchMovies(keyword, config.APIKey)
+	keyword := strings.TrimSpace(r.URL.Query().Get("keyword"))
+	sortBy, order := config.ResultSort, r.URL.Query().Get("order")
+	if raw, ok := r.URL.Query()["sort"]; ok {
+		sortBy = raw[0]
+		if sortBy == "relevance" {
+			sortBy = ""
+		}
+	}
+	if _, known := resultSorts[sortBy]; sortBy != "" && !known {
+		http.Error(w, fmt.Sprintf("unknown sort %q", sortBy), http.StatusBadRequest)
+		return
+	}
+	if order != "" && order != "asc" && order != "desc" {
+		http.Error(w, `order must be "asc" or "desc"`, http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the results before writing anything so we can still redirect or fail cleanly.
+	var movies []Movie
+	start := time.Now()
+	if isIMDbID(keyword) {
+		movie, err := resolveIMDbID(r.Context(), keyword, config.TMDB)
+		RecordTiming(r.Context(), "tmdb_find", start)
+		switch {
+		case err == nil:
+			// A match goes straight to the detail page.
+			http.Redirect(w, r, movieURL(movie.ID, movie.Title), http.StatusFound)
+			return
+		case !errors.Is(err, errIMDbNotMovie) && !errors.Is(err, errIMDbUnknown):
+			log.Printf("Error looking up IMDb ID: %v", err)
+			tmdbFailure(w, r, err, "Failed to look up IMDb ID")
+			return
+		}
+		// Otherwise there is no movie to show.
+	} else if keyword != "" {
+		results, err := searchMovies(r.Context(), keyword, config.TMDB, config.IncludeAdult && !config.AdultContentLocked)
+		RecordTiming(r.Context(), "tmdb_search", start)
 		if err != nil {
 			log.Printf("Error searching movies: %v", err)
-			http.Error(w, "Failed to search movies", http.StatusInternalServerError)
+			tmdbFailure(w, r, err, "Failed to search movies")
 			return
 		}
+		if config.ExcludeVideos {
+			*results = results.FilterBy(notVideo)
+		}
+		movies = results.Results
 
-		// Iterate through the search results and create links for detailed view.
-		for _, movie := range movies.Results {
-			fmt.Fprintf(w, "<p><a href=\"/movie/%d\">%s (%s)</a></p>", movie.ID, movie.Title, movie.Year)
+		// Skip the results page when one result is clearly what the user meant,
+		// unless they turned that off at /settings or asked to see the list.
+		if searchRedirect(r, config.SearchAutoRedirect) && r.URL.Query().Get("no_redirect") != "1" {
+			if match, ok := strongMatch(keyword, movies); ok {
+				target := movieURL(match.ID, match.Title) + "?from_search=" + url.QueryEscape(keyword)
+				w.Header().Set("Vary", "Cookie")
+				http.Redirect(w, r, target, http.StatusFound)
+				return
+			}
 		}
 	}
 
-	fmt.Fprintf(w, "</body></html>")
+	page := HomePage{
+		Keyword:        keyword,
+		Movies:         sortForDisplay(ComputePercentiles(movies), sortBy, order),
+		MaxTitleLength: config.MaxTitleLength,
+		BestYears:      bestYears(localNow(config)),
+		Related:        relatedSearches(keyword, movies, config.RelatedSearches),
+		Sort:           sortBy,
+		Order:          order,
+		Meta:           pageMeta("/"),
+		Theme:          theme(r),
+		Preload:        preloadPosters(movies, config.PreloadPosters),
+	}
+	if keyword != "" {
+		page.Meta = pageMeta(searchResultsRoute)
+	}
+	if keyword == "" {
+		// Modules are a nice-to-have; the search form works without them.
+		page.Modules = renderHomeModules(r.Context(), chosenHomeModules(r, config.HomeModules), config, textMode(r))
+	}
+
+	body, err := render(r, homeTmpl, page)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the Content-Type header to ensure correct rendering of HTML.
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, searchResponse)
+	body.WriteTo(w)
 }
 
 func movieDetailsHandler(w http.ResponseWriter, r *http.Request, config Config) {
@@ -127,51 +607,203 @@ func movieDetailsHandler(w http.ResponseWriter, r *http.Request, config Config)
 		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
 		return
 	}
-	movieID := pathParts[2]
+	// IMDb IDs, pasted from imdb.com, move to the movie's own URL.
+	if isIMDbID(pathParts[2]) {
+		imdbRedirect(w, r, config, pathParts[2], strings.Join(pathParts[3:], "/"))
+		return
+	}
+	// The JSON endpoint answers its errors in JSON too.
+	streaming := len(pathParts) > 3 && pathParts[3] == "streaming-availability"
+	// The segment is a slug such as "the-dark-knight-603"; the ID is its last part.
+	id, err := movieIDFromSlug(pathParts[2])
+	if err != nil {
+		if streaming {
+			writeAPIError(w, http.StatusBadRequest, apiBadRequest, "Invalid movie ID")
+		} else {
+			http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		}
+		return
+	}
+	movieID := strconv.Itoa(id)
+	r, cancel := withDeadline(r, config.DetailTimeout)
+	defer cancel()
+
+	if streaming {
+		streamingAvailabilityHandler(w, r, config, movieID)
+		return
+	}
+	if rejectDuplicateParams(w, r, "from_search") {
+		return
+	}
 
 	// Fetching movie details using the extracted ID.
-	movie, err := fetchMovieDetails(movieID, config.APIKey)
+	start := time.Now()
+	movie, err := fetchMovieDetails(r.Context(), movieID, config.TMDB, detailPageAppends...)
+	RecordTiming(r.Context(), "tmdb_detail", start)
 	if err != nil {
 		log.Printf("Error fetching movie details: %v", err)
-		http.Error(w, "Failed to fetch movie details", http.StatusInternalServerError)
+		tmdbFailure(w, r, err, "Failed to fetch movie details")
+		return
+	}
+	// TMDB answers unknown IDs with an error object, which decodes to an
+	// empty movie.
+	if movie.ID == 0 {
+		http.Error(w, "Movie not found", http.StatusNotFound)
+		return
+	}
+
+	// Bare IDs and outdated or mistyped slugs move permanently to the canonical URL.
+	if canonical := Slug(movie.Title, id); len(pathParts) > 3 || pathParts[2] != canonical {
+		target := movieURL(id, movie.Title)
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
 		return
 	}
 
-	// Render the movie details using the template.
-	if err := tmpl.Execute(w, movie); err != nil {
+	// Revisits are views too. The count is on the page, so it changes the
+	// ETag below and a revisit is never answered with a 304.
+	var views int64
+	if config.ViewCounts != nil {
+		views = config.ViewCounts.Increment(movie.ID)
+	}
+
+	// Everything below comes from the same response, via append_to_response.
+	data := DetailPage{
+		MovieDetail: movie,
+		FromSearch:  r.URL.Query().Get("from_search"),
+		SpoilerFree: spoilerFree(r, config.SpoilerFreeDefault),
+		Theme:       theme(r),
+		Meta:        pageMeta("/movie/"),
+		JSONLD:      movieJSONLD(movie, config.URLs),
+		OEmbed:      oEmbedDiscoveryURL(config.URLs, movie.ID, movie.Title),
+		Releases:    buildReleaseTimeline(movie.ReleaseDates, config.Region),
+		Badges:      buildBadges(config.DetailBadges, movie, config.Region),
+		Views:       views,
+	}
+	if config.ContentAdvisory {
+		data.Advisory = buildContentAdvisory(config.Region, movie.Runtime, movie.ReleaseDates, movie.Keywords)
+	}
+	if movie.Credits != nil {
+		data.Crew = keyCrew(movie.Credits.Crew)
+	}
+	data.Providers, data.ProvidersLink = providerGroups(movie.WatchProviders, config.Region)
+	data.Recommended = recommendations(r.Context(), movie, config.RecommendationsCount, config.TMDB)
+	data.SameRuntime = runtimeShelf(r.Context(), movie, config.RuntimeShelfBand, config.RuntimeShelfCount, data.Recommended, config.TMDB)
+	if preferOriginalPosters(r) {
+		start := time.Now()
+		poster, err := originalPoster(r.Context(), movie, config.TMDB)
+		RecordTiming(r.Context(), "tmdb_images", start)
+		if err != nil {
+			// The default poster will do.
+			log.Printf("Error fetching original posters: %v", err)
+		} else if poster != "" {
+			data.PosterPath = poster
+		}
+	}
+	// Render the movie details into a buffer first so the render time makes it into Server-Timing.
+	page, err := render(r, tmpl, data)
+	if err != nil {
 		log.Printf("Error executing template: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
-}
 
-func searchMovies(keyword string, apiKey string) (*SearchResults, error) {
-	requestURL := fmt.Sprintf("%s%s?api_key=%s&query=%s", baseURL, searchEndpoint, apiKey, url.QueryEscape(keyword))
-	resp, err := http.Get(requestURL)
-	if err != nil {
-		return nil, err
+	// The page mixes TMDB's data with recommendations, view counts and the
+	// visitor's settings, so only the rendered body says whether it changed.
+	etag := pageETag(page.Bytes())
+	w.Header().Set("Vary", "Cookie")
+	w.Header().Set("ETag", etag)
+	setCacheControl(w, config, detailResponse)
+	if etagMatches(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
-	defer resp.Body.Close()
+	page.WriteTo(w)
+}
 
-	var results SearchResults
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+// searchMovies searches movies by title. Searches TMDB found nothing for
+// are answered from tmdb.emptySearches for a while.
+func searchMovies(ctx context.Context, keyword string, tmdb *TMDB, includeAdult bool) (*SearchResults, error) {
+	key := searchKey(keyword, includeAdult)
+	if tmdb.emptySearches.has(ctx, key) {
+		return &SearchResults{}, nil
+	}
+	requestURL := fmt.Sprintf("%s%s?api_key=%s&query=%s&include_adult=%t", tmdb.BaseURL, searchEndpoint, tmdb.APIKey, url.QueryEscape(keyword), includeAdult)
+	// An error object, such as TMDB's rate limit answer, also decodes to
+	// no results, but only a successful search that found nothing may be
+	// cached.
+	var response struct {
+		SearchResults
+		StatusCode int   `json:"status_code"`
+		Success    *bool `json:"success"`
+	}
+	if err := tmdbGet(ctx, tmdb, requestURL, &response); err != nil {
 		return nil, err
 	}
+	failed := response.StatusCode != 0 || (response.Success != nil && !*response.Success)
+	if response.TotalResults == 0 && !failed {
+		tmdb.emptySearches.add(key)
+	}
 
-	return &results, nil
+	return &response.SearchResults, nil
 }
 
-func fetchMovieDetails(movieID string, apiKey string) (*MovieDetail, error) {
-	requestURL := fmt.Sprintf("%s%s%s?api_key=%s", baseURL, movieEndpoint, movieID, apiKey)
-	resp, err := http.Get(requestURL)
-	if err != nil {
+// fetchMovieDetails fetches a movie. Sub-resources named in appendTo (see
+// detailPageAppends) are included in the same request and decoded into the
+// matching MovieDetail fields, which stay nil otherwise. IDs TMDB answered
+// 404 for are answered from tmdb.missingMovies for a while, with the same
+// empty movie.
+func fetchMovieDetails(ctx context.Context, movieID string, tmdb *TMDB, appendTo ...string) (*MovieDetail, error) {
+	if tmdb.missingMovies.has(ctx, movieID) {
+		return &MovieDetail{}, nil
+	}
+	requestURL := fmt.Sprintf("%s%s%s?api_key=%s", tmdb.BaseURL, movieEndpoint, movieID, tmdb.APIKey)
+	if len(appendTo) > 0 {
+		requestURL += "&append_to_response=" + strings.Join(appendTo, ",")
+	}
+	// TMDB answers errors with an object that decodes to an empty movie;
+	// only its status_code tells a missing movie from other failures.
+	var response struct {
+		MovieDetail
+		StatusCode int `json:"status_code"`
+	}
+	if err := tmdbGet(ctx, tmdb, requestURL, &response); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	if response.ID == 0 && response.StatusCode == tmdbNotFound {
+		tmdb.missingMovies.add(movieID)
+	}
 
-	var movieDetail MovieDetail
-	if err := json.NewDecoder(resp.Body).Decode(&movieDetail); err != nil {
+	return &response.MovieDetail, nil
+}
+
+// detailPageAppends are the sub-resources the detail page needs, fetched
+// together with the movie in a single TMDB request.
+var detailPageAppends = []string{"credits", "videos", "external_ids", "release_dates", "keywords", "recommendations", watchProvidersAppend}
+
+// ExternalIDs are a movie's IDs on other sites.
+type ExternalIDs struct {
+	IMDbID      string `json:"imdb_id"`
+	WikidataID  string `json:"wikidata_id"`
+	FacebookID  string `json:"facebook_id"`
+	InstagramID string `json:"instagram_id"`
+	TwitterID   string `json:"twitter_id"`
+}
+
+// isIMDbID reports whether the query looks like an IMDb title ID (tt1234567).
+func isIMDbID(query string) bool {
+	return imdbIDPattern.MatchString(strings.ToLower(strings.TrimSpace(query)))
+}
+
+func findByIMDbID(ctx context.Context, imdbID string, tmdb *TMDB) (*FindResults, error) {
+	imdbID = strings.ToLower(strings.TrimSpace(imdbID))
+	requestURL := fmt.Sprintf("%s%s%s?api_key=%s&external_source=imdb_id", tmdb.BaseURL, findEndpoint, imdbID, tmdb.APIKey)
+	var results FindResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
 		return nil, err
 	}
 
-	return &movieDetail, nil
+	return &results, nil
 }
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestMain checks the downloaded modules against go.sum before running the
+// tests, so tampered dependencies fail the ordinary go test run as well as
+// make verify. go test -short skips the check.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Short() {
+		if out, err := exec.Command("go", "mod", "verify").CombinedOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "go mod verify: %v\n%s", err, out)
+			os.Exit(1)
+		}
+	}
+	os.Exit(m.Run())
+}
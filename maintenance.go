@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaintenanceRetryAfter is the Retry-After sent while TMDB is down
+// for maintenance, unless TMDB suggests its own.
+const defaultMaintenanceRetryAfter = 5 * time.Minute
+
+// maintenanceSnippetLength is how many runes of a non-JSON TMDB response
+// are kept for the log.
+const maintenanceSnippetLength = 200
+
+// apiTMDBUnavailable is the JSON error code for TMDB maintenance.
+const apiTMDBUnavailable = "tmdb_unavailable"
+
+// TMDBMaintenanceError is returned by tmdbGet when TMDB answers 503 Service
+// Unavailable, as it does during maintenance, or with a body that isn't
+// JSON. It is TMDB's outage, not ours, so handlers answer it with the
+// maintenance page rather than a 500.
+type TMDBMaintenanceError struct {
+	RetryAfter time.Duration // from TMDB's Retry-After, or the default
+
+	// For a response that isn't JSON: its status, Content-Type and the
+	// start of its body, whitespace collapsed, so the log shows what came
+	// back. All empty for a JSON 503.
+	Status      string
+	ContentType string
+	Snippet     string
+}
+
+func (e *TMDBMaintenanceError) Error() string {
+	if e.Status != "" {
+		return fmt.Sprintf("TMDB unavailable, retry after %s: %s %q response: %q", e.RetryAfter, e.Status, e.ContentType, e.Snippet)
+	}
+	return fmt.Sprintf("TMDB unavailable for maintenance, retry after %s", e.RetryAfter)
+}
+
+// newTMDBMaintenanceError reads the Retry-After of a 503 from TMDB, in
+// seconds or as an HTTP date. When body isn't JSON, the error also records
+// what it is.
+func newTMDBMaintenanceError(resp *http.Response, body *bufio.Reader) *TMDBMaintenanceError {
+	retryAfter := defaultMaintenanceRetryAfter
+	raw := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(raw); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(raw); err == nil && time.Until(date) > 0 {
+		retryAfter = time.Until(date).Round(time.Second)
+	}
+	err := &TMDBMaintenanceError{RetryAfter: retryAfter}
+	if !looksLikeJSON(body) {
+		start, _ := io.ReadAll(io.LimitReader(body, 4*maintenanceSnippetLength))
+		err.Status = resp.Status
+		err.ContentType = resp.Header.Get("Content-Type")
+		err.Snippet = excerpt(strings.Join(strings.Fields(strings.ToValidUTF8(string(start), "")), " "), maintenanceSnippetLength)
+	}
+	return err
+}
+
+// looksLikeJSON reports whether body starts, after any whitespace, with a
+// JSON object or array, as every TMDB API response does. It skips the
+// whitespace but leaves the first other byte unread. Content-Type alone
+// can't be trusted: CDN error pages have been served as JSON.
+func looksLikeJSON(body *bufio.Reader) bool {
+	for {
+		c, err := body.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		body.UnreadByte()
+		return c == '{' || c == '['
+	}
+}
+
+// inMaintenance reports whether err is, or wraps, a TMDBMaintenanceError.
+// Caches use it to keep serving what they hold while TMDB is down.
+func inMaintenance(err error) bool {
+	var maintenance *TMDBMaintenanceError
+	return errors.As(err, &maintenance)
+}
+
+// MaintenancePage is the data rendered by the maintenance template.
+type MaintenancePage struct {
+	Meta  Meta
+	Theme string
+}
+
+var maintenanceTmpl = pageTemplate("maintenance", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>Down for maintenance</title>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1>The movie database is undergoing maintenance</h1>
+    <p>Movie Finder gets its data from TMDB, which is down for maintenance right now. Please try again in a few minutes.</p>
+    {{template "footer"}}
+</body>
+</html>
+`, "")
+
+// tmdbFailure answers a page request whose TMDB call failed with err: the
+// maintenance page with 503 and Retry-After when TMDB is down for
+// maintenance, or a 500 with message for anything else. Callers log err.
+func tmdbFailure(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var maintenance *TMDBMaintenanceError
+	if !errors.As(err, &maintenance) {
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+
+	body, err := render(r, maintenanceTmpl, MaintenancePage{Meta: pageMeta(""), Theme: theme(r)})
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	w.Header().Set("Retry-After", strconv.Itoa(int(maintenance.RetryAfter.Seconds())))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	body.WriteTo(w)
+}
+
+// tmdbAPIFailure is tmdbFailure for the JSON endpoints.
+func tmdbAPIFailure(w http.ResponseWriter, err error, message string) {
+	var maintenance *TMDBMaintenanceError
+	if !errors.As(err, &maintenance) {
+		writeAPIError(w, http.StatusInternalServerError, apiInternalError, message)
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(maintenance.RetryAfter.Seconds())))
+	w.Header().Set("Cache-Control", "no-store")
+	writeAPIError(w, http.StatusServiceUnavailable, apiTMDBUnavailable, "TMDB is undergoing maintenance")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// cdnErrorPage is the kind of HTML a CDN in front of TMDB serves when the
+// API behind it is down.
+const cdnErrorPage = `<!DOCTYPE html>
+<html>
+<head><title>502 Bad Gateway</title></head>
+<body>
+  <center><h1>502   Bad Gateway</h1></center>
+  <hr><center>cloudfront</center>
+</body>
+</html>`
+
+func TestTMDBGetNonJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		response fakeResponse
+		status   string // "" for a JSON 503, which keeps no response details
+		snippet  string
+	}{
+		{
+			"HTML 502",
+			fakeResponse{Status: http.StatusBadGateway, ContentType: "text/html", Body: cdnErrorPage},
+			"502 Bad Gateway", "<!DOCTYPE html> <html> <head><title>502 Bad Gateway</title></head> <body> <center><h1>502 Bad Gateway</h1></center> <hr><center>cloudfront</center> </body> </html>",
+		},
+		{
+			"HTML 503",
+			fakeResponse{Status: http.StatusServiceUnavailable, ContentType: "text/html", Body: "\n  <h1>Service  Unavailable</h1>\n"},
+			"503 Service Unavailable", "<h1>Service Unavailable</h1>",
+		},
+		{
+			"HTML 200",
+			fakeResponse{ContentType: "text/html; charset=utf-8", Body: "<html>Please wait</html>"},
+			"200 OK", "<html>Please wait</html>",
+		},
+		{
+			"HTML served as JSON",
+			fakeResponse{Status: http.StatusBadGateway, Body: "<html>Bad gateway</html>"},
+			"502 Bad Gateway", "<html>Bad gateway</html>",
+		},
+		{
+			"empty body",
+			fakeResponse{Status: http.StatusBadGateway, ContentType: "text/plain"},
+			"502 Bad Gateway", "",
+		},
+		{
+			"JSON 503",
+			fakeResponse{Status: http.StatusServiceUnavailable, Body: `{"status_code":9,"status_message":"Service offline."}`},
+			"", "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeTMDB(t, nil)
+			fake.handle("/movie/603", tt.response)
+
+			var movie MovieDetail
+			err := tmdbGet(context.Background(), fake.tmdb(), fake.URL+"/movie/603", &movie)
+			var maintenance *TMDBMaintenanceError
+			if !errors.As(err, &maintenance) {
+				t.Fatalf("tmdbGet = %v, want a TMDBMaintenanceError", err)
+			}
+			if maintenance.Status != tt.status || maintenance.Snippet != tt.snippet {
+				t.Errorf("status %q, snippet %q\nwant %q, %q", maintenance.Status, maintenance.Snippet, tt.status, tt.snippet)
+			}
+			if tt.status != "" && tt.response.ContentType != "" && maintenance.ContentType != tt.response.ContentType {
+				t.Errorf("content type %q, want %q", maintenance.ContentType, tt.response.ContentType)
+			}
+			if strings.Contains(err.Error(), "invalid character") {
+				t.Errorf("error still reads like a JSON syntax error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTMDBGetJSONWithLeadingSpace(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{"/movie/603": "\n\t " + `{"id":603,"title":"The Matrix"}`})
+	var movie MovieDetail
+	if err := tmdbGet(context.Background(), fake.tmdb(), fake.URL+"/movie/603", &movie); err != nil || movie.ID != 603 {
+		t.Errorf("tmdbGet = %v, movie %d", err, movie.ID)
+	}
+}
+
+func TestMaintenanceSnippet(t *testing.T) {
+	fake := newFakeTMDB(t, nil)
+	fake.handle("/movie/603", fakeResponse{
+		Status:      http.StatusBadGateway,
+		ContentType: "text/html",
+		Body:        "<p>\xff\xfe" + strings.Repeat("gateway error ", 500) + "</p>",
+	})
+	err := tmdbGet(context.Background(), fake.tmdb(), fake.URL+"/movie/603", &MovieDetail{})
+	var maintenance *TMDBMaintenanceError
+	if !errors.As(err, &maintenance) {
+		t.Fatalf("tmdbGet = %v", err)
+	}
+	snippet := maintenance.Snippet
+	if n := utf8.RuneCountInString(snippet); n > maintenanceSnippetLength+1 {
+		t.Errorf("snippet is %d runes, want at most %d", n, maintenanceSnippetLength+1)
+	}
+	if !utf8.ValidString(snippet) || !strings.HasPrefix(snippet, "<p>gateway error") || !strings.HasSuffix(snippet, string(ellipsis)) {
+		t.Errorf("snippet = %q", snippet)
+	}
+}
+
+func TestMaintenanceRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       time.Duration
+	}{
+		{"seconds", "120", 2 * time.Minute},
+		{"missing", "", defaultMaintenanceRetryAfter},
+		{"malformed", "soon", defaultMaintenanceRetryAfter},
+		{"zero", "0", defaultMaintenanceRetryAfter},
+		{"date in the past", "Mon, 02 Jan 2006 15:04:05 GMT", defaultMaintenanceRetryAfter},
+		{"date", time.Now().Add(10 * time.Minute).UTC().Format(http.TimeFormat), 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.retryAfter != "" {
+			resp.Header.Set("Retry-After", tt.retryAfter)
+		}
+		got := newTMDBMaintenanceError(resp, bufio.NewReader(strings.NewReader("{}"))).RetryAfter
+		if diff := got - tt.want; diff < -2*time.Second || diff > 2*time.Second {
+			t.Errorf("%s: RetryAfter = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMaintenancePageForHTMLError(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable} {
+		fake := newFakeTMDB(t, nil)
+		fake.handle("/movie/603", fakeResponse{Status: status, ContentType: "text/html", Body: cdnErrorPage})
+		fake.handle("/search/movie", fakeResponse{Status: status, ContentType: "text/html", Body: cdnErrorPage})
+		app := newTestApp(t, fake)
+
+		for _, target := range []string{"/movie/the-matrix-603", "/?keyword=matrix"} {
+			rec := get(app, target)
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("TMDB %d: GET %s = %d, want 503", status, target, rec.Code)
+			}
+			if rec.Header().Get("Retry-After") != "300" || rec.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("TMDB %d: GET %s: Retry-After %q, Cache-Control %q", status, target, rec.Header().Get("Retry-After"), rec.Header().Get("Cache-Control"))
+			}
+			if body := rec.Body.String(); !strings.Contains(body, "undergoing maintenance") || strings.Contains(body, "cloudfront") {
+				t.Errorf("TMDB %d: GET %s doesn't show the maintenance page", status, target)
+			}
+		}
+
+		api := get(app, "/api/search?query=matrix")
+		var body apiErrorBody
+		if err := json.NewDecoder(api.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if api.Code != http.StatusServiceUnavailable || body.Error.Code != apiTMDBUnavailable {
+			t.Errorf("TMDB %d: API status %d, code %q", status, api.Code, body.Error.Code)
+		}
+	}
+}
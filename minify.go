@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// verbatimTags are elements whose content is copied untouched, since their
+// whitespace is significant.
+var verbatimTags = []string{"pre", "script", "textarea"}
+
+// minifyStats accumulates byte counts across all minified responses so the
+// average saving can be logged.
+var minifyStats struct {
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+}
+
+// MinifyMiddleware strips comments, indentation and blank lines from HTML
+// responses. Anything that isn't text/html, such as the JSON API or
+// plain-text errors, is passed through as it is written.
+func MinifyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw := &minifyWriter{ResponseWriter: w}
+		next.ServeHTTP(mw, r)
+		mw.finish(r)
+	})
+}
+
+// minifyWriter buffers HTML bodies until the handler returns. Whether a
+// response is HTML is decided on the first Write, sniffing the body the same
+// way net/http does when the handler didn't set a Content-Type.
+type minifyWriter struct {
+	http.ResponseWriter
+	status  int
+	decided bool
+	html    bool
+	buf     bytes.Buffer
+}
+
+func (mw *minifyWriter) WriteHeader(status int) {
+	if mw.status == 0 {
+		mw.status = status
+	}
+}
+
+func (mw *minifyWriter) Write(b []byte) (int, error) {
+	if !mw.decided {
+		mw.decided = true
+		contentType := mw.Header().Get("Content-Type")
+		if contentType == "" {
+			contentType = http.DetectContentType(b)
+			mw.Header().Set("Content-Type", contentType)
+		}
+		mw.html = strings.HasPrefix(contentType, "text/html")
+		if !mw.html {
+			mw.ResponseWriter.WriteHeader(mw.statusOrOK())
+		}
+	}
+	if mw.html {
+		return mw.buf.Write(b)
+	}
+	return mw.ResponseWriter.Write(b)
+}
+
+func (mw *minifyWriter) statusOrOK() int {
+	if mw.status == 0 {
+		return http.StatusOK
+	}
+	return mw.status
+}
+
+// finish sends whatever the handler left buffered.
+func (mw *minifyWriter) finish(r *http.Request) {
+	if !mw.decided {
+		if mw.status != 0 {
+			mw.ResponseWriter.WriteHeader(mw.status)
+		}
+		return
+	}
+	if !mw.html {
+		return
+	}
+
+	out := minifyHTML(mw.buf.Bytes())
+	in := minifyStats.bytesIn.Add(int64(mw.buf.Len()))
+	total := minifyStats.bytesOut.Add(int64(len(out)))
+	slog.DebugContext(r.Context(), "minified html",
+		"path", r.URL.Path,
+		"bytes_in", mw.buf.Len(),
+		"bytes_out", len(out),
+		"avg_saved_pct", 100*float64(in-total)/float64(in))
+
+	mw.Header().Set("Content-Length", strconv.Itoa(len(out)))
+	mw.ResponseWriter.WriteHeader(mw.statusOrOK())
+	mw.ResponseWriter.Write(out)
+}
+
+// minifyHTML removes comments and collapses the whitespace between lines.
+// The contents of verbatimTags are left alone. It is a plain scan rather
+// than a parser, which is enough for the markup our own templates produce.
+func minifyHTML(src []byte) []byte {
+	lower := bytes.ToLower(src)
+	var out bytes.Buffer
+	out.Grow(len(src))
+
+	i := 0
+	for i < len(src) {
+		start, tag := nextProtected(lower, i)
+		if start < 0 {
+			writeCollapsed(&out, src[i:])
+			break
+		}
+		writeCollapsed(&out, src[i:start])
+
+		if tag == "" {
+			// Drop the comment; an unterminated one runs to the end.
+			end := bytes.Index(lower[start:], []byte("-->"))
+			if end < 0 {
+				break
+			}
+			i = start + end + len("-->")
+			continue
+		}
+
+		closing := []byte("</" + tag + ">")
+		end := bytes.Index(lower[start:], closing)
+		if end < 0 {
+			out.Write(src[start:])
+			break
+		}
+		i = start + end + len(closing)
+		out.Write(src[start:i])
+	}
+	return out.Bytes()
+}
+
+// nextProtected finds the earliest comment or verbatim element at or after
+// from. It returns the tag name, or "" for a comment, and -1 if there is none.
+func nextProtected(lower []byte, from int) (int, string) {
+	best, bestTag := bytes.Index(lower[from:], []byte("<!--")), ""
+	if best >= 0 {
+		best += from
+	}
+	for _, tag := range verbatimTags {
+		if pos := indexTag(lower, from, tag); pos >= 0 && (best < 0 || pos < best) {
+			best, bestTag = pos, tag
+		}
+	}
+	return best, bestTag
+}
+
+// indexTag finds the opening tag <name> or <name ...>, skipping longer
+// names that merely share the prefix.
+func indexTag(lower []byte, from int, name string) int {
+	open := []byte("<" + name)
+	for from < len(lower) {
+		pos := bytes.Index(lower[from:], open)
+		if pos < 0 {
+			return -1
+		}
+		pos += from
+		after := pos + len(open)
+		if after >= len(lower) || bytes.IndexByte([]byte(">/ \t\r\n"), lower[after]) >= 0 {
+			return pos
+		}
+		from = after
+	}
+	return -1
+}
+
+// writeCollapsed appends the collapsed segment, avoiding a doubled newline
+// where a removed comment had whitespace on both sides.
+func writeCollapsed(out *bytes.Buffer, seg []byte) {
+	collapsed := collapseLines(seg)
+	if len(collapsed) > 0 && collapsed[0] == '\n' && bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		collapsed = collapsed[1:]
+	}
+	out.Write(collapsed)
+}
+
+// collapseLines trims every line and drops the empty ones. Whitespace at
+// either edge of the segment shrinks to a single newline rather than
+// disappearing, so words on both sides of a removed comment stay apart.
+func collapseLines(seg []byte) []byte {
+	if len(seg) == 0 {
+		return nil
+	}
+	var out bytes.Buffer
+	for _, line := range bytes.Split(seg, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if out.Len() > 0 {
+			out.WriteByte('\n')
+		}
+		out.Write(line)
+	}
+	if out.Len() == 0 {
+		return []byte("\n")
+	}
+
+	result := out.Bytes()
+	if isHTMLSpace(seg[0]) {
+		result = append([]byte("\n"), result...)
+	}
+	if isHTMLSpace(seg[len(seg)-1]) {
+		result = append(result, '\n')
+	}
+	return result
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f'
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"indentation and blank lines", "<ul>\n    <li>One</li>\n\n\t<li>Two</li>\n</ul>\n", "<ul>\n<li>One</li>\n<li>Two</li>\n</ul>\n"},
+		{"comments", "<p>Hello <!-- greeting -->world</p>", "<p>Hello\nworld</p>"},
+		{"unterminated comment", "<p>Hi</p>\n<!-- to the end", "<p>Hi</p>\n"},
+		{"pre", "<div>\n  <pre>\n  line one\n\n    line two <!-- kept -->\n</pre>\n</div>", "<div>\n<pre>\n  line one\n\n    line two <!-- kept -->\n</pre>\n</div>"},
+		{"textarea", "<form>\n  <textarea name=\"q\">\n  keep\n\n  me\n</textarea>\n</form>", "<form>\n<textarea name=\"q\">\n  keep\n\n  me\n</textarea>\n</form>"},
+		{"script", "<script>\n  if (a <!-- b) {\n    run();\n  }\n</script>\n  <p>x</p>", "<script>\n  if (a <!-- b) {\n    run();\n  }\n</script>\n<p>x</p>"},
+		{"upper case tag", "<PRE>\n  a\n    b\n</PRE>", "<PRE>\n  a\n    b\n</PRE>"},
+		{"unclosed pre", "<pre>\n  a\n  b", "<pre>\n  a\n  b"},
+		{"longer tag name", "<prefix>\n  a\n</prefix>", "<prefix>\na\n</prefix>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(minifyHTML([]byte(tt.src))); got != tt.want {
+				t.Errorf("minifyHTML(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinifyMiddleware(t *testing.T) {
+	const page = "<!DOCTYPE html>\n<html>\n  <body>\n    <pre>\n  as is\n</pre>\n  </body>\n</html>\n"
+	const minified = "<!DOCTYPE html>\n<html>\n<body>\n<pre>\n  as is\n</pre>\n</body>\n</html>\n"
+	const indented = "{\n  \"title\": \"Dune\"\n}\n"
+
+	tests := []struct {
+		name        string
+		contentType string // "" to let the body be sniffed
+		status      int
+		body        string
+		want        string
+	}{
+		{"html", "text/html; charset=utf-8", http.StatusOK, page, minified},
+		{"sniffed html", "", http.StatusOK, page, minified},
+		{"html error page", "text/html; charset=utf-8", http.StatusNotFound, page, minified},
+		{"json", "application/json", http.StatusOK, indented, indented},
+		{"plain text", "text/plain; charset=utf-8", http.StatusBadGateway, "  Bad Gateway\n\n", "  Bad Gateway\n\n"},
+		{"css", "text/css", http.StatusOK, "body {\n  margin: 0;\n}\n", "body {\n  margin: 0;\n}\n"},
+		{"html in xml", "application/xml", http.StatusOK, page, page},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(MinifyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Header().Set("Content-Length", strconv.Itoa(len(tt.body)))
+				w.WriteHeader(tt.status)
+				// In two writes, so the HTML decision isn't made on a
+				// partial body.
+				io.WriteString(w, tt.body[:len(tt.body)/2])
+				io.WriteString(w, tt.body[len(tt.body)/2:])
+			})))
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status || string(body) != tt.want {
+				t.Errorf("%d %q, want %d %q", resp.StatusCode, body, tt.status, tt.want)
+			}
+			if resp.ContentLength != int64(len(tt.want)) {
+				t.Errorf("Content-Length %d for a %d byte body", resp.ContentLength, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMinifyMiddlewareWithoutBody(t *testing.T) {
+	handler := MinifyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("%d %q, want 304 and no body", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// movieSorters orders two movies for each supported sort key. Titles sort
+// A-Z; everything else sorts highest/newest first.
+var movieSorters = map[string]func(a, b Movie) bool{
+	"popularity":   func(a, b Movie) bool { return a.Popularity > b.Popularity },
+	"release_date": func(a, b Movie) bool { return a.ReleaseDate.String() > b.ReleaseDate.String() },
+	"title":        func(a, b Movie) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) },
+	"vote_average": func(a, b Movie) bool { return a.VoteAverage > b.VoteAverage },
+	"vote_count":   func(a, b Movie) bool { return a.VoteCount > b.VoteCount },
+}
+
+// sortMovies returns a sorted copy of movies, leaving the input untouched.
+// by is matched case-insensitively against popularity, release_date, title,
+// vote_average and vote_count; anything else sorts by popularity. Movies
+// that compare equal keep their original order.
+func sortMovies(movies []Movie, by string) []Movie {
+	less, ok := movieSorters[strings.ToLower(strings.TrimSpace(by))]
+	if !ok {
+		less = movieSorters["popularity"]
+	}
+	return sortedBy(movies, less)
+}
+
+// sortedBy returns a copy of movies stably sorted by less.
+func sortedBy(movies []Movie, less func(a, b Movie) bool) []Movie {
+	sorted := make([]Movie, len(movies))
+	copy(sorted, movies)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// resultSorts maps the display sorts offered on the results page to their
+// movieSorters key.
+var resultSorts = map[string]string{
+	"year":   "release_date",
+	"title":  "title",
+	"rating": "vote_average",
+}
+
+// sortForDisplay returns a copy of movies reordered by one of resultSorts,
+// or movies unchanged for "" (TMDB's relevance order). order is "asc",
+// "desc" or "" for the natural order: titles A-Z, years and ratings highest
+// first. Movies that compare equal keep their original order. It only
+// reorders what was fetched: a search shows one page of results, so this is
+// not the order TMDB would give across all pages.
+func sortForDisplay(movies []Movie, field, order string) []Movie {
+	key, ok := resultSorts[field]
+	if !ok {
+		return movies
+	}
+	if order == "" || (order == "asc") == (key == "title") {
+		return sortMovies(movies, key)
+	}
+	// Reversing the comparison rather than sortMovies' result keeps equal
+	// movies in their original order.
+	natural := movieSorters[key]
+	return sortedBy(movies, func(a, b Movie) bool { return natural(b, a) })
+}
+
+// FilterBy returns a copy of the results holding only the movies keep
+// reports true for. TotalPages and TotalResults are carried over unchanged,
+// so after filtering they only approximate what is left.
+func (sr SearchResults) FilterBy(keep func(Movie) bool) SearchResults {
+	filtered := sr
+	filtered.Results = nil
+	for _, movie := range sr.Results {
+		if keep(movie) {
+			filtered.Results = append(filtered.Results, movie)
+		}
+	}
+	return filtered
+}
+
+// SortBy returns a copy of the results ordered by field, as sortMovies does.
+func (sr SearchResults) SortBy(field string) SearchResults {
+	sorted := sr
+	sorted.Results = sortMovies(sr.Results, field)
+	return sorted
+}
+
+// notVideo is a FilterBy predicate leaving out direct-to-video releases and
+// shorts.
+func notVideo(movie Movie) bool {
+	return !movie.Video
+}
+
+// AverageRating is the movie's average vote out of 10, or 0 when nobody has
+// voted yet, whatever TMDB reports in that case.
+func (m *MovieDetail) AverageRating() float64 {
+	if m.VoteCount == 0 {
+		return 0
+	}
+	return m.VoteAverage
+}
+
+// RatingClass buckets the average rating for color-coding: "high" above 7,
+// "medium" from 5 to 7, "low" below 5, and "unrated" without votes.
+func (m *MovieDetail) RatingClass() string {
+	rating := m.AverageRating()
+	switch {
+	case m.VoteCount == 0:
+		return "unrated"
+	case rating > 7:
+		return "high"
+	case rating >= 5:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// topPercentileThreshold is the percentile from which a movie gets a
+// "Top N% popular" label in result lists.
+const topPercentileThreshold = 90
+
+// ComputePercentiles returns a copy of movies with PopularityPercentile set
+// to the share of movies in the list that are less popular, from 0 up to
+// (but not including) 100. Ties share the same percentile.
+func ComputePercentiles(movies []Movie) []Movie {
+	ranked := make([]Movie, len(movies))
+	copy(ranked, movies)
+
+	for i := range ranked {
+		lower := 0
+		for _, other := range movies {
+			if other.Popularity < ranked[i].Popularity {
+				lower++
+			}
+		}
+		ranked[i].PopularityPercentile = 100 * float64(lower) / float64(len(movies))
+	}
+	return ranked
+}
+
+// PopularityLabel returns "Top N% popular" for movies at or above the
+// display threshold, and "" for everything else.
+func (m Movie) PopularityLabel() string {
+	if m.PopularityPercentile < topPercentileThreshold {
+		return ""
+	}
+	return fmt.Sprintf("Top %.0f%% popular", 100-m.PopularityPercentile)
+}
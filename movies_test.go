@@ -0,0 +1,208 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// movieIDs lists the IDs of movies, in order.
+func movieIDs(movies []Movie) []int {
+	ids := []int{}
+	for _, movie := range movies {
+		ids = append(ids, movie.ID)
+	}
+	return ids
+}
+
+// sortFixture differs in every sort key, so each one gives its own order.
+var sortFixture = []Movie{
+	{ID: 1, Title: "brazil", ReleaseDate: parseReleaseDate("1985-02-20"), Popularity: 20, VoteAverage: 7.8, VoteCount: 3000},
+	{ID: 2, Title: "Alien", ReleaseDate: parseReleaseDate("1979-05-25"), Popularity: 60, VoteAverage: 8.1, VoteCount: 14000},
+	{ID: 3, Title: "Cube", ReleaseDate: parseReleaseDate("1997-09-09"), Popularity: 10, VoteAverage: 7.2, VoteCount: 5000},
+	{ID: 4, Title: "Dune", ReleaseDate: parseReleaseDate("2021"), Popularity: 90, VoteAverage: 7.9, VoteCount: 9000},
+}
+
+func TestSortMovies(t *testing.T) {
+	tied := []Movie{{ID: 1, Popularity: 5}, {ID: 2, Popularity: 5}, {ID: 3, Popularity: 5}}
+
+	tests := []struct {
+		name   string
+		movies []Movie
+		by     string
+		want   []int
+	}{
+		{"empty", nil, "popularity", []int{}},
+		{"single", sortFixture[:1], "title", []int{1}},
+		{"all equal keep their order", tied, "popularity", []int{1, 2, 3}},
+		{"popularity", sortFixture, "popularity", []int{4, 2, 1, 3}},
+		{"release date, newest first", sortFixture, "release_date", []int{4, 3, 1, 2}},
+		{"title, ignoring case", sortFixture, "title", []int{2, 1, 3, 4}},
+		{"vote average", sortFixture, "vote_average", []int{2, 4, 1, 3}},
+		{"vote count", sortFixture, "vote_count", []int{2, 4, 3, 1}},
+		{"upper case", sortFixture, "TITLE", []int{2, 1, 3, 4}},
+		{"mixed case and spaces", sortFixture, " Vote_Count ", []int{2, 4, 3, 1}},
+		{"invalid falls back to popularity", sortFixture, "budget", []int{4, 2, 1, 3}},
+		{"empty falls back to popularity", sortFixture, "", []int{4, 2, 1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := movieIDs(sortMovies(tt.movies, tt.by)); !slices.Equal(got, tt.want) {
+				t.Errorf("sortMovies(%q) = %v, want %v", tt.by, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortMoviesLeavesInputAlone(t *testing.T) {
+	movies := slices.Clone(sortFixture)
+	sortMovies(movies, "title")
+	if got := movieIDs(movies); !slices.Equal(got, []int{1, 2, 3, 4}) {
+		t.Errorf("input reordered to %v", got)
+	}
+}
+
+func TestSortForDisplay(t *testing.T) {
+	tests := []struct {
+		field, order string
+		want         []int
+	}{
+		{"", "", []int{1, 2, 3, 4}},
+		{"unknown", "asc", []int{1, 2, 3, 4}},
+		{"year", "", []int{4, 3, 1, 2}},
+		{"year", "desc", []int{4, 3, 1, 2}},
+		{"year", "asc", []int{2, 1, 3, 4}},
+		{"title", "", []int{2, 1, 3, 4}},
+		{"title", "asc", []int{2, 1, 3, 4}},
+		{"title", "desc", []int{4, 3, 1, 2}},
+		{"rating", "", []int{2, 4, 1, 3}},
+		{"rating", "asc", []int{3, 1, 4, 2}},
+	}
+	for _, tt := range tests {
+		if got := movieIDs(sortForDisplay(sortFixture, tt.field, tt.order)); !slices.Equal(got, tt.want) {
+			t.Errorf("sortForDisplay(%q, %q) = %v, want %v", tt.field, tt.order, got, tt.want)
+		}
+	}
+
+	tied := []Movie{{ID: 1, VoteAverage: 7}, {ID: 2, VoteAverage: 7}, {ID: 3, VoteAverage: 6}}
+	for _, order := range []string{"", "desc", "asc"} {
+		want := []int{1, 2, 3}
+		if order == "asc" {
+			want = []int{3, 1, 2}
+		}
+		if got := movieIDs(sortForDisplay(tied, "rating", order)); !slices.Equal(got, want) {
+			t.Errorf("sortForDisplay(tied, %q) = %v, want %v", order, got, want)
+		}
+	}
+}
+
+func TestFilterBy(t *testing.T) {
+	movies := []Movie{
+		{ID: 1, VoteAverage: 8.1},
+		{ID: 2, VoteAverage: 6.5, Video: true},
+		{ID: 3, VoteAverage: 7.0},
+		{ID: 4, VoteAverage: 4.2, Video: true},
+	}
+	rated := func(min float64) func(Movie) bool {
+		return func(m Movie) bool { return m.VoteAverage >= min }
+	}
+
+	tests := []struct {
+		name    string
+		results []Movie
+		keep    func(Movie) bool
+		want    []int
+	}{
+		{"keep all", movies, func(Movie) bool { return true }, []int{1, 2, 3, 4}},
+		{"keep none", movies, func(Movie) bool { return false }, []int{}},
+		{"not video", movies, notVideo, []int{1, 3}},
+		{"rating threshold is inclusive", movies, rated(7.0), []int{1, 3}},
+		{"empty results", nil, notVideo, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := SearchResults{Results: tt.results, TotalPages: 3, TotalResults: 57}
+			got := sr.FilterBy(tt.keep)
+			if ids := movieIDs(got.Results); !slices.Equal(ids, tt.want) {
+				t.Errorf("FilterBy kept %v, want %v", ids, tt.want)
+			}
+			if got.TotalPages != 3 || got.TotalResults != 57 {
+				t.Errorf("FilterBy changed the totals to %d pages, %d results", got.TotalPages, got.TotalResults)
+			}
+		})
+	}
+
+	sr := SearchResults{Results: slices.Clone(movies)}
+	sr.FilterBy(notVideo).Results[0].ID = 99
+	if ids := movieIDs(sr.Results); !slices.Equal(ids, []int{1, 2, 3, 4}) {
+		t.Errorf("FilterBy changed the original results to %v", ids)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	sr := SearchResults{Results: slices.Clone(sortFixture), TotalPages: 2, TotalResults: 40}
+	sorted := sr.SortBy("title")
+	if ids := movieIDs(sorted.Results); !slices.Equal(ids, []int{2, 1, 3, 4}) {
+		t.Errorf("SortBy(title) = %v", ids)
+	}
+	if sorted.TotalPages != 2 || sorted.TotalResults != 40 {
+		t.Errorf("SortBy changed the totals to %d pages, %d results", sorted.TotalPages, sorted.TotalResults)
+	}
+	if ids := movieIDs(sr.Results); !slices.Equal(ids, []int{1, 2, 3, 4}) {
+		t.Errorf("SortBy reordered the original results to %v", ids)
+	}
+
+	chained := sr.FilterBy(func(m Movie) bool { return m.VoteAverage >= 7.8 }).SortBy("vote_count")
+	if ids := movieIDs(chained.Results); !slices.Equal(ids, []int{2, 4, 1}) {
+		t.Errorf("FilterBy then SortBy = %v, want [2 4 1]", ids)
+	}
+}
+
+func TestRating(t *testing.T) {
+	tests := []struct {
+		voteAverage float64
+		voteCount   int
+		average     float64
+		class       string
+	}{
+		{0, 0, 0, "unrated"},
+		{6.5, 0, 0, "unrated"}, // TMDB sometimes reports an average without votes
+		{0, 3, 0, "low"},
+		{4.9, 10, 4.9, "low"},
+		{4.99, 10, 4.99, "low"},
+		{5.0, 10, 5.0, "medium"},
+		{6.9, 10, 6.9, "medium"},
+		{7.0, 10, 7.0, "medium"},
+		{7.01, 10, 7.01, "high"},
+		{10.0, 1, 10.0, "high"},
+	}
+	for _, tt := range tests {
+		m := &MovieDetail{VoteAverage: tt.voteAverage, VoteCount: tt.voteCount}
+		if got := m.AverageRating(); got != tt.average {
+			t.Errorf("AverageRating() of %v from %d votes = %v, want %v", tt.voteAverage, tt.voteCount, got, tt.average)
+		}
+		if got := m.RatingClass(); got != tt.class {
+			t.Errorf("RatingClass() of %v from %d votes = %q, want %q", tt.voteAverage, tt.voteCount, got, tt.class)
+		}
+	}
+}
+
+func TestRatingOnDetailPage(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/movie/603": movieMatrixJSON,
+		"/movie/1":   `{"id":1,"title":"Unseen","vote_average":0,"vote_count":0}`,
+	})
+	app := newTestApp(t, fake)
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/movie/the-matrix-603", `<p class="rating rating-high">8.2/10 from 25000 votes</p>`},
+		{"/movie/unseen-1", `<p class="rating rating-unrated">Not rated yet</p>`},
+	}
+	for _, tt := range tests {
+		if body := get(app, tt.path).Body.String(); !strings.Contains(body, tt.want) {
+			t.Errorf("GET %s does not contain %s", tt.path, tt.want)
+		}
+	}
+}
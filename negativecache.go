@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// negativeCacheTTL is how long a lookup TMDB found nothing for is
+	// answered from memory.
+	negativeCacheTTL = 10 * time.Minute
+
+	// maxNegativeEntries bounds each negative cache, since bots can probe
+	// any number of IDs. Past it, new misses go uncached until old ones
+	// expire.
+	maxNegativeEntries = 10000
+
+	// tmdbNotFound is the status_code in TMDB's error object for a
+	// resource that doesn't exist.
+	tmdbNotFound = 34
+)
+
+// negativeCache remembers lookups TMDB found nothing for, so repeats,
+// mostly bots probing made-up IDs, don't cost a TMDB round trip. It never
+// holds results: a key is only looked up at TMDB once its entry has
+// expired, and a lookup that finds something is simply not added. Each
+// kind of lookup has its own cache, apart from the caches of positive
+// results, so they can be flushed separately.
+type negativeCache struct {
+	name string // Server-Timing entry recorded on each hit
+
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// has reports whether key is cached as not found. A hit is recorded in
+// the request's Server-Timing under the cache's name, which shows how much
+// traffic the cache absorbs.
+func (c *negativeCache) has(ctx context.Context, key string) bool {
+	c.mu.Lock()
+	expires, ok := c.expires[key]
+	if ok && time.Now().After(expires) {
+		delete(c.expires, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		RecordTiming(ctx, c.name, time.Now())
+	}
+	return ok
+}
+
+// add caches key as not found for negativeCacheTTL.
+func (c *negativeCache) add(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.expires == nil {
+		c.expires = map[string]time.Time{}
+	}
+	if len(c.expires) >= maxNegativeEntries {
+		for k, expires := range c.expires {
+			if now.After(expires) {
+				delete(c.expires, k)
+			}
+		}
+		if len(c.expires) >= maxNegativeEntries {
+			return
+		}
+	}
+	c.expires[key] = now.Add(negativeCacheTTL)
+}
+
+// searchKey is the TMDB.emptySearches key of a search. Searches differing only
+// in case or spacing share it. Every key starts with the adult setting, so
+// no keyword can pass for a search with the other setting.
+func searchKey(keyword string, includeAdult bool) string {
+	return strconv.FormatBool(includeAdult) + "/" + strings.Join(strings.Fields(strings.ToLower(keyword)), " ")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchKey(t *testing.T) {
+	same := []string{"dune", "Dune", "DUNE", "  dune ", "\tdune\n"}
+	for _, keyword := range same {
+		if got := searchKey(keyword, false); got != "false/dune" {
+			t.Errorf("searchKey(%q, false) = %q, want %q", keyword, got, "false/dune")
+		}
+	}
+	if a, b := searchKey("the  Dark   knight", false), searchKey("The Dark Knight", false); a != b {
+		t.Errorf("inner spacing changes the key: %q and %q", a, b)
+	}
+	if a, b := searchKey("dune", false), searchKey("dune", true); a == b {
+		t.Errorf("adult and non-adult searches share the key %q", a)
+	}
+	if got := searchKey("Dune", true); got != "true/dune" {
+		t.Errorf(`searchKey("Dune", true) = %q`, got)
+	}
+	// A keyword that looks like the prefix must not collide with it.
+	for _, keyword := range []string{"true/dune", "adult/dune"} {
+		if a, b := searchKey(keyword, false), searchKey("dune", true); a == b {
+			t.Errorf("keyword %q collides with an adult search", keyword)
+		}
+	}
+	if a, b := searchKey("dune part two", false), searchKey("part two dune", false); a == b {
+		t.Errorf("word order is ignored: %q", a)
+	}
+	for i := 0; i < 3; i++ {
+		if got := searchKey("Blade Runner 2049", true); got != "true/blade runner 2049" {
+			t.Fatalf("searchKey is not deterministic: %q", got)
+		}
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	ctx := context.Background()
+	cache := &negativeCache{name: "negative_cache_test"}
+	if cache.has(ctx, "603") {
+		t.Fatal("empty cache has a key")
+	}
+	cache.add("603")
+	if !cache.has(ctx, "603") {
+		t.Fatal("added key is missing")
+	}
+	if cache.has(ctx, "604") {
+		t.Error("cache has a key that was never added")
+	}
+
+	if ttl := time.Until(cache.expires["603"]); ttl <= negativeCacheTTL-time.Minute || ttl > negativeCacheTTL {
+		t.Errorf("entry expires in %s, want about %s", ttl, negativeCacheTTL)
+	}
+
+	// Expire the entry by moving it into the past.
+	cache.expires["603"] = time.Now().Add(-time.Second)
+	if cache.has(ctx, "603") {
+		t.Error("expired key is still cached")
+	}
+	if _, ok := cache.expires["603"]; ok {
+		t.Error("expired key was not removed on lookup")
+	}
+}
+
+func TestNegativeCacheLimit(t *testing.T) {
+	ctx := context.Background()
+	cache := &negativeCache{name: "negative_cache_test"}
+	for i := 0; i < maxNegativeEntries; i++ {
+		cache.add(strconv.Itoa(i))
+	}
+
+	// A full cache of live entries turns new misses away...
+	cache.add("new")
+	if cache.has(ctx, "new") {
+		t.Error("full cache took a new entry")
+	}
+	if len(cache.expires) != maxNegativeEntries {
+		t.Errorf("cache holds %d entries, want %d", len(cache.expires), maxNegativeEntries)
+	}
+	// ...but still answers for the ones it has...
+	if !cache.has(ctx, "0") {
+		t.Error("full cache lost an existing entry")
+	}
+
+	// ...and makes room once some of them expire.
+	for i := 0; i < 10; i++ {
+		cache.expires[strconv.Itoa(i)] = time.Now().Add(-time.Second)
+	}
+	cache.add("new")
+	if !cache.has(ctx, "new") {
+		t.Error("new entry was not added after old ones expired")
+	}
+	if want := maxNegativeEntries - 10 + 1; len(cache.expires) != want {
+		t.Errorf("cache holds %d entries, want %d", len(cache.expires), want)
+	}
+}
+
+func TestEmptySearchIsCached(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/search/movie": `{"page":1,"results":[],"total_pages":0,"total_results":0}`,
+	})
+	app := newTestApp(t, fake)
+
+	for _, target := range []string{"/?keyword=nothing+here", "/?keyword=Nothing++HERE", "/?keyword=nothing%20%20here"} {
+		if rec := get(app, target); rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d", target, rec.Code)
+		}
+	}
+	if calls := fake.calls("/search/movie"); calls != 1 {
+		t.Errorf("%d TMDB searches for one normalised query, want 1", calls)
+	}
+}
+
+func TestFailedSearchIsNotCached(t *testing.T) {
+	failures := []fakeResponse{
+		{Status: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"1"}}, Body: `{"success":false,"status_code":25,"status_message":"Your request count (41) is over the allowed limit of 40."}`},
+		{Status: http.StatusUnauthorized, Body: `{"status_code":7,"status_message":"Invalid API key: You must be granted a valid key.","success":false}`},
+		{Status: http.StatusInternalServerError, Body: `{"success":false}`},
+	}
+	for _, failure := range failures {
+		fake := newFakeTMDB(t, nil)
+		fake.handle("/search/movie", failure)
+		app := newTestApp(t, fake)
+
+		get(app, "/?keyword=matrix")
+		fake.handle("/search/movie", fakeResponse{Body: searchMatrixJSON})
+		if body := get(app, "/?keyword=matrix").Body.String(); !strings.Contains(body, "The Matrix") {
+			t.Errorf("TMDB %d: search after the error doesn't show the results", failure.Status)
+		}
+		if calls := fake.calls("/search/movie"); calls != 2 {
+			t.Errorf("TMDB %d: %d TMDB searches, want 2", failure.Status, calls)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// oEmbedWidth and oEmbedHeight are the size of the embed card unless
+	// the consumer asks for less with maxwidth and maxheight.
+	oEmbedWidth  = 500
+	oEmbedHeight = 300
+
+	// oEmbedOverviewLength is how many runes of the overview the card
+	// shows.
+	oEmbedOverviewLength = 200
+)
+
+// OEmbed is an oEmbed 1.0 "rich" response.
+type OEmbed struct {
+	Type            string `json:"type"`
+	Version         string `json:"version"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name"`
+	AuthorURL       string `json:"author_url"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	CacheAge        int    `json:"cache_age"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+}
+
+var oEmbedTmpl = pageTemplate("oembed", `
+<div style="display: flex; gap: 12px; max-width: {{.Width}}px; max-height: {{.Height}}px; overflow: hidden; font-family: sans-serif;">
+    {{with .Thumbnail}}<a href="{{$.URL}}" style="flex: none;"><img src="{{.}}" width="{{$.ThumbnailWidth}}" height="{{$.ThumbnailHeight}}" alt=""></a>{{end}}
+    <div>
+        <strong><a href="{{.URL}}" dir="auto">{{.Movie.Title}}</a></strong> ({{.Movie.ReleaseDate.DisplayYear}})
+        <br>{{if .Movie.VoteCount}}{{printf "%.1f" .Movie.VoteAverage}}/10 from {{.Movie.VoteCount}} votes{{else}}Not rated yet{{end}}
+        {{with .Overview}}<p dir="auto">{{.}}</p>{{end}}
+        <small>Data from TMDB</small>
+    </div>
+</div>
+`, "")
+
+// oEmbedHandler serves GET /oembed?url=...&format=json for the canonical
+// URLs of our movie pages, so forums and chat apps can show a preview card.
+// Other URLs are a 404, and formats other than JSON a 501, as the oEmbed
+// spec asks.
+func oEmbedHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	if rejectDuplicateAPIParams(w, r, "url", "format", "maxwidth", "maxheight") {
+		return
+	}
+	r, cancel := withDeadline(r, config.DetailTimeout)
+	defer cancel()
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" && format != "json" {
+		writeAPIError(w, http.StatusNotImplemented, apiBadRequest, "Only format=json is supported")
+		return
+	}
+	id, ok := config.URLs.ParseMovie(query.Get("url"))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, apiNotFound, "Not a movie page URL")
+		return
+	}
+	maxWidth, maxHeight, ok := oEmbedBounds(query.Get("maxwidth"), query.Get("maxheight"))
+	if !ok {
+		writeAPIError(w, http.StatusBadRequest, apiBadRequest, "maxwidth and maxheight must be positive integers")
+		return
+	}
+
+	start := time.Now()
+	movie, err := fetchMovieDetails(r.Context(), strconv.Itoa(id), config.TMDB)
+	RecordTiming(r.Context(), "tmdb_detail", start)
+	if err != nil {
+		log.Printf("Error fetching movie details: %v", err)
+		tmdbAPIFailure(w, err, "Failed to fetch movie details")
+		return
+	}
+	// TMDB answers unknown IDs with an error object, which decodes to an
+	// empty movie.
+	if movie.ID == 0 {
+		writeAPIError(w, http.StatusNotFound, apiNotFound, "Movie not found")
+		return
+	}
+
+	card := struct {
+		Movie                           *MovieDetail
+		URL                             string
+		Overview                        string
+		Thumbnail                       string
+		ThumbnailWidth, ThumbnailHeight int
+		Width, Height                   int
+	}{
+		Movie:    movie,
+		URL:      config.URLs.Movie(movie.ID, movie.Title),
+		Overview: excerpt(movie.Overview, oEmbedOverviewLength),
+		Width:    min(oEmbedWidth, maxWidth),
+		Height:   min(oEmbedHeight, maxHeight),
+	}
+	// The thumbnail has to fit the card, so the card's bounds cap it too.
+	if width := oEmbedPosterWidth(card.Width/2, card.Height); width > 0 && movie.PosterPath != "" {
+		card.Thumbnail = imageURL(fmt.Sprintf("w%d", width), movie.PosterPath)
+		card.ThumbnailWidth, card.ThumbnailHeight = width, width*3/2
+	}
+	var html bytes.Buffer
+	if err := oEmbedTmpl.variant(false).Execute(&html, card); err != nil {
+		log.Printf("Error executing template: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, apiInternalError, "Internal Server Error")
+		return
+	}
+
+	response := OEmbed{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        movie.Title,
+		AuthorName:   "TMDB",
+		AuthorURL:    fmt.Sprintf("https://www.themoviedb.org/movie/%d", movie.ID),
+		ProviderName: "Movie Finder",
+		ProviderURL:  config.URLs.Absolute("/"),
+		CacheAge:     3600,
+		HTML:         strings.TrimSpace(html.String()),
+		Width:        card.Width,
+		Height:       card.Height,
+	}
+	if card.Thumbnail != "" {
+		// oEmbed's thumbnail_* fields are all or nothing.
+		response.ThumbnailURL = card.Thumbnail
+		response.ThumbnailWidth, response.ThumbnailHeight = card.ThumbnailWidth, card.ThumbnailHeight
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setCacheControl(w, config, widgetResponse)
+	setSurrogateKeys(w, movieSurrogateKey(movie.ID))
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding oEmbed response: %v", err)
+	}
+}
+
+// oEmbedBounds parses the optional maxwidth and maxheight parameters. Unset
+// ones impose no bound.
+func oEmbedBounds(rawWidth, rawHeight string) (maxWidth, maxHeight int, ok bool) {
+	parse := func(raw string) (int, bool) {
+		if raw == "" {
+			return oEmbedWidth + oEmbedHeight, true // larger than any default
+		}
+		n, err := strconv.Atoi(raw)
+		return n, err == nil && n > 0
+	}
+	maxWidth, okWidth := parse(rawWidth)
+	maxHeight, okHeight := parse(rawHeight)
+	return maxWidth, maxHeight, okWidth && okHeight
+}
+
+// oEmbedPosterWidth returns the widest poster size that fits within
+// maxWidth by maxHeight, or 0 when even the smallest doesn't.
+func oEmbedPosterWidth(maxWidth, maxHeight int) int {
+	best := 0
+	for _, w := range posterWidths {
+		if w <= maxWidth && w*3/2 <= maxHeight {
+			best = w
+		}
+	}
+	return best
+}
+
+// excerpt shortens text to at most maxRunes, cutting at the last space and
+// adding an ellipsis when anything was cut.
+func excerpt(text string, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	cut := string(runes[:maxRunes])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + string(ellipsis)
+}
+
+// oEmbedDiscoveryURL is the oEmbed endpoint URL for a movie page, for the
+// discovery <link> in its head.
+func oEmbedDiscoveryURL(urls URLBuilder, id int, title string) string {
+	return urls.Absolute("/oembed?format=json&url=" + url.QueryEscape(urls.Movie(id, title)))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// duplicateParam returns the first of names that appears more than once in
+// the query string with different values, or "" if there is none. Repeating
+// the same value is harmless and allowed.
+func duplicateParam(r *http.Request, names ...string) string {
+	query := r.URL.Query()
+	for _, name := range names {
+		values := query[name]
+		for _, value := range values[min(1, len(values)):] {
+			if value != values[0] {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// rejectDuplicateParams answers 400 and returns true when one of names is
+// given conflicting values, e.g. ?keyword=a&keyword=b. Query().Get would
+// silently use the first one, hiding the client's bug.
+func rejectDuplicateParams(w http.ResponseWriter, r *http.Request, names ...string) bool {
+	if name := duplicateParam(r, names...); name != "" {
+		http.Error(w, fmt.Sprintf("Conflicting values for the %s parameter", name), http.StatusBadRequest)
+		return true
+	}
+	return false
+}
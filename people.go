@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const personSearchEndpoint = "/search/person"
+
+// PersonSearchResult is one person matching a people search, with the
+// titles they are best known for.
+type PersonSearchResult struct {
+	ID                 int            `json:"id"`
+	Name               string         `json:"name"`
+	KnownForDepartment string         `json:"known_for_department"`
+	ProfilePath        string         `json:"profile_path"`
+	KnownFor           []KnownForItem `json:"known_for"`
+}
+
+// KnownForItem is a movie or TV show a person is known for. Like Credit,
+// movies use title while TV shows use name.
+type KnownForItem struct {
+	ID         int    `json:"id"`
+	MediaType  string `json:"media_type"`
+	Title      string `json:"title"`
+	Name       string `json:"name"`
+	PosterPath string `json:"poster_path"`
+}
+
+// DisplayTitle returns the movie title or the TV show name.
+func (k KnownForItem) DisplayTitle() string {
+	if k.Title != "" {
+		return k.Title
+	}
+	return k.Name
+}
+
+// PersonSearchResults wraps the list of people returned by the API.
+type PersonSearchResults struct {
+	Results []PersonSearchResult `json:"results"`
+}
+
+// PeoplePage is the data rendered by the people search template.
+type PeoplePage struct {
+	Query  string
+	Actors []PersonSearchResult
+	Crew   []PersonSearchResult
+	Meta   Meta
+	Theme  string
+}
+
+var peopleTmpl = pageTemplate("people", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>People matching {{.Query}}</title>
+    <style>
+        .tabs > input { position: absolute; opacity: 0; }
+        .tabs > label { display: inline-block; padding: .3em .8em; border-bottom: 2px solid transparent; cursor: pointer; }
+        .tabs > input:checked + label { border-bottom-color: var(--link); font-weight: bold; }
+        .tabs > input:focus-visible + label { outline: 2px solid var(--link); }
+        .tabs > section { display: none; }
+        #tab-actors:checked ~ .actors, #tab-crew:checked ~ .crew { display: block; }
+    </style>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1>People matching &ldquo;<span dir="auto">{{.Query}}</span>&rdquo;</h1>
+    {{block "results" .}}
+    <div class="tabs">
+        <input type="radio" name="tab" id="tab-actors"{{if or .Actors (not .Crew)}} checked{{end}}>
+        <label for="tab-actors">Actors ({{len .Actors}})</label>
+        <input type="radio" name="tab" id="tab-crew"{{if and .Crew (not .Actors)}} checked{{end}}>
+        <label for="tab-crew">Directors &amp; Crew ({{len .Crew}})</label>
+        <section class="actors">{{range .Actors}}{{template "person" .}}{{else}}<p>No actors found.</p>{{end}}</section>
+        <section class="crew">{{range .Crew}}{{template "person" .}}{{else}}<p>No directors or crew found.</p>{{end}}</section>
+    </div>
+    {{end}}
+    {{template "footer"}}
+</body>
+</html>
+{{define "person"}}
+<article>
+    <h2><a href="/person/{{.ID}}" dir="auto">{{.Name}}</a> <small>{{.KnownForDepartment}}</small></h2>
+    {{with .KnownFor}}
+    <div style="display: flex; gap: .75em; overflow-x: auto;">
+        {{range .}}
+        {{if eq .MediaType "movie"}}<a href="{{movieURL .ID .Title}}" title="{{.Title}}" style="flex: none;">{{posterImg .PosterPath 92 .Title}}</a>{{else}}<span title="{{.DisplayTitle}}" style="flex: none;">{{posterImg .PosterPath 92 .DisplayTitle}}</span>{{end}}
+        {{end}}
+    </div>
+    {{end}}
+</article>
+{{end}}
+`, `
+{{define "results"}}
+<h2>Actors</h2>
+{{with .Actors}}<ol>{{range .}}<li>{{template "person" .}}</li>{{end}}</ol>{{else}}<p>No actors found.</p>{{end}}
+<h2>Directors &amp; Crew</h2>
+{{with .Crew}}<ol>{{range .}}<li>{{template "person" .}}</li>{{end}}</ol>{{else}}<p>No directors or crew found.</p>{{end}}
+{{end}}
+{{define "person"}}
+<a href="/person/{{.ID}}" dir="auto">{{.Name}}</a> ({{.KnownForDepartment}}){{with .KnownFor}}, known for {{range $i, $item := .}}{{if $i}}, {{end}}{{if eq $item.MediaType "movie"}}<a href="{{movieURL $item.ID $item.Title}}" dir="auto">{{$item.Title}}</a>{{else}}<span dir="auto">{{$item.DisplayTitle}}</span>{{end}}{{end}}{{end}}
+{{end}}
+`)
+
+// peopleHandler serves GET /people?query=...: matching people split into
+// actors and directors & crew, each with the posters of what they are
+// known for.
+func peopleHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	if rejectDuplicateParams(w, r, "query") {
+		return
+	}
+	r, cancel := withDeadline(r, config.SearchTimeout)
+	defer cancel()
+	query := strings.TrimSpace(r.URL.Query().Get("query"))
+	if query == "" {
+		http.Error(w, "Missing query parameter", http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	results, err := searchPeople(r.Context(), query, config.TMDB, config.IncludeAdult && !config.AdultContentLocked)
+	RecordTiming(r.Context(), "tmdb_search_person", start)
+	if err != nil {
+		log.Printf("Error searching people: %v", err)
+		tmdbFailure(w, r, err, "Failed to search people")
+		return
+	}
+
+	page := PeoplePage{Query: query, Meta: pageMeta("/people"), Theme: theme(r)}
+	page.Actors, page.Crew = partitionPersonResults(results.Results)
+	body, err := render(r, peopleTmpl, page)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, searchResponse)
+	body.WriteTo(w)
+}
+
+// partitionPersonResults splits people by the department they are known
+// for: Acting goes to actors, every other department (Directing and
+// Writing, but also the rest of the crew) to crew. Both keep TMDB's order.
+func partitionPersonResults(results []PersonSearchResult) (actors, crew []PersonSearchResult) {
+	for _, person := range results {
+		if person.KnownForDepartment == "Acting" {
+			actors = append(actors, person)
+		} else {
+			crew = append(crew, person)
+		}
+	}
+	return actors, crew
+}
+
+func searchPeople(ctx context.Context, query string, tmdb *TMDB, includeAdult bool) (*PersonSearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s&query=%s&include_adult=%t", tmdb.BaseURL, personSearchEndpoint, tmdb.APIKey, url.QueryEscape(query), includeAdult)
+	var results PersonSearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const personEndpoint = "/person/"
+
+// Person holds the profile information shown at the top of a person page.
+type Person struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Biography string `json:"biography"`
+}
+
+// Credit is one entry of a person's combined movie and TV credits.
+// Movies use title/release_date while TV shows use name/first_air_date.
+type Credit struct {
+	ID           int         `json:"id"`
+	MediaType    string      `json:"media_type"`
+	Title        string      `json:"title"`
+	Name         string      `json:"name"`
+	ReleaseDate  ReleaseDate `json:"release_date"`
+	FirstAirDate ReleaseDate `json:"first_air_date"`
+	Popularity   float64     `json:"popularity"`
+	Character    string      `json:"character"`
+	Job          string      `json:"job"`
+}
+
+// CombinedCredits wraps the cast and crew lists from combined_credits.
+type CombinedCredits struct {
+	Cast []Credit `json:"cast"`
+	Crew []Credit `json:"crew"`
+}
+
+// FilmographyEntry is a single title in the unified filmography, with every
+// role the person had on it.
+type FilmographyEntry struct {
+	Credit
+	Roles []string
+}
+
+// PersonPage is the data rendered by the person template.
+type PersonPage struct {
+	Person   *Person
+	Filter   string
+	Sort     string
+	Released []FilmographyEntry
+	Upcoming []FilmographyEntry
+	Roles    []RoleCount
+	Meta     Meta
+	Theme    string
+}
+
+var personTmpl = pageTemplate("person", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>{{.Person.Name}}</title>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1 dir="auto">{{.Person.Name}}</h1>
+    <p dir="auto">{{.Person.Biography}}</p>
+    {{with .Roles}}
+    <p>Crew roles: {{range $i, $role := .}}{{if $i}} &middot; {{end}}<a href="/person/{{$.Person.ID}}/role/{{$role.Slug}}">{{$role.Title}}</a> ({{$role.Count}}){{end}}</p>
+    {{end}}
+    <p>
+        Show:
+        <a href="?type=all&sort={{.Sort}}">All</a> |
+        <a href="?type=movie&sort={{.Sort}}">Movies</a> |
+        <a href="?type=tv&sort={{.Sort}}">TV</a>
+        &middot; Sort by:
+        <a href="?type={{.Filter}}&sort=date">Date</a> |
+        <a href="?type={{.Filter}}&sort=popularity">Popularity</a>
+    </p>
+    {{block "filmography" .}}
+    {{if .Upcoming}}
+    <h2>Upcoming</h2>
+    {{range .Upcoming}}{{template "entry" .}}{{end}}
+    {{end}}
+    <h2>Filmography</h2>
+    {{range .Released}}{{template "entry" .}}{{else}}<p>No credits found.</p>{{end}}
+    {{end}}
+    {{template "footer"}}
+</body>
+</html>
+{{define "entry"}}
+    <p>
+        <span>[{{if eq .MediaType "tv"}}TV{{else}}Movie{{end}}]</span>
+        <a href="{{.Link}}"><span dir="auto">{{.DisplayTitle}}</span>{{with .Year}} ({{.}}){{end}}</a>
+        {{with .Roles}}&ndash; {{join . ", "}}{{end}}
+    </p>
+{{end}}
+`, `
+{{define "filmography"}}
+{{with .Upcoming}}
+<h2>Upcoming</h2>
+<ol>
+    {{range .}}<li>{{template "entry" .}}</li>{{end}}
+</ol>
+{{end}}
+<h2>Filmography</h2>
+{{with .Released}}
+<ol>
+    {{range .}}<li>{{template "entry" .}}</li>{{end}}
+</ol>
+{{else}}
+<p>No credits found.</p>
+{{end}}
+{{end}}
+`)
+
+// personHandler serves /person/{id}, the person's profile and filmography,
+// and /person/{id}/role/{slug} through personRoleHandler.
+func personHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	if rejectDuplicateParams(w, r, "type", "sort") {
+		return
+	}
+	r, cancel := withDeadline(r, config.DetailTimeout)
+	defer cancel()
+
+	// Extracting the person ID from the URL path.
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 || pathParts[2] == "" {
+		http.Error(w, "Invalid person ID", http.StatusBadRequest)
+		return
+	}
+	// TMDB person IDs are positive integers; anything else names nobody.
+	id, err := strconv.Atoi(pathParts[2])
+	if err != nil || id <= 0 {
+		http.Error(w, "Person not found", http.StatusNotFound)
+		return
+	}
+	personID := strconv.Itoa(id)
+	if len(pathParts) == 5 && pathParts[3] == "role" && pathParts[4] != "" {
+		personRoleHandler(w, r, config, personID, pathParts[4])
+		return
+	}
+
+	start := time.Now()
+	person, err := fetchPerson(r.Context(), personID, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_person", start)
+	if err != nil {
+		log.Printf("Error fetching person: %v", err)
+		tmdbFailure(w, r, err, "Failed to fetch person")
+		return
+	}
+	// TMDB answers unknown IDs with an error object, which decodes to an
+	// empty person.
+	if person.ID == 0 {
+		http.Error(w, "Person not found", http.StatusNotFound)
+		return
+	}
+
+	start = time.Now()
+	credits, err := fetchCombinedCredits(r.Context(), personID, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_credits", start)
+	if err != nil {
+		log.Printf("Error fetching combined credits: %v", err)
+		tmdbFailure(w, r, err, "Failed to fetch credits")
+		return
+	}
+
+	page := PersonPage{
+		Person: person,
+		Filter: r.URL.Query().Get("type"),
+		Sort:   r.URL.Query().Get("sort"),
+		Roles:  personRoles(credits),
+		Meta:   pageMeta("/person/"),
+		Theme:  theme(r),
+	}
+	if page.Filter != "movie" && page.Filter != "tv" {
+		page.Filter = "all"
+	}
+	if page.Sort != "popularity" {
+		page.Sort = "date"
+	}
+
+	for _, entry := range buildFilmography(credits, page.Filter, page.Sort) {
+		if !entry.Date().Known() {
+			page.Upcoming = append(page.Upcoming, entry)
+		} else {
+			page.Released = append(page.Released, entry)
+		}
+	}
+
+	body, err := render(r, personTmpl, page)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, detailResponse)
+	body.WriteTo(w)
+}
+
+// buildFilmography merges cast and crew credits into one entry per title,
+// keeps only the requested media type ("all", "movie" or "tv") and sorts the
+// result by date (newest first) or popularity.
+func buildFilmography(credits *CombinedCredits, filter string, sortBy string) []FilmographyEntry {
+	var entries []FilmographyEntry
+	index := make(map[string]int)
+
+	add := func(c Credit, role string) {
+		if filter != "all" && c.MediaType != filter {
+			return
+		}
+		key := fmt.Sprintf("%s-%d", c.MediaType, c.ID)
+		i, ok := index[key]
+		if !ok {
+			i = len(entries)
+			index[key] = i
+			entries = append(entries, FilmographyEntry{Credit: c})
+		}
+		if role != "" {
+			entries[i].Roles = append(entries[i].Roles, role)
+		}
+	}
+	for _, c := range credits.Cast {
+		add(c, c.Character)
+	}
+	for _, c := range credits.Crew {
+		add(c, c.Job)
+	}
+
+	sortFilmography(entries, sortBy)
+	return entries
+}
+
+// sortFilmography orders entries by popularity or, for any other sortBy,
+// by date, newest first. Entries are credits rather than Movies and a TV
+// credit is dated by its first air date, so they can't go through
+// sortMovies.
+func sortFilmography(entries []FilmographyEntry, sortBy string) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if sortBy == "popularity" {
+			return entries[i].Popularity > entries[j].Popularity
+		}
+		return entries[i].Date().String() > entries[j].Date().String()
+	})
+}
+
+// DisplayTitle returns the movie title or TV show name.
+func (c Credit) DisplayTitle() string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return c.Name
+}
+
+// Date returns the release or first air date.
+func (c Credit) Date() ReleaseDate {
+	if c.ReleaseDate.Known() {
+		return c.ReleaseDate
+	}
+	return c.FirstAirDate
+}
+
+// Year returns the year of Date, or "TBA" when it isn't known yet.
+func (c Credit) Year() string {
+	return c.Date().DisplayYear()
+}
+
+// Link points movies at our detail page. There is no TV page in the app,
+// so TV credits link to TMDB instead.
+func (c Credit) Link() string {
+	if c.MediaType == "tv" {
+		return fmt.Sprintf("https://www.themoviedb.org/tv/%d", c.ID)
+	}
+	return movieURL(c.ID, c.DisplayTitle())
+}
+
+func fetchPerson(ctx context.Context, personID string, tmdb *TMDB) (*Person, error) {
+	requestURL := fmt.Sprintf("%s%s%s?api_key=%s", tmdb.BaseURL, personEndpoint, personID, tmdb.APIKey)
+	var person Person
+	if err := tmdbGet(ctx, tmdb, requestURL, &person); err != nil {
+		return nil, err
+	}
+
+	return &person, nil
+}
+
+func fetchCombinedCredits(ctx context.Context, personID string, tmdb *TMDB) (*CombinedCredits, error) {
+	requestURL := fmt.Sprintf("%s%s%s/combined_credits?api_key=%s", tmdb.BaseURL, personEndpoint, personID, tmdb.APIKey)
+	var credits CombinedCredits
+	if err := tmdbGet(ctx, tmdb, requestURL, &credits); err != nil {
+		return nil, err
+	}
+
+	return &credits, nil
+}
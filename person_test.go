@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPersonPage(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/person/6384":                  `{"id":6384,"name":"Keanu Reeves","biography":"Actor."}`,
+		"/person/6384/combined_credits": `{"cast":[{"id":603,"media_type":"movie","title":"The Matrix","release_date":"1999-03-30","character":"Neo"}],"crew":[]}`,
+	})
+	app := newTestApp(t, fake)
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/person/6384", http.StatusOK, "Keanu Reeves"},
+		{"/person/999999999", http.StatusNotFound, "Person not found"},
+		{"/person/keanu", http.StatusNotFound, "Person not found"},
+		{"/person/-1", http.StatusNotFound, "Person not found"},
+		{"/person/999999999/role/director", http.StatusNotFound, "Person not found"},
+		{"/person/", http.StatusBadRequest, "Invalid person ID"},
+	}
+	for _, tt := range tests {
+		rec := get(app, tt.path)
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("GET %s = %d, want %d with %q", tt.path, rec.Code, tt.status, tt.want)
+		}
+	}
+	if n := fake.calls("/person/keanu"); n != 0 {
+		t.Errorf("non-numeric ID sent to TMDB %d times", n)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// plannerMinVotes keeps barely rated movies, which top any sort by vote
+// average, out of the plan.
+const plannerMinVotes = 500
+
+// movieGenres are TMDB's movie genres, used to pick genres at random when
+// the request doesn't name any.
+var movieGenres = map[int]string{
+	28:    "Action",
+	12:    "Adventure",
+	16:    "Animation",
+	35:    "Comedy",
+	80:    "Crime",
+	99:    "Documentary",
+	18:    "Drama",
+	10751: "Family",
+	14:    "Fantasy",
+	36:    "History",
+	27:    "Horror",
+	10402: "Music",
+	9648:  "Mystery",
+	10749: "Romance",
+	878:   "Science Fiction",
+	53:    "Thriller",
+	10752: "War",
+	37:    "Western",
+}
+
+// movieGenreOptions are movieGenres sorted by name, for genre dropdowns.
+var movieGenreOptions = sortedGenres(movieGenres)
+
+// sortedGenres returns genres sorted by name, ignoring case, so dropdowns
+// list them alphabetically rather than in map order.
+func sortedGenres(genres map[int]string) []Genre {
+	sorted := make([]Genre, 0, len(genres))
+	for id, name := range genres {
+		sorted = append(sorted, Genre{ID: id, Name: name})
+	}
+	slices.SortFunc(sorted, func(a, b Genre) int {
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	return sorted
+}
+
+// MovieNight is an evening of three movies from different genres.
+type MovieNight struct {
+	Movies       [3]Movie
+	Genres       [3]string
+	Runtimes     [3]int // minutes, 0 when unknown
+	TotalRuntime int    // minutes, counting only movies whose runtime is known
+}
+
+// PlannerPage is the data rendered by the planner template.
+type PlannerPage struct {
+	*MovieNight
+	Meta  Meta
+	Theme string
+}
+
+var plannerTmpl = pageTemplate("planner", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>Movie Night Plan</title>
+    <style>
+        .collage { display: flex; gap: 1em; flex-wrap: wrap; }
+        .collage figure { margin: 0; }
+        .popcorn { display: inline-block; animation: pop 1.2s ease-in-out infinite; }
+        .popcorn:nth-child(2) { animation-delay: .2s; }
+        .popcorn:nth-child(3) { animation-delay: .4s; }
+        @keyframes pop { 0%, 100% { transform: translateY(0); } 40% { transform: translateY(-.6em) rotate(15deg); } }
+        @media (prefers-reduced-motion: reduce) { .popcorn { animation: none; } }
+    </style>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1><span class="popcorn">🍿</span><span class="popcorn">🍿</span><span class="popcorn">🍿</span> Movie Night Plan</h1>
+    {{block "plan" .}}
+    <div class="collage">
+        {{range $i, $movie := .Movies}}
+        <figure>
+            <a href="{{movieURL $movie.ID $movie.Title}}">{{posterImg $movie.PosterPath 185 ""}}</a>
+            <figcaption><small>{{index $.Genres $i}}</small><br><a href="{{movieURL $movie.ID $movie.Title}}" dir="auto">{{$movie.Title}}</a> ({{$movie.ReleaseDate.DisplayYear}})</figcaption>
+        </figure>
+        {{end}}
+    </div>
+    {{end}}
+    {{if .TotalRuntime}}<p>Total runtime: {{.TotalRuntime}} min</p>{{end}}
+    <p><a href="/planner/generate">Plan another night</a></p>
+    {{template "footer"}}
+</body>
+</html>
+`, `
+{{define "plan"}}
+<ol>
+    {{range $i, $movie := .Movies}}<li>{{index $.Genres $i}}: <a href="{{movieURL $movie.ID $movie.Title}}" dir="auto">{{$movie.Title}}</a> ({{$movie.ReleaseDate.DisplayYear}}){{if $movie.VoteCount}}, rated {{printf "%.1f" $movie.VoteAverage}}/10{{end}}{{with index $.Runtimes $i}}, {{.}} min{{end}}</li>
+    {{end}}
+</ol>
+{{end}}
+`)
+
+// plannerHandler serves GET /planner/generate?genres=28,35,18. Without
+// genres, three are picked at random.
+func plannerHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	if rejectDuplicateParams(w, r, "genres") {
+		return
+	}
+
+	genres, err := parsePlannerGenres(r.URL.Query().Get("genres"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	night, err := planMovieNight(r.Context(), genres, config.TMDB)
+	if err != nil {
+		log.Printf("Error planning movie night: %v", err)
+		tmdbFailure(w, r, err, "Failed to plan movie night")
+		return
+	}
+
+	page, err := render(r, plannerTmpl, PlannerPage{MovieNight: night, Meta: pageMeta("/planner/generate"), Theme: theme(r)})
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, searchResponse)
+	page.WriteTo(w)
+}
+
+// parsePlannerGenres reads three distinct genre IDs, or picks three random
+// genres when raw is empty.
+func parsePlannerGenres(raw string) ([3]int, error) {
+	var genres [3]int
+	if strings.TrimSpace(raw) == "" {
+		ids := make([]int, 0, len(movieGenres))
+		for id := range movieGenres {
+			ids = append(ids, id)
+		}
+		rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		copy(genres[:], ids)
+		return genres, nil
+	}
+
+	parts := strings.Split(raw, ",")
+	if len(parts) != len(genres) {
+		return genres, fmt.Errorf("genres must list exactly %d genre IDs", len(genres))
+	}
+	for i, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if _, known := movieGenres[id]; err != nil || !known {
+			return genres, fmt.Errorf("unknown genre %q", part)
+		}
+		for _, seen := range genres[:i] {
+			if seen == id {
+				return genres, fmt.Errorf("genres must be different")
+			}
+		}
+		genres[i] = id
+	}
+	return genres, nil
+}
+
+// planMovieNight picks the best-rated movie of each genre, skipping movies
+// already chosen for an earlier genre, and adds up their runtimes.
+func planMovieNight(ctx context.Context, genres [3]int, tmdb *TMDB) (*MovieNight, error) {
+	night := &MovieNight{}
+	chosen := map[int]bool{}
+	for i, genre := range genres {
+		start := time.Now()
+		results, err := fetchTopRatedInGenre(ctx, genre, tmdb)
+		RecordTiming(ctx, "tmdb_discover", start)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for _, movie := range results.Results {
+			if !chosen[movie.ID] {
+				chosen[movie.ID] = true
+				night.Movies[i] = movie
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no movie found for genre %d", genre)
+		}
+		night.Genres[i] = movieGenres[genre]
+
+		// Discover results don't include the runtime.
+		start = time.Now()
+		detail, err := fetchMovieDetails(ctx, strconv.Itoa(night.Movies[i].ID), tmdb)
+		RecordTiming(ctx, "tmdb_detail", start)
+		if err != nil {
+			log.Printf("Error fetching runtime for movie %d: %v", night.Movies[i].ID, err)
+			continue
+		}
+		night.Runtimes[i] = detail.Runtime
+		night.TotalRuntime += detail.Runtime
+	}
+	return night, nil
+}
+
+func fetchTopRatedInGenre(ctx context.Context, genre int, tmdb *TMDB) (*SearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s&with_genres=%d&sort_by=vote_average.desc&vote_count.gte=%d&page=1",
+		tmdb.BaseURL, discoverEndpoint, tmdb.APIKey, genre, plannerMinVotes)
+	var results SearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
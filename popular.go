@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"sync"
+	"time"
+)
+
+const (
+	popularEndpoint = "/movie/popular"
+
+	defaultHomepageMovieCount = 6
+	maxHomepageMovieCount     = 20
+
+	// popularStripTTL is how long the rendered strip is reused. TMDB's
+	// popularity list only changes a few times a day.
+	popularStripTTL = 30 * time.Minute
+)
+
+var popularStripTmpl = pageTemplate("popular", `
+<section>
+    <h2>Popular now</h2>
+    {{template "strip" .}}
+</section>
+`, "")
+
+// popularStripCache holds the rendered popular strip shared by all visitors,
+// in its standard and text-only variants.
+type popularStripCache struct {
+	mu       sync.Mutex
+	standard template.HTML
+	text     template.HTML
+	expires  time.Time
+}
+
+// popularStrip returns the home page's row of popular movie thumbnails,
+// rendering it at most once per popularStripTTL. Failures aren't cached, so
+// the next request tries again; while TMDB is down for maintenance, an
+// expired strip is served instead.
+func popularStrip(ctx context.Context, count int, tmdb *TMDB, textOnly bool) (template.HTML, error) {
+	tmdb.popularStrip.mu.Lock()
+	defer tmdb.popularStrip.mu.Unlock()
+	if !time.Now().Before(tmdb.popularStrip.expires) {
+		start := time.Now()
+		results, err := fetchPopular(ctx, tmdb)
+		RecordTiming(ctx, "tmdb_popular", start)
+		if err != nil {
+			if inMaintenance(err) && tmdb.popularStrip.standard != "" {
+				return tmdb.popularStrip.variant(textOnly), nil
+			}
+			return "", err
+		}
+		movies := results.Results
+		if len(movies) > count {
+			movies = movies[:count]
+		}
+
+		var standard, text bytes.Buffer
+		if err := popularStripTmpl.standard.Execute(&standard, movies); err != nil {
+			return "", err
+		}
+		if err := popularStripTmpl.text.Execute(&text, movies); err != nil {
+			return "", err
+		}
+		tmdb.popularStrip.standard = template.HTML(standard.String())
+		tmdb.popularStrip.text = template.HTML(text.String())
+		tmdb.popularStrip.expires = time.Now().Add(popularStripTTL)
+	}
+
+	return tmdb.popularStrip.variant(textOnly), nil
+}
+
+// variant returns the cached strip for the text-only or the standard
+// variant. c.mu must be held.
+func (c *popularStripCache) variant(textOnly bool) template.HTML {
+	if textOnly {
+		return c.text
+	}
+	return c.standard
+}
+
+func fetchPopular(ctx context.Context, tmdb *TMDB) (*SearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s", tmdb.BaseURL, popularEndpoint, tmdb.APIKey)
+	var results SearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
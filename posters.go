@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// posterCookie holds the visitor's poster preference: "original" for the
+// original-language poster, anything else for TMDB's default one.
+const posterCookie = "posters"
+
+// MovieImage is one poster or backdrop from a movie's images. Language is
+// "" for images without text.
+type MovieImage struct {
+	FilePath    string  `json:"file_path"`
+	Language    string  `json:"iso_639_1"`
+	VoteAverage float64 `json:"vote_average"`
+}
+
+// MovieImages are the images of a movie.
+type MovieImages struct {
+	Posters []MovieImage `json:"posters"`
+}
+
+// preferOriginalPosters reports whether the visitor chose original-language
+// posters at /settings. Pages that depend on it must send Vary: Cookie.
+func preferOriginalPosters(r *http.Request) bool {
+	cookie, err := r.Cookie(posterCookie)
+	return err == nil && cookie.Value == "original"
+}
+
+// pickPoster returns the poster in the first of languages that has any,
+// where "" stands for posters without text. Among a language's posters the
+// best voted wins, ties going to TMDB's order. It returns "" when no poster
+// is in any of languages.
+func pickPoster(posters []MovieImage, languages ...string) string {
+	for _, language := range languages {
+		var best *MovieImage
+		for i, poster := range posters {
+			if poster.Language == language && (best == nil || poster.VoteAverage > best.VoteAverage) {
+				best = &posters[i]
+			}
+		}
+		if best != nil {
+			return best.FilePath
+		}
+	}
+	return ""
+}
+
+// originalPoster returns the movie's original-language poster, else one
+// without text, else "" to keep the default poster_path.
+func originalPoster(ctx context.Context, movie *MovieDetail, tmdb *TMDB) (string, error) {
+	images, err := fetchPosters(ctx, movie.ID, tmdb, movie.OriginalLanguage)
+	if err != nil {
+		return "", err
+	}
+	return pickPoster(images.Posters, movie.OriginalLanguage, ""), nil
+}
+
+// fetchPosters fetches the posters of a movie in language and without text
+// ("null" to TMDB).
+func fetchPosters(ctx context.Context, movieID int, tmdb *TMDB, language string) (*MovieImages, error) {
+	requestURL := fmt.Sprintf("%s%s%d/images?api_key=%s&include_image_language=%s,null", tmdb.BaseURL, movieEndpoint, movieID, tmdb.APIKey, language)
+	var images MovieImages
+	if err := tmdbGet(ctx, tmdb, requestURL, &images); err != nil {
+		return nil, err
+	}
+
+	return &images, nil
+}
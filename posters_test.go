@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPickPoster(t *testing.T) {
+	posters := []MovieImage{
+		{FilePath: "/en-low.jpg", Language: "en", VoteAverage: 5.1},
+		{FilePath: "/ja-first.jpg", Language: "ja", VoteAverage: 5.3},
+		{FilePath: "/textless.jpg", Language: "", VoteAverage: 5.0},
+		{FilePath: "/en-best.jpg", Language: "en", VoteAverage: 5.6},
+		{FilePath: "/ja-tie.jpg", Language: "ja", VoteAverage: 5.3},
+	}
+
+	tests := []struct {
+		name      string
+		posters   []MovieImage
+		languages []string
+		want      string
+	}{
+		{"preferred language, best voted", posters, []string{"en", ""}, "/en-best.jpg"},
+		{"tie goes to TMDB's order", posters, []string{"ja", ""}, "/ja-first.jpg"},
+		{"preferred language missing falls back to textless", posters, []string{"fr", ""}, "/textless.jpg"},
+		{"textless first", posters, []string{"", "en"}, "/textless.jpg"},
+		{"no language matches", posters, []string{"fr", "de"}, ""},
+		{"no languages", posters, nil, ""},
+		{"no posters", nil, []string{"en", ""}, ""},
+		{"only textless posters", []MovieImage{{FilePath: "/a.jpg"}, {FilePath: "/b.jpg", VoteAverage: 1}}, []string{"ko", ""}, "/b.jpg"},
+	}
+	for _, tt := range tests {
+		if got := pickPoster(tt.posters, tt.languages...); got != tt.want {
+			t.Errorf("%s: pickPoster(%q) = %q, want %q", tt.name, tt.languages, got, tt.want)
+		}
+	}
+}
+
+func TestMovieImagesNullLanguage(t *testing.T) {
+	var images MovieImages
+	if err := json.Unmarshal([]byte(`{"posters":[{"file_path":"/a.jpg","iso_639_1":null,"vote_average":5}]}`), &images); err != nil {
+		t.Fatal(err)
+	}
+	if got := pickPoster(images.Posters, ""); got != "/a.jpg" {
+		t.Errorf("a poster with a null language is not picked as textless: %q", got)
+	}
+}
+
+func TestOriginalPosterPreference(t *testing.T) {
+	const detail = `{"id":129,"title":"Spirited Away","original_language":"ja","poster_path":"/default-en.jpg"}`
+	tests := []struct {
+		name   string
+		images string // "" for TMDB not finding the images
+		cookie bool
+		want   string
+	}{
+		{"default", `{"posters":[{"file_path":"/ja.jpg","iso_639_1":"ja"}]}`, false, "/default-en.jpg"},
+		{"original language", `{"posters":[{"file_path":"/ja.jpg","iso_639_1":"ja"},{"file_path":"/none.jpg","iso_639_1":null}]}`, true, "/ja.jpg"},
+		{"textless when no original", `{"posters":[{"file_path":"/none.jpg","iso_639_1":null}]}`, true, "/none.jpg"},
+		{"default when no posters", `{"posters":[]}`, true, "/default-en.jpg"},
+		{"default when images are not found", "", true, "/default-en.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies := map[string]string{"/movie/129": detail}
+			if tt.images != "" {
+				bodies["/movie/129/images"] = tt.images
+			}
+			fake := newFakeTMDB(t, bodies)
+			app := newTestApp(t, fake)
+
+			req := httptest.NewRequest(http.MethodGet, "/movie/spirited-away-129", nil)
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: posterCookie, Value: "original"})
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET = %d", rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("page does not show %s", tt.want)
+			}
+			if calls := fake.calls("/movie/129/images"); (calls > 0) != tt.cookie {
+				t.Errorf("%d images calls with the preference set to %v", calls, tt.cookie)
+			}
+		})
+	}
+}
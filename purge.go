@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// purgeWebhookTimeout bounds the call to CDN_PURGE_WEBHOOK_URL.
+const purgeWebhookTimeout = 10 * time.Second
+
+// purgeResponse is the /admin/purge response body.
+type purgeResponse struct {
+	Keys []string `json:"keys"`
+	// Purged is true once CDN_PURGE_WEBHOOK_URL accepted the keys, and
+	// false when no webhook is configured.
+	Purged bool `json:"purged"`
+}
+
+// purgeWebhookPayload is POSTed to CDN_PURGE_WEBHOOK_URL.
+type purgeWebhookPayload struct {
+	SurrogateKeys []string `json:"surrogate_keys"`
+}
+
+// moviePurgeKeys returns the surrogate keys that drop every cached response
+// showing the movie.
+func moviePurgeKeys(id int) []string {
+	return []string{movieSurrogateKey(id)}
+}
+
+// purgeHandler serves POST /admin/purge?movie={id}: it answers with the
+// surrogate keys to purge for the movie and, when CDN_PURGE_WEBHOOK_URL is
+// set, sends them there. It needs ADMIN_TOKEN as a bearer token and is a
+// 404 while ADMIN_TOKEN is unset.
+func purgeHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	if config.AdminToken == "" {
+		writeAPIError(w, http.StatusNotFound, apiNotFound, "Not found")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if !adminAuthorized(r, config.AdminToken) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeAPIError(w, http.StatusUnauthorized, apiUnauthorized, "Missing or wrong admin token")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeAPIError(w, http.StatusMethodNotAllowed, apiBadRequest, "Use POST")
+		return
+	}
+	if rejectDuplicateAPIParams(w, r, "movie") {
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("movie"))
+	if err != nil || id <= 0 {
+		writeAPIError(w, http.StatusBadRequest, apiBadRequest, "movie must be a TMDB movie ID")
+		return
+	}
+
+	response := purgeResponse{Keys: moviePurgeKeys(id)}
+	if config.PurgeWebhookURL != "" {
+		if err := sendPurge(r.Context(), config.PurgeWebhookURL, response.Keys); err != nil {
+			log.Printf("Error purging movie %d: %v", id, err)
+			writeAPIError(w, http.StatusBadGateway, apiPurgeFailed, "The CDN purge webhook failed")
+			return
+		}
+		response.Purged = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding purge response: %v", err)
+	}
+}
+
+// adminAuthorized reports whether r carries token as its bearer token.
+func adminAuthorized(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// sendPurge POSTs keys to the CDN purge webhook.
+func sendPurge(ctx context.Context, webhookURL string, keys []string) error {
+	payload, err := json.Marshal(purgeWebhookPayload{SurrogateKeys: keys})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, purgeWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
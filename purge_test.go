@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// purgeRequest serves a /admin/purge request with token as the bearer
+// token, or none when token is "".
+func purgeRequest(app http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPurgeHandler(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "s3cret")
+	app := newTestApp(t, newFakeTMDB(t, nil))
+
+	tests := []struct {
+		name, method, target, token string
+		status                      int
+		code                        string // error code, "" on success
+	}{
+		{"no token", http.MethodPost, "/admin/purge?movie=603", "", http.StatusUnauthorized, apiUnauthorized},
+		{"wrong token", http.MethodPost, "/admin/purge?movie=603", "guess", http.StatusUnauthorized, apiUnauthorized},
+		{"token prefix", http.MethodPost, "/admin/purge?movie=603", "s3cre", http.StatusUnauthorized, apiUnauthorized},
+		{"GET", http.MethodGet, "/admin/purge?movie=603", "s3cret", http.StatusMethodNotAllowed, apiBadRequest},
+		{"no movie", http.MethodPost, "/admin/purge", "s3cret", http.StatusBadRequest, apiBadRequest},
+		{"not an ID", http.MethodPost, "/admin/purge?movie=the-matrix", "s3cret", http.StatusBadRequest, apiBadRequest},
+		{"zero", http.MethodPost, "/admin/purge?movie=0", "s3cret", http.StatusBadRequest, apiBadRequest},
+		{"two movies", http.MethodPost, "/admin/purge?movie=603&movie=604", "s3cret", http.StatusBadRequest, apiBadRequest},
+		{"movie", http.MethodPost, "/admin/purge?movie=603", "s3cret", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := purgeRequest(app, tt.method, tt.target, tt.token)
+			if rec.Code != tt.status {
+				t.Fatalf("%s %s = %d, want %d", tt.method, tt.target, rec.Code, tt.status)
+			}
+			if rec.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", rec.Header().Get("Cache-Control"))
+			}
+			if tt.code != "" {
+				var body apiErrorBody
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error.Code != tt.code {
+					t.Errorf("error code %q (%v), want %q", body.Error.Code, err, tt.code)
+				}
+				if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+					t.Error("401 without a WWW-Authenticate challenge")
+				}
+				return
+			}
+			var body purgeResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(body.Keys, []string{"movie-603"}) || body.Purged {
+				t.Errorf("response = %+v, want movie-603 returned but not purged", body)
+			}
+		})
+	}
+}
+
+func TestPurgeHandlerDisabled(t *testing.T) {
+	app := newTestApp(t, newFakeTMDB(t, nil))
+	if rec := purgeRequest(app, http.MethodPost, "/admin/purge?movie=603", "anything"); rec.Code != http.StatusNotFound {
+		t.Errorf("without ADMIN_TOKEN: %d, want 404", rec.Code)
+	}
+}
+
+func TestPurgeWebhook(t *testing.T) {
+	tests := []struct {
+		name          string
+		webhookStatus int
+		status        int
+		purged        bool
+	}{
+		{"accepted", http.StatusOK, http.StatusOK, true},
+		{"failed", http.StatusInternalServerError, http.StatusBadGateway, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload purgeWebhookPayload
+			webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("webhook got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("webhook payload: %v", err)
+				}
+				w.WriteHeader(tt.webhookStatus)
+			}))
+			defer webhook.Close()
+			t.Setenv("ADMIN_TOKEN", "s3cret")
+			t.Setenv("CDN_PURGE_WEBHOOK_URL", webhook.URL)
+			app := newTestApp(t, newFakeTMDB(t, nil))
+
+			rec := purgeRequest(app, http.MethodPost, "/admin/purge?movie=603", "s3cret")
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if !slices.Equal(payload.SurrogateKeys, []string{"movie-603"}) {
+				t.Errorf("webhook got keys %q, want movie-603", payload.SurrogateKeys)
+			}
+			if tt.purged && !strings.Contains(rec.Body.String(), `"purged":true`) {
+				t.Errorf("response %s doesn't report the purge", rec.Body)
+			}
+			if !tt.purged && !strings.Contains(rec.Body.String(), apiPurgeFailed) {
+				t.Errorf("response %s doesn't report the failure", rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// requestQueue bounds how many requests are handled at once. Requests over
+// the limit wait in a queue of fixed depth; once that is full, or a request
+// has waited too long, it is turned away with a 503 instead of piling up.
+type requestQueue struct {
+	slots   chan struct{}
+	waiting chan struct{}
+	timeout time.Duration
+}
+
+func newRequestQueue(maxInFlight int, depth int, timeout time.Duration) *requestQueue {
+	return &requestQueue{
+		slots:   make(chan struct{}, maxInFlight),
+		waiting: make(chan struct{}, depth),
+		timeout: timeout,
+	}
+}
+
+// middleware applies the queue to every request.
+func (q *requestQueue) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !q.acquire(r) {
+			q.reject(w)
+			return
+		}
+		defer func() { <-q.slots }()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire takes a handler slot, waiting in the queue if necessary. It
+// returns false when the queue is full, the wait times out or the client
+// goes away.
+func (q *requestQueue) acquire(r *http.Request) bool {
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case q.waiting <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-q.waiting }()
+
+	timer := time.NewTimer(q.timeout)
+	defer timer.Stop()
+
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (q *requestQueue) reject(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(q.timeout.Seconds()))))
+	http.Error(w, "Server is busy, please try again shortly", http.StatusServiceUnavailable)
+}
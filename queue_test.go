@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// blockingQueue puts q in front of a handler that holds each request until
+// release is closed. The function it returns serves a request with ctx in
+// the background and delivers the response on the channel.
+func blockingQueue(q *requestQueue, release chan struct{}) func(context.Context) <-chan *httptest.ResponseRecorder {
+	handler := q.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	return func(ctx context.Context) <-chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+			done <- rec
+		}()
+		return done
+	}
+}
+
+// waitFor fails the test unless cond holds within a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRequestQueueFull(t *testing.T) {
+	q := newRequestQueue(1, 1, time.Minute)
+	release := make(chan struct{})
+	serve := blockingQueue(q, release)
+
+	running := serve(context.Background())
+	waitFor(t, "the first request to run", func() bool { return len(q.slots) == 1 })
+	queued := serve(context.Background())
+	waitFor(t, "the second request to queue", func() bool { return len(q.waiting) == 1 })
+
+	start := time.Now()
+	rejected := <-serve(context.Background())
+	if rejected.Code != http.StatusServiceUnavailable || rejected.Header().Get("Retry-After") != "60" {
+		t.Errorf("full queue: %d, Retry-After %q; want 503, 60", rejected.Code, rejected.Header().Get("Retry-After"))
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("full queue took %s to answer", elapsed)
+	}
+
+	close(release)
+	for name, done := range map[string]<-chan *httptest.ResponseRecorder{"running": running, "queued": queued} {
+		if rec := <-done; rec.Code != http.StatusOK {
+			t.Errorf("%s request = %d, want 200", name, rec.Code)
+		}
+	}
+}
+
+func TestRequestQueueTimeout(t *testing.T) {
+	q := newRequestQueue(1, 1, 50*time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	serve := blockingQueue(q, release)
+
+	serve(context.Background())
+	waitFor(t, "the first request to run", func() bool { return len(q.slots) == 1 })
+
+	start := time.Now()
+	rec := <-serve(context.Background())
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("timed out: %d, Retry-After %q; want 503, 1", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("gave up after %s, before the timeout", elapsed)
+	}
+	if len(q.waiting) != 0 {
+		t.Error("the timed out request kept its place in the queue")
+	}
+}
+
+func TestRequestQueueClientGoesAway(t *testing.T) {
+	q := newRequestQueue(1, 1, time.Minute)
+	release := make(chan struct{})
+	serve := blockingQueue(q, release)
+
+	running := serve(context.Background())
+	waitFor(t, "the first request to run", func() bool { return len(q.slots) == 1 })
+	ctx, cancel := context.WithCancel(context.Background())
+	gone := serve(ctx)
+	waitFor(t, "the second request to queue", func() bool { return len(q.waiting) == 1 })
+
+	cancel()
+	<-gone
+	if len(q.waiting) != 0 {
+		t.Fatal("the cancelled request kept its place in the queue")
+	}
+
+	// Its place is free for the next request, which gets the slot once the
+	// first is done.
+	next := serve(context.Background())
+	waitFor(t, "the next request to queue", func() bool { return len(q.waiting) == 1 })
+	close(release)
+	<-running
+	if rec := <-next; rec.Code != http.StatusOK {
+		t.Errorf("next request = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	topRatedEndpoint = "/movie/top_rated"
+
+	// quizLength is the number of questions in a quiz.
+	quizLength = 10
+	// quizOptions is the number of choices per question, the answer included.
+	quizOptions = 4
+	// quizPoolPages is how many pages of top-rated movies a quiz may draw
+	// from; one page, picked at random, is used per quiz.
+	quizPoolPages = 5
+
+	// quizCookie holds the encrypted quizState for the current browser session.
+	quizCookie = "quiz"
+)
+
+// Question is one multiple-choice quiz question.
+type Question struct {
+	MovieID int      `json:"movie_id"`
+	Prompt  string   `json:"prompt"`
+	Options []string `json:"options"`
+	Answer  string   `json:"answer"`
+}
+
+// quizState is a quiz in progress: its questions and the answers given so
+// far, keyed by question index.
+type quizState struct {
+	Questions []Question     `json:"questions"`
+	Answers   map[int]string `json:"answers"`
+}
+
+// QuizPage is the data rendered by the quiz template.
+type QuizPage struct {
+	Number   int // 1-based number of the question shown
+	Total    int
+	Question *Question // nil once every question is answered
+	Feedback string    // verdict on the previous answer
+	Score    int
+	Meta     Meta
+	Theme    string
+}
+
+var quizTmpl = pageTemplate("quiz", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>Movie Quiz</title>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1>Movie Quiz</h1>
+    {{with .Feedback}}<p>{{.}}</p>{{end}}
+    {{with .Question}}
+    <h2>Question {{$.Number}} of {{$.Total}}</h2>
+    <p dir="auto">{{.Prompt}}</p>
+    <form action="/quiz" method="POST">
+        {{range .Options}}<p><button type="submit" name="answer" value="{{.}}" dir="auto">{{.}}</button></p>
+        {{end}}
+    </form>
+    {{else}}
+    <p>You scored {{.Score}} out of {{.Total}}.</p>
+    <form action="/quiz" method="GET">
+        <input type="hidden" name="new" value="1">
+        <button type="submit">Play again</button>
+    </form>
+    {{end}}
+    {{template "footer"}}
+</body>
+</html>
+`, "")
+
+// quizHandler serves /quiz. GET shows the current question, starting a new
+// quiz when there is none (or ?new=1 is given); POST records the answer to
+// the current question and redirects back.
+func quizHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	if rejectDuplicateParams(w, r, "new") {
+		return
+	}
+	state := loadQuizState(r, config.CookieKey)
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		if state == nil || len(state.Answers) >= len(state.Questions) {
+			http.Redirect(w, r, "/quiz", http.StatusSeeOther)
+			return
+		}
+		current := state.Questions[len(state.Answers)]
+		answer := r.PostFormValue("answer")
+		if !slices.Contains(current.Options, answer) {
+			http.Error(w, "answer must be one of the options", http.StatusBadRequest)
+			return
+		}
+		state.Answers[len(state.Answers)] = answer
+		if err := saveQuizState(w, config.CookieKey, state); err != nil {
+			log.Printf("Error saving quiz: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/quiz", http.StatusSeeOther)
+		return
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if state == nil || r.URL.Query().Get("new") == "1" {
+		start := time.Now()
+		pool, err := fetchTopRated(r.Context(), rand.IntN(quizPoolPages)+1, config.TMDB)
+		RecordTiming(r.Context(), "tmdb_top_rated", start)
+		if err != nil {
+			log.Printf("Error fetching quiz movies: %v", err)
+			tmdbFailure(w, r, err, "Failed to start quiz")
+			return
+		}
+		state = newQuiz(pool.Results, movieGenres)
+		if len(state.Questions) == 0 {
+			log.Printf("Error starting quiz: no questions from %d movies", len(pool.Results))
+			http.Error(w, "Failed to start quiz", http.StatusInternalServerError)
+			return
+		}
+		if err := saveQuizState(w, config.CookieKey, state); err != nil {
+			log.Printf("Error saving quiz: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	answered := len(state.Answers)
+	page := QuizPage{Number: answered + 1, Total: len(state.Questions), Meta: pageMeta("/quiz"), Theme: theme(r)}
+	if answered < len(state.Questions) {
+		page.Question = &state.Questions[answered]
+	} else {
+		page.Score = ScoreQuiz(state.Answers, state.Questions)
+	}
+	if answered > 0 {
+		previous := state.Questions[answered-1]
+		if state.Answers[answered-1] == previous.Answer {
+			page.Feedback = "Correct!"
+		} else {
+			page.Feedback = fmt.Sprintf("Not quite, the answer was %s.", previous.Answer)
+		}
+	}
+
+	body, err := render(r, quizTmpl, page)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, searchResponse)
+	body.WriteTo(w)
+}
+
+// newQuiz asks up to quizLength questions, each about a different movie.
+func newQuiz(movies []Movie, genres map[int]string) *quizState {
+	state := &quizState{Answers: map[int]string{}}
+	remaining := slices.Clone(movies)
+	for len(state.Questions) < quizLength && len(remaining) > 0 {
+		question := GenerateQuestion(remaining, genres)
+		if question.Prompt == "" {
+			break
+		}
+		state.Questions = append(state.Questions, question)
+		remaining = slices.DeleteFunc(remaining, func(m Movie) bool { return m.ID == question.MovieID })
+	}
+	return state
+}
+
+// GenerateQuestion asks about the release year or the genre of a random
+// movie. The wrong options are the years or genres of other movies, topped
+// up with nearby years or other genres when those run short. It returns the
+// zero Question when no movie has a known year or genre.
+func GenerateQuestion(movies []Movie, genres map[int]string) Question {
+	const (
+		yearQuestion = iota
+		genreQuestion
+	)
+	kinds := []func(Movie) string{
+		yearQuestion:  func(m Movie) string { return m.ReleaseDate.Year() },
+		genreQuestion: func(m Movie) string { return movieGenre(m, genres) },
+	}
+	first := rand.IntN(len(kinds))
+	for i := range kinds {
+		kind := (first + i) % len(kinds)
+		attribute := kinds[kind]
+
+		var candidates []Movie
+		for _, movie := range movies {
+			if attribute(movie) != "" {
+				candidates = append(candidates, movie)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		subject := candidates[rand.IntN(len(candidates))]
+
+		question := Question{MovieID: subject.ID, Answer: attribute(subject)}
+		// Any genre of the subject would be a correct answer, so none of
+		// them may be offered as a wrong one.
+		exclude := map[string]bool{question.Answer: true}
+		if kind == yearQuestion {
+			question.Prompt = fmt.Sprintf("What year was %s released?", subject.Title)
+		} else {
+			question.Prompt = fmt.Sprintf("Which genre is %s?", subject.Title)
+			for _, id := range subject.GenreIDs {
+				exclude[genres[id]] = true
+			}
+		}
+
+		question.Options = []string{question.Answer}
+		add := func(option string) {
+			if option != "" && !exclude[option] && len(question.Options) < quizOptions {
+				exclude[option] = true
+				question.Options = append(question.Options, option)
+			}
+		}
+		for _, j := range rand.Perm(len(movies)) {
+			add(attribute(movies[j]))
+		}
+		if kind == yearQuestion {
+			year, _ := strconv.Atoi(question.Answer)
+			for offset := 1; len(question.Options) < quizOptions; offset++ {
+				add(strconv.Itoa(year + offset))
+				add(strconv.Itoa(year - offset))
+			}
+		} else {
+			ids := sortedGenreIDs(genres)
+			for _, j := range rand.Perm(len(ids)) {
+				add(genres[ids[j]])
+			}
+		}
+		rand.Shuffle(len(question.Options), func(a, b int) {
+			question.Options[a], question.Options[b] = question.Options[b], question.Options[a]
+		})
+		return question
+	}
+	return Question{}
+}
+
+// ScoreQuiz counts the correctly answered questions.
+func ScoreQuiz(answers map[int]string, questions []Question) int {
+	score := 0
+	for i, question := range questions {
+		if answer, ok := answers[i]; ok && answer == question.Answer {
+			score++
+		}
+	}
+	return score
+}
+
+// movieGenre returns the name of the movie's first known genre, or "".
+func movieGenre(movie Movie, genres map[int]string) string {
+	for _, id := range movie.GenreIDs {
+		if name, ok := genres[id]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// sortedGenreIDs returns the keys of genres in ascending order.
+func sortedGenreIDs(genres map[int]string) []int {
+	ids := make([]int, 0, len(genres))
+	for id := range genres {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// loadQuizState returns the quiz stored in the request's cookie, or nil when
+// there is none or it can't be read, e.g. after COOKIE_SECRET changed.
+func loadQuizState(r *http.Request, key []byte) *quizState {
+	cookie, err := r.Cookie(quizCookie)
+	if err != nil {
+		return nil
+	}
+	plaintext, err := openCookie(key, cookie.Value)
+	if err != nil {
+		return nil
+	}
+	var state quizState
+	if err := json.Unmarshal(plaintext, &state); err != nil || state.Answers == nil {
+		return nil
+	}
+	return &state
+}
+
+// saveQuizState stores the quiz in an encrypted session cookie.
+func saveQuizState(w http.ResponseWriter, key []byte, state *quizState) error {
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	value, err := sealCookie(key, plaintext)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     quizCookie,
+		Value:    value,
+		Path:     "/quiz",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func fetchTopRated(ctx context.Context, page int, tmdb *TMDB) (*SearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s&page=%d", tmdb.BaseURL, topRatedEndpoint, tmdb.APIKey, page)
+	var results SearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
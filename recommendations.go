@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// defaultRecommendationsCount is how many movies "You might also like"
+// shows unless RECOMMENDATIONS_COUNT says otherwise.
+const defaultRecommendationsCount = 6
+
+// recommendations returns up to count movies to suggest alongside movie:
+// TMDB's recommendations first, topped up with popular movies of the same
+// genre and decade when there are fewer than count. The top-up is best
+// effort; if it fails, the recommendations found so far are returned.
+func recommendations(ctx context.Context, movie *MovieDetail, count int, tmdb *TMDB) []Movie {
+	if count <= 0 {
+		return nil
+	}
+
+	seen := map[int]bool{movie.ID: true}
+	var picks []Movie
+	add := func(candidates []Movie) {
+		for _, candidate := range candidates {
+			if len(picks) == count {
+				return
+			}
+			if !seen[candidate.ID] {
+				seen[candidate.ID] = true
+				picks = append(picks, candidate)
+			}
+		}
+	}
+	if movie.Recommendations != nil {
+		add(movie.Recommendations.Results)
+	}
+	if len(picks) == count || len(movie.Genres) == 0 || !movie.ReleaseDate.Known() {
+		return picks
+	}
+
+	start := time.Now()
+	fallback, err := fetchGenreDecade(ctx, movie.Genres[0].ID, movie.ReleaseDate.year/10*10, tmdb)
+	RecordTiming(ctx, "tmdb_discover", start)
+	if err != nil {
+		log.Printf("Error fetching fallback recommendations for movie %d: %v", movie.ID, err)
+		return picks
+	}
+	add(fallback.Results)
+	return picks
+}
+
+// fetchGenreDecade returns the most popular movies of a genre released in
+// the ten years from decade.
+func fetchGenreDecade(ctx context.Context, genre int, decade int, tmdb *TMDB) (*SearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s&with_genres=%d&primary_release_date.gte=%d-01-01&primary_release_date.lte=%d-12-31&sort_by=popularity.desc",
+		tmdb.BaseURL, discoverEndpoint, tmdb.APIKey, genre, decade, decade+9)
+	var results SearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
+
+// Defaults for the "More like this length and vibe" shelf, unless
+// RUNTIME_SHELF_COUNT and RUNTIME_SHELF_BAND say otherwise.
+const (
+	defaultRuntimeShelfCount = 6
+	defaultRuntimeShelfBand  = 20 // minutes either side of the movie's runtime
+
+	// runtimeShelfTTL is how long a discover result for one genre and
+	// runtime band is reused. Popularity shifts slowly.
+	runtimeShelfTTL = 6 * time.Hour
+)
+
+// runtimeShelfCache holds discover results by genre and runtime band,
+// shared by every movie that falls in the same band.
+type runtimeShelfCache struct {
+	mu      sync.Mutex
+	results map[string]cachedMovies
+}
+
+type cachedMovies struct {
+	movies  []Movie
+	expires time.Time
+}
+
+// runtimeShelf returns up to count popular movies sharing movie's first
+// genre and running within band minutes of it, other than movie itself and
+// those in exclude. Movies without a runtime or genre get no shelf, and
+// neither does a failed discover call.
+func runtimeShelf(ctx context.Context, movie *MovieDetail, band, count int, exclude []Movie, tmdb *TMDB) []Movie {
+	if count <= 0 || movie.Runtime == 0 || len(movie.Genres) == 0 {
+		return nil
+	}
+	genre, from, to := movie.Genres[0].ID, max(movie.Runtime-band, 1), movie.Runtime+band
+
+	key := fmt.Sprintf("%d/%d-%d", genre, from, to)
+	tmdb.runtimeShelf.mu.Lock()
+	cached, ok := tmdb.runtimeShelf.results[key]
+	tmdb.runtimeShelf.mu.Unlock()
+	if !ok || !time.Now().Before(cached.expires) {
+		start := time.Now()
+		results, err := fetchGenreRuntime(ctx, genre, from, to, tmdb)
+		RecordTiming(ctx, "tmdb_discover_runtime", start)
+		if err != nil {
+			log.Printf("Error fetching runtime shelf for movie %d: %v", movie.ID, err)
+			if !inMaintenance(err) || !ok {
+				return nil
+			}
+		} else {
+			cached = cachedMovies{movies: results.Results, expires: time.Now().Add(runtimeShelfTTL)}
+			tmdb.runtimeShelf.mu.Lock()
+			if tmdb.runtimeShelf.results == nil {
+				tmdb.runtimeShelf.results = map[string]cachedMovies{}
+			}
+			tmdb.runtimeShelf.results[key] = cached
+			tmdb.runtimeShelf.mu.Unlock()
+		}
+	}
+
+	seen := map[int]bool{movie.ID: true}
+	for _, m := range exclude {
+		seen[m.ID] = true
+	}
+	var picks []Movie
+	for _, candidate := range cached.movies {
+		if len(picks) == count {
+			break
+		}
+		if !seen[candidate.ID] {
+			seen[candidate.ID] = true
+			picks = append(picks, candidate)
+		}
+	}
+	return picks
+}
+
+// fetchGenreRuntime returns the most popular movies of a genre running
+// between from and to minutes.
+func fetchGenreRuntime(ctx context.Context, genre int, from, to int, tmdb *TMDB) (*SearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s?api_key=%s&with_genres=%d&with_runtime.gte=%d&with_runtime.lte=%d&sort_by=popularity.desc",
+		tmdb.BaseURL, discoverEndpoint, tmdb.APIKey, genre, from, to)
+	var results SearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
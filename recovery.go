@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoveryMiddleware turns a panicking handler into a 500 response instead
+// of the aborted connection net/http leaves behind. The panic value and the
+// stack trace are logged at Error level. When logger has Debug enabled
+// (LOG_LEVEL=debug), the response also carries the panic message, but never
+// the stack trace.
+func RecoveryMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				// ErrAbortHandler is how handlers abort a response on
+				// purpose; net/http handles it without logging.
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				logger.LogAttrs(r.Context(), slog.LevelError, "panic",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", requestIDFromContext(r.Context())),
+					slog.String("panic", fmt.Sprint(p)),
+					slog.String("stack", string(debug.Stack())),
+				)
+				message := "Internal Server Error"
+				if logger.Enabled(r.Context(), slog.LevelDebug) {
+					message = fmt.Sprintf("%s: panic: %v", message, p)
+				}
+				http.Error(w, message, http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
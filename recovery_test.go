@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	for _, level := range []slog.Level{slog.LevelInfo, slog.LevelDebug} {
+		t.Run(level.String(), func(t *testing.T) {
+			var logged bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: level}))
+			handler := RequestIDMiddleware(RecoveryMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/movie/the-matrix-603", nil)
+			req.Header.Set("X-Request-ID", "req-123")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			wantBody := "Internal Server Error\n"
+			if level == slog.LevelDebug {
+				wantBody = "Internal Server Error: panic: boom\n"
+			}
+			if rec.Code != http.StatusInternalServerError || rec.Body.String() != wantBody {
+				t.Errorf("%d %q, want 500 %q", rec.Code, rec.Body, wantBody)
+			}
+			for _, want := range []string{"level=ERROR", "msg=panic", "path=/movie/the-matrix-603", "request_id=req-123", "panic=boom", "stack=", "recovery_test.go"} {
+				if !strings.Contains(logged.String(), want) {
+					t.Errorf("log %q is missing %q", logged.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddlewareAbortHandler(t *testing.T) {
+	logger, logged := newCountingLogger()
+	handler := RecoveryMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	rec := httptest.NewRecorder()
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("panicked with %v, want http.ErrAbortHandler", p)
+		}
+		if rec.Code == http.StatusInternalServerError || rec.Body.Len() != 0 {
+			t.Errorf("aborted response was answered with %d %q", rec.Code, rec.Body)
+		}
+		if n := logged.count(slog.LevelError); n != 0 {
+			t.Errorf("logged %d errors for an aborted response", n)
+		}
+	}()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+}
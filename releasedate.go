@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// ReleaseDate is a TMDB release date such as "2021-10-22". TMDB sends an
+// empty string for undated titles and now and then a bare year or some other
+// malformed value; none of these are decoding errors. A bare year keeps the
+// year, anything unparseable is treated as unknown.
+type ReleaseDate struct {
+	date time.Time // zero unless the full date is known
+	year int       // 0 when unknown
+}
+
+// releaseDateLayout is the format TMDB uses for release_date.
+const releaseDateLayout = "2006-01-02"
+
+// UnmarshalJSON accepts "YYYY-MM-DD", "YYYY", "", null and anything else
+// without failing the surrounding decode.
+func (d *ReleaseDate) UnmarshalJSON(data []byte) error {
+	*d = ReleaseDate{}
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil // null or a non-string value: unknown
+	}
+	*d = parseReleaseDate(raw)
+	return nil
+}
+
+// MarshalJSON writes the date back in TMDB's format, or "" when unknown.
+func (d ReleaseDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// parseReleaseDate parses a full date or a bare year, returning the zero
+// ReleaseDate for anything else.
+func parseReleaseDate(raw string) ReleaseDate {
+	if t, err := time.Parse(releaseDateLayout, raw); err == nil {
+		return ReleaseDate{date: t, year: t.Year()}
+	}
+	if len(raw) == 4 {
+		if year, err := strconv.Atoi(raw); err == nil && year > 0 {
+			return ReleaseDate{year: year}
+		}
+	}
+	return ReleaseDate{}
+}
+
+// Time returns the full release date, or the zero time when only the year
+// or nothing is known.
+func (d ReleaseDate) Time() time.Time {
+	return d.date
+}
+
+// Known reports whether at least the year is known.
+func (d ReleaseDate) Known() bool {
+	return d.year != 0
+}
+
+// Year returns the release year, or "" when it is unknown.
+func (d ReleaseDate) Year() string {
+	if !d.Known() {
+		return ""
+	}
+	return strconv.Itoa(d.year)
+}
+
+// DisplayYear is the year templates show next to a title: the release year,
+// or "TBA" for undated titles, so they never get empty parentheses.
+func (d ReleaseDate) DisplayYear() string {
+	if !d.Known() {
+		return "TBA"
+	}
+	return d.Year()
+}
+
+// String returns the ISO date, just the year when only that is known, or ""
+// when nothing is. The result sorts chronologically as a string.
+func (d ReleaseDate) String() string {
+	switch {
+	case !d.date.IsZero():
+		return d.date.Format(releaseDateLayout)
+	case d.year != 0:
+		return strconv.Itoa(d.year)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseReleaseDate(t *testing.T) {
+	tests := []struct {
+		raw    string
+		known  bool
+		year   string
+		string string
+	}{
+		{"2021-10-22", true, "2021", "2021-10-22"},
+		{"1999-01-01", true, "1999", "1999-01-01"},
+		{"2021", true, "2021", "2021"},
+		{"0000", false, "", ""},
+		{"", false, "", ""},
+		{"2021-13-45", false, "", ""},
+		{"2021-02-30", false, "", ""},
+		{"21-10-22", false, "", ""},
+		{"2021-10", false, "", ""},
+		{"20211", false, "", ""},
+		{"-202", false, "", ""},
+		{"TBA", false, "", ""},
+		{" 2021", false, "", ""},
+	}
+	for _, tt := range tests {
+		d := parseReleaseDate(tt.raw)
+		if d.Known() != tt.known || d.Year() != tt.year || d.String() != tt.string {
+			t.Errorf("parseReleaseDate(%q) = known %v, year %q, string %q; want %v, %q, %q",
+				tt.raw, d.Known(), d.Year(), d.String(), tt.known, tt.year, tt.string)
+		}
+	}
+}
+
+func TestReleaseDateJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want string // String() after decoding, and the re-encoded value
+	}{
+		{`"2021-10-22"`, "2021-10-22"},
+		{`"2021"`, "2021"},
+		{`""`, ""},
+		{`null`, ""},
+		{`2021`, ""},
+		{`"not a date"`, ""},
+	}
+	for _, tt := range tests {
+		var movie Movie
+		if err := json.Unmarshal([]byte(`{"id":1,"title":"X","release_date":`+tt.json+`}`), &movie); err != nil {
+			t.Errorf("release_date %s failed the decode: %v", tt.json, err)
+			continue
+		}
+		if movie.ID != 1 || movie.ReleaseDate.String() != tt.want {
+			t.Errorf("release_date %s decoded to %q, want %q", tt.json, movie.ReleaseDate.String(), tt.want)
+		}
+		encoded, err := json.Marshal(movie.ReleaseDate)
+		if want, _ := json.Marshal(tt.want); err != nil || string(encoded) != string(want) {
+			t.Errorf("release_date %s encoded to %s, want %s", tt.json, encoded, want)
+		}
+	}
+}
+
+func TestUndatedMovieInResults(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{"/search/movie": `{"page":1,"total_pages":1,"total_results":3,"results":[
+		{"id":603,"title":"The Matrix","release_date":"1999-03-30","popularity":80},
+		{"id":1,"title":"The Matrix Untitled","release_date":"","popularity":50},
+		{"id":604,"title":"The Matrix Reloaded","release_date":"2003-05-15","popularity":40}
+	]}`})
+	app := newTestApp(t, fake)
+
+	var api struct {
+		Results []struct {
+			ID          int    `json:"id"`
+			ReleaseDate string `json:"release_date"`
+			Year        string `json:"year"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(get(app, "/api/search?query=matrix").Body.Bytes(), &api); err != nil {
+		t.Fatal(err)
+	}
+	var apiOrder []int
+	for _, movie := range api.Results {
+		apiOrder = append(apiOrder, movie.ID)
+		if movie.ID == 1 && (movie.ReleaseDate != "" || movie.Year != "") {
+			t.Errorf("API gives the undated movie release_date %q, year %q, want both empty", movie.ReleaseDate, movie.Year)
+		}
+	}
+
+	for _, view := range []string{"standard", "text"} {
+		body := get(app, "/?keyword=matrix&view="+view).Body.String()
+		if !strings.Contains(body, "Untitled</span> (TBA)") && !strings.Contains(body, "Untitled</a> (TBA)") {
+			t.Errorf("%s view does not show TBA for the undated movie", view)
+		}
+		if strings.Contains(body, "()") {
+			t.Errorf("%s view shows empty parentheses", view)
+		}
+		if htmlOrder := movieLinkOrder(body, apiOrder); !slices.Equal(htmlOrder, apiOrder) {
+			t.Errorf("%s view lists %v, API %v", view, htmlOrder, apiOrder)
+		}
+	}
+
+	// Sorting by year puts undated titles after dated ones, newest first.
+	body := get(app, "/?keyword=matrix&sort=year").Body.String()
+	if got := movieLinkOrder(body, apiOrder); !slices.Equal(got, []int{604, 603, 1}) {
+		t.Errorf("sorted by year: %v, want [604 603 1]", got)
+	}
+}
+
+// movieLinkOrder returns ids in the order their detail links first appear
+// in body.
+func movieLinkOrder(body string, ids []int) []int {
+	order := slices.Clone(ids)
+	slices.SortFunc(order, func(a, b int) int {
+		return strings.Index(body, fmt.Sprintf(`-%d"`, a)) - strings.Index(body, fmt.Sprintf(`-%d"`, b))
+	})
+	return order
+}
@@ -0,0 +1,63 @@
+package main
+
+import "strings"
+
+// Robots meta tag values.
+const (
+	robotsIndex         = "index,follow"
+	robotsNoindex       = "noindex,nofollow"
+	robotsNoindexFollow = "noindex,follow"
+)
+
+// searchResultsRoute is the route of the home page showing the results for
+// a keyword, which unlike the bare home page isn't indexed.
+const searchResultsRoute = "/?keyword"
+
+// robotPolicies maps routes, as registered in newHandler, to their robots
+// policy. Patterns ending in a slash cover every path below them. Routes
+// missing here are not indexed, so a new route has to opt in to indexing
+// explicitly.
+var robotPolicies = map[string]string{
+	"/":                 robotsIndex,
+	"/movie/":           robotsIndex,
+	"/person/":          robotsIndex,
+	"/company/":         robotsIndex,
+	"/collection/":      robotsIndex,
+	"/collections":      robotsIndex,
+	"/best/":            robotsIndex,
+	"/cinema":           robotsNoindexFollow, // changes daily
+	searchResultsRoute:  robotsNoindexFollow,
+	"/people":           robotsNoindexFollow, // search results too
+	"/tonight":          robotsNoindex,
+	"/planner/generate": robotsNoindex,
+	"/quiz":             robotsNoindex,
+	"/settings":         robotsNoindex,
+}
+
+// Meta is the per-page data rendered into the <head> of every page.
+type Meta struct {
+	Robots string // content of <meta name="robots">
+}
+
+// pageMeta returns the Meta of a page served by route.
+func pageMeta(route string) Meta {
+	return Meta{Robots: RobotPolicy(route)}
+}
+
+// RobotPolicy returns the robots meta tag content for route: the policy of
+// the exact pattern in robotPolicies, or else of the longest pattern ending
+// in a slash that route falls under, or else noindex,nofollow. "/" only
+// matches the home page itself.
+func RobotPolicy(route string) string {
+	if policy, ok := robotPolicies[route]; ok {
+		return policy
+	}
+	policy, longest := robotsNoindex, 0
+	for pattern, p := range robotPolicies {
+		if len(pattern) > 1 && strings.HasSuffix(pattern, "/") &&
+			strings.HasPrefix(route, pattern) && len(pattern) > longest {
+			policy, longest = p, len(pattern)
+		}
+	}
+	return policy
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CrewRole groups the crew jobs TMDB credits under one browsable role. TMDB's
+// job strings vary from credit to credit ("Director of Photography",
+// "Cinematography", ...), so each role lists every spelling it covers.
+type CrewRole struct {
+	Slug  string // path segment in /person/{id}/role/{slug}
+	Title string
+	Jobs  []string
+}
+
+// crewRoles are the roles a person's movies can be browsed by, in the order
+// the person page lists them.
+var crewRoles = []CrewRole{
+	{"director", "Director", []string{"Director", "Co-Director"}},
+	{"writer", "Writer", []string{"Writer", "Screenplay", "Story", "Author", "Novel", "Original Story", "Characters", "Co-Writer"}},
+	{"producer", "Producer", []string{"Producer", "Executive Producer", "Co-Producer", "Associate Producer"}},
+	{"composer", "Composer", []string{"Original Music Composer", "Music", "Composer", "Music Score"}},
+	{"director-of-photography", "Director of Photography", []string{"Director of Photography", "Cinematography", "Cinematographer"}},
+	{"editor", "Editor", []string{"Editor", "Film Editor", "Editing"}},
+}
+
+// crewRoleByJob maps a normalized job string to the slug of its role.
+var crewRoleByJob = func() map[string]string {
+	byJob := map[string]string{}
+	for _, role := range crewRoles {
+		for _, job := range role.Jobs {
+			byJob[normalizeJob(job)] = role.Slug
+		}
+	}
+	return byJob
+}()
+
+// normalizeJob folds the case and spacing differences between TMDB job
+// strings.
+func normalizeJob(job string) string {
+	return strings.ToLower(strings.Join(strings.Fields(job), " "))
+}
+
+// roleForJob returns the slug of the role job belongs to, or "" when it
+// isn't one of crewRoles.
+func roleForJob(job string) string {
+	return crewRoleByJob[normalizeJob(job)]
+}
+
+// RoleCount is one of a person's crew roles with the number of movies they
+// held it on.
+type RoleCount struct {
+	CrewRole
+	Count int
+}
+
+// personRoles lists the crew roles a person held on movies, in crewRoles
+// order. A movie counts once per role, however many of the role's jobs the
+// person had on it.
+func personRoles(credits *CombinedCredits) []RoleCount {
+	movies := map[string]map[int]bool{}
+	for _, c := range credits.Crew {
+		slug := roleForJob(c.Job)
+		if c.MediaType != "movie" || slug == "" {
+			continue
+		}
+		if movies[slug] == nil {
+			movies[slug] = map[int]bool{}
+		}
+		movies[slug][c.ID] = true
+	}
+	var roles []RoleCount
+	for _, role := range crewRoles {
+		if n := len(movies[role.Slug]); n > 0 {
+			roles = append(roles, RoleCount{CrewRole: role, Count: n})
+		}
+	}
+	return roles
+}
+
+// roleFilmography returns the movies on which the person held the role
+// slug, newest first, each with the jobs as TMDB credits them.
+func roleFilmography(credits *CombinedCredits, slug string) []FilmographyEntry {
+	var entries []FilmographyEntry
+	index := map[int]int{}
+	for _, c := range credits.Crew {
+		if c.MediaType != "movie" || roleForJob(c.Job) != slug {
+			continue
+		}
+		i, ok := index[c.ID]
+		if !ok {
+			i = len(entries)
+			index[c.ID] = i
+			entries = append(entries, FilmographyEntry{Credit: c})
+		}
+		entries[i].Roles = append(entries[i].Roles, c.Job)
+	}
+	sortFilmography(entries, "date")
+	return entries
+}
+
+// PersonRolePage is the data rendered by the person role template. Role is
+// nil when the person never held the requested role.
+type PersonRolePage struct {
+	Person *Person
+	Role   *CrewRole
+	Slug   string
+	Movies []FilmographyEntry
+	Roles  []RoleCount
+	Meta   Meta
+	Theme  string
+}
+
+var personRoleTmpl = pageTemplate("person_role", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>{{.Person.Name}}{{with .Role}} &ndash; {{.Title}}{{end}}</title>
+</head>
+<body>
+    {{template "header" ""}}
+    {{with .Role}}
+    <h1><a href="/person/{{$.Person.ID}}" dir="auto">{{$.Person.Name}}</a> &ndash; {{.Title}}</h1>
+    <ol>
+        {{range $.Movies}}<li><a href="{{.Link}}" dir="auto">{{.DisplayTitle}}</a>{{with .Year}} ({{.}}){{end}} &ndash; {{join .Roles ", "}}</li>
+        {{end}}
+    </ol>
+    {{else}}
+    <h1><a href="/person/{{.Person.ID}}" dir="auto">{{.Person.Name}}</a> has no movie credits as &ldquo;{{.Slug}}&rdquo;</h1>
+    {{end}}
+    {{with .Roles}}
+    <p>Crew roles: {{range $i, $role := .}}{{if $i}} &middot; {{end}}<a href="/person/{{$.Person.ID}}/role/{{$role.Slug}}">{{$role.Title}}</a> ({{$role.Count}}){{end}}</p>
+    {{end}}
+    {{template "footer"}}
+</body>
+</html>
+`, "")
+
+// personRoleHandler serves /person/{id}/role/{slug}: the movies on which the
+// person held one crew role. A role they never held, or one that isn't in
+// crewRoles, is a 404 listing the roles they did hold.
+func personRoleHandler(w http.ResponseWriter, r *http.Request, config Config, personID, slug string) {
+	start := time.Now()
+	person, err := fetchPerson(r.Context(), personID, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_person", start)
+	if err != nil {
+		log.Printf("Error fetching person: %v", err)
+		tmdbFailure(w, r, err, "Failed to fetch person")
+		return
+	}
+	if person.ID == 0 {
+		http.Error(w, "Person not found", http.StatusNotFound)
+		return
+	}
+
+	start = time.Now()
+	credits, err := fetchCombinedCredits(r.Context(), personID, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_credits", start)
+	if err != nil {
+		log.Printf("Error fetching combined credits: %v", err)
+		tmdbFailure(w, r, err, "Failed to fetch credits")
+		return
+	}
+
+	page := PersonRolePage{
+		Person: person,
+		Slug:   slug,
+		Movies: roleFilmography(credits, slug),
+		Roles:  personRoles(credits),
+		Meta:   pageMeta("/person/"),
+		Theme:  theme(r),
+	}
+	status := http.StatusOK
+	if len(page.Movies) > 0 {
+		for i := range crewRoles {
+			if crewRoles[i].Slug == slug {
+				page.Role = &crewRoles[i]
+			}
+		}
+	} else {
+		status = http.StatusNotFound
+		page.Meta = pageMeta("")
+	}
+
+	body, err := render(r, personRoleTmpl, page)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	if status == http.StatusOK {
+		setCacheControl(w, config, detailResponse)
+	}
+	w.WriteHeader(status)
+	body.WriteTo(w)
+}
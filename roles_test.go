@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// nolanCreditsJSON is a combined_credits response with the mess TMDB really
+// returns: several jobs on one movie, odd spacing and case, TV credits and
+// jobs no role covers.
+const nolanCreditsJSON = `{"cast":[
+	{"id":11660,"media_type":"movie","title":"Following","release_date":"1998-09-12","character":"Burglar's Assistant"}
+],"crew":[
+	{"id":27205,"media_type":"movie","title":"Inception","release_date":"2010-07-15","job":"Director"},
+	{"id":27205,"media_type":"movie","title":"Inception","release_date":"2010-07-15","job":"Screenplay"},
+	{"id":27205,"media_type":"movie","title":"Inception","release_date":"2010-07-15","job":"Producer"},
+	{"id":155,"media_type":"movie","title":"The Dark Knight","release_date":"2008-07-16","job":"Director"},
+	{"id":155,"media_type":"movie","title":"The Dark Knight","release_date":"2008-07-16","job":"Screenplay"},
+	{"id":155,"media_type":"movie","title":"The Dark Knight","release_date":"2008-07-16","job":"Story"},
+	{"id":11660,"media_type":"movie","title":"Following","release_date":"1998-09-12","job":"director"},
+	{"id":11660,"media_type":"movie","title":"Following","release_date":"1998-09-12","job":"Director  of Photography"},
+	{"id":11660,"media_type":"movie","title":"Following","release_date":"1998-09-12","job":" Editor "},
+	{"id":49026,"media_type":"movie","title":"The Dark Knight Rises","release_date":"2012-07-17","job":"Executive Producer"},
+	{"id":1402,"media_type":"tv","name":"Westworld","first_air_date":"2016-10-02","job":"Executive Producer"},
+	{"id":27205,"media_type":"movie","title":"Inception","release_date":"2010-07-15","job":"Thanks"}
+]}`
+
+func TestRoleForJob(t *testing.T) {
+	tests := []struct {
+		job  string
+		want string
+	}{
+		{"Director", "director"},
+		{"Co-Director", "director"},
+		{"DIRECTOR", "director"},
+		{"Screenplay", "writer"},
+		{"Novel", "writer"},
+		{"Original Story", "writer"},
+		{"Executive Producer", "producer"},
+		{"Original Music Composer", "composer"},
+		{"Music", "composer"},
+		{"Director of Photography", "director-of-photography"},
+		{"Cinematography", "director-of-photography"},
+		{"  director\tof   photography ", "director-of-photography"},
+		{"Film Editor", "editor"},
+		{"Assistant Director", ""},
+		{"Stunt Coordinator", ""},
+		{"Directed", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := roleForJob(tt.job); got != tt.want {
+			t.Errorf("roleForJob(%q) = %q, want %q", tt.job, got, tt.want)
+		}
+	}
+}
+
+func TestCrewRolesDontOverlap(t *testing.T) {
+	seen := map[string]string{}
+	for _, role := range crewRoles {
+		for _, job := range role.Jobs {
+			key := normalizeJob(job)
+			if other, ok := seen[key]; ok {
+				t.Errorf("job %q is in both %s and %s", job, other, role.Slug)
+			}
+			seen[key] = role.Slug
+		}
+	}
+}
+
+func TestPersonRoles(t *testing.T) {
+	var credits CombinedCredits
+	if err := json.Unmarshal([]byte(nolanCreditsJSON), &credits); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, role := range personRoles(&credits) {
+		got = append(got, role.Slug+":"+strconv.Itoa(role.Count))
+	}
+	// Writer counts The Dark Knight once for its two writing jobs; the TV
+	// show and "Thanks" count for nothing.
+	want := []string{"director:3", "writer:2", "producer:2", "director-of-photography:1", "editor:1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("personRoles = %q, want %q", got, want)
+	}
+
+	if roles := personRoles(&CombinedCredits{}); roles != nil {
+		t.Errorf("no credits gave roles %v", roles)
+	}
+}
+
+func TestRoleFilmography(t *testing.T) {
+	var credits CombinedCredits
+	if err := json.Unmarshal([]byte(nolanCreditsJSON), &credits); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		slug string
+		want []string // title: jobs, newest first
+	}{
+		{"director", []string{"Inception: Director", "The Dark Knight: Director", "Following: director"}},
+		{"writer", []string{"Inception: Screenplay", "The Dark Knight: Screenplay, Story"}},
+		{"producer", []string{"The Dark Knight Rises: Executive Producer", "Inception: Producer"}},
+		{"composer", nil},
+		{"gaffer", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, entry := range roleFilmography(&credits, tt.slug) {
+			got = append(got, entry.Title+": "+strings.Join(entry.Roles, ", "))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("roleFilmography(%q) = %q, want %q", tt.slug, got, tt.want)
+		}
+	}
+}
+
+func TestPersonRolePage(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/person/525":                  `{"id":525,"name":"Christopher Nolan"}`,
+		"/person/525/combined_credits": nolanCreditsJSON,
+	})
+	app := newTestApp(t, fake)
+
+	writer := get(app, "/person/525/role/writer")
+	if writer.Code != http.StatusOK {
+		t.Fatalf("writer: status %d, want 200", writer.Code)
+	}
+	body := writer.Body.String()
+	if !strings.Contains(body, "Screenplay, Story") || strings.Contains(body, "Following") {
+		t.Errorf("writer page lists the wrong movies:\n%s", body)
+	}
+
+	for _, slug := range []string{"composer", "gaffer"} {
+		rec := get(app, "/person/525/role/"+slug)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", slug, rec.Code)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, `<a href="/person/525/role/director">Director</a> (3)`) {
+			t.Errorf("%s: 404 page doesn't list the roles that exist:\n%s", slug, body)
+		}
+	}
+
+	person := get(app, "/person/525").Body.String()
+	if !strings.Contains(person, `<a href="/person/525/role/editor">Editor</a> (1)`) {
+		t.Error("person page doesn't link to the roles")
+	}
+}
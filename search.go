@@ -0,0 +1,101 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// strongMatchPopularityRatio is how many times more popular an exact title
+// match must be than the next best result to count as a strong match.
+const strongMatchPopularityRatio = 3.0
+
+// trailingYearPattern splits a query such as "dune 2021" into title and year.
+var trailingYearPattern = regexp.MustCompile(`^(.*\S)\s+\(?((?:18|19|20)\d\d)\)?$`)
+
+// strongMatch picks the single result a search obviously refers to, if any.
+// A result is a strong match when its normalized title equals the normalized
+// query, it is the only such result (after applying a trailing year in the
+// query, if there is one), and it is at least strongMatchPopularityRatio
+// times as popular as the most popular other result.
+func strongMatch(query string, movies []Movie) (Movie, bool) {
+	title, year := splitQueryYear(query)
+	if match, ok := strongMatchFor(normalizeTitle(title), year, movies); ok || year == "" {
+		return match, ok
+	}
+	// The "year" may be part of the title, as in "Blade Runner 2049".
+	return strongMatchFor(normalizeTitle(query), "", movies)
+}
+
+func strongMatchFor(title string, year string, movies []Movie) (Movie, bool) {
+	if title == "" {
+		return Movie{}, false
+	}
+
+	var match Movie
+	matches := 0
+	for _, m := range movies {
+		if normalizeTitle(m.Title) != title {
+			continue
+		}
+		if year != "" && m.ReleaseDate.Year() != year {
+			continue
+		}
+		match = m
+		matches++
+	}
+	if matches != 1 {
+		return Movie{}, false
+	}
+
+	for _, m := range movies {
+		if m.ID != match.ID && match.Popularity < m.Popularity*strongMatchPopularityRatio {
+			return Movie{}, false
+		}
+	}
+	return match, true
+}
+
+// splitQueryYear separates a trailing release year from a search query.
+func splitQueryYear(query string) (title string, year string) {
+	query = strings.TrimSpace(query)
+	if m := trailingYearPattern.FindStringSubmatch(query); m != nil {
+		return m[1], m[2]
+	}
+	return query, ""
+}
+
+// normalizeTitle lowercases a title and reduces punctuation and runs of
+// whitespace to single spaces, so "Spider-Man: No Way Home" and
+// "spider man no way home" compare equal.
+func normalizeTitle(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// relatedSearches suggests follow-up queries from the titles in a result
+// list, most popular first, leaving out the query itself and duplicate
+// titles. It only looks at results already fetched. limit caps the number
+// of suggestions; zero or less disables them.
+func relatedSearches(query string, movies []Movie, limit int) []string {
+	if limit <= 0 || len(movies) < 2 {
+		return nil
+	}
+
+	seen := map[string]bool{normalizeTitle(query): true}
+	var related []string
+	for _, m := range sortMovies(movies, "popularity") {
+		key := normalizeTitle(m.Title)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		related = append(related, m.Title)
+		if len(related) == limit {
+			break
+		}
+	}
+	return related
+}
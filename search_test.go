@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStrongMatch(t *testing.T) {
+	dune1984 := Movie{ID: 841, Title: "Dune", ReleaseDate: parseReleaseDate("1984-12-14"), Popularity: 30}
+	dune2021 := Movie{ID: 438631, Title: "Dune", ReleaseDate: parseReleaseDate("2021-09-15"), Popularity: 200}
+	duneTwo := Movie{ID: 693134, Title: "Dune: Part Two", ReleaseDate: parseReleaseDate("2024-02-27"), Popularity: 150}
+	amelie := Movie{ID: 194, Title: "Amélie", ReleaseDate: parseReleaseDate("2001-04-25"), Popularity: 40}
+	blade2049 := Movie{ID: 335984, Title: "Blade Runner 2049", ReleaseDate: parseReleaseDate("2017-10-04"), Popularity: 80}
+	matrix := Movie{ID: 603, Title: "The Matrix", ReleaseDate: parseReleaseDate("1999-03-30"), Popularity: 90}
+	reloaded := Movie{ID: 604, Title: "The Matrix Reloaded", ReleaseDate: parseReleaseDate("2003-05-15"), Popularity: 10}
+
+	tests := []struct {
+		name   string
+		query  string
+		movies []Movie
+		want   int // the matched ID, 0 for no match
+	}{
+		{"exact title, dominant", "The Matrix", []Movie{matrix, reloaded}, 603},
+		{"only result", "The Matrix", []Movie{matrix}, 603},
+		{"case and punctuation", "the matrix!", []Movie{matrix, reloaded}, 603},
+		{"case of non-ASCII letters", "AMÉLIE", []Movie{amelie}, 194},
+		{"diacritics are not dropped", "Amelie", []Movie{amelie}, 0},
+		{"no exact title", "Matrix", []Movie{matrix, reloaded}, 0},
+		{"two exact titles", "Dune", []Movie{dune2021, dune1984}, 0},
+		{"year picks one, runner-up too close", "Dune 2021", []Movie{dune2021, dune1984, duneTwo}, 0},
+		{"year picks one, dominant", "Dune 1984", []Movie{dune1984, reloaded}, 841},
+		{"year in parentheses", "Dune (2021)", []Movie{dune2021, dune1984}, 438631},
+		{"year matches nothing", "Dune 1999", []Movie{dune2021, dune1984}, 0},
+		{"year is part of the title", "Blade Runner 2049", []Movie{blade2049}, 335984},
+		{"near-equal runner-up", "Dune", []Movie{dune2021, duneTwo}, 0},
+		{"runner-up at exactly a third", "The Matrix", []Movie{matrix, {ID: 1, Title: "Other", Popularity: 30}}, 603},
+		{"runner-up just over a third", "The Matrix", []Movie{matrix, {ID: 1, Title: "Other", Popularity: 30.1}}, 0},
+		{"empty query", "", []Movie{matrix}, 0},
+		{"punctuation only", "!!", []Movie{{ID: 1, Title: "!!"}}, 0},
+		{"no results", "The Matrix", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, ok := strongMatch(tt.query, tt.movies)
+			if ok != (tt.want != 0) || match.ID != tt.want {
+				t.Errorf("strongMatch(%q) = %d, %v, want %d", tt.query, match.ID, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRedirect(t *testing.T) {
+	for _, byDefault := range []bool{true, false} {
+		t.Run(fmt.Sprintf("SEARCH_AUTO_REDIRECT=%t", byDefault), func(t *testing.T) {
+			t.Setenv("SEARCH_AUTO_REDIRECT", strconv.FormatBool(byDefault))
+			app := newTestApp(t, newFakeTMDB(t, map[string]string{"/search/movie": `{"page":1,"total_pages":1,"total_results":2,"results":[
+				{"id":603,"title":"The Matrix","release_date":"1999-03-30","popularity":90},
+				{"id":604,"title":"The Matrix Reloaded","release_date":"2003-05-15","popularity":10}
+			]}`}))
+
+			redirect := "/movie/the-matrix-603?from_search=The+Matrix"
+			byDefaultStatus, byDefaultLocation := http.StatusOK, ""
+			if byDefault {
+				byDefaultStatus, byDefaultLocation = http.StatusFound, redirect
+			}
+			tests := []struct {
+				target   string
+				cookie   string // search_redirect, "" for none
+				status   int
+				location string
+			}{
+				{"/?keyword=The+Matrix", "", byDefaultStatus, byDefaultLocation},
+				{"/?keyword=The+Matrix", "on", http.StatusFound, redirect},
+				{"/?keyword=The+Matrix", "off", http.StatusOK, ""},
+				{"/?keyword=The+Matrix", "bogus", byDefaultStatus, byDefaultLocation},
+				{"/?keyword=The+Matrix&no_redirect=1", "on", http.StatusOK, ""},
+				{"/?keyword=Matrix", "on", http.StatusOK, ""},
+			}
+			for _, tt := range tests {
+				req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+				if tt.cookie != "" {
+					req.AddCookie(&http.Cookie{Name: searchRedirectCookie, Value: tt.cookie})
+				}
+				rec := httptest.NewRecorder()
+				app.ServeHTTP(rec, req)
+				if rec.Code != tt.status || rec.Header().Get("Location") != tt.location {
+					t.Errorf("GET %s with cookie %q = %d to %q, want %d to %q", tt.target, tt.cookie, rec.Code, rec.Header().Get("Location"), tt.status, tt.location)
+				}
+				if rec.Header().Get("Vary") != "Cookie" {
+					t.Errorf("GET %s with cookie %q doesn't vary by cookie", tt.target, tt.cookie)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchRedirectSetting(t *testing.T) {
+	app := newTestApp(t, newFakeTMDB(t, nil))
+
+	for _, tt := range []struct {
+		value      string
+		status     int
+		wantCookie string // "" for none
+	}{
+		{"on", http.StatusSeeOther, "on"},
+		{"off", http.StatusSeeOther, "off"},
+		{"", http.StatusSeeOther, ""},
+		{"sometimes", http.StatusBadRequest, ""},
+	} {
+		form := url.Values{"spoiler_mode": {"off"}}
+		if tt.value != "" {
+			form.Set("search_redirect", tt.value)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/settings", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		var got string
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == searchRedirectCookie {
+				got = cookie.Value
+			}
+		}
+		if rec.Code != tt.status || got != tt.wantCookie {
+			t.Errorf("search_redirect=%q: %d, cookie %q; want %d, %q", tt.value, rec.Code, got, tt.status, tt.wantCookie)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/settings", nil)
+	req.AddCookie(&http.Cookie{Name: searchRedirectCookie, Value: "on"})
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `value="on" checked> On`) {
+		t.Error("settings page doesn't show the saved choice")
+	}
+}
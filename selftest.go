@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Known-good inputs for the self-test. The Matrix has been on TMDB forever
+// and is unlikely to move.
+const (
+	selfTestQuery   = "The Matrix"
+	selfTestMovieID = 603
+)
+
+// selfTestStepTimeout bounds each step so a hung connection fails the run
+// instead of blocking a deploy.
+const selfTestStepTimeout = 10 * time.Second
+
+// selfTestStep is one check run by -selftest.
+type selfTestStep struct {
+	Name string
+	Run  func(ctx context.Context, config Config, state *selfTestState) error
+}
+
+// selfTestState carries results from one step to the next.
+type selfTestState struct {
+	movie *MovieDetail
+}
+
+// selfTestSteps run in order; a step is skipped when an earlier one it
+// depends on failed.
+var selfTestSteps = []selfTestStep{
+	{Name: "search", Run: func(ctx context.Context, config Config, state *selfTestState) error {
+		results, err := searchMovies(ctx, selfTestQuery, config.TMDB, false)
+		if err != nil {
+			return err
+		}
+		if len(results.Results) == 0 {
+			return fmt.Errorf("no results for %q", selfTestQuery)
+		}
+		return nil
+	}},
+	{Name: "details", Run: func(ctx context.Context, config Config, state *selfTestState) error {
+		movie, err := fetchMovieDetails(ctx, strconv.Itoa(selfTestMovieID), config.TMDB)
+		if err != nil {
+			return err
+		}
+		if movie.Title == "" {
+			return fmt.Errorf("movie %d has no title (is TMDB_API_KEY valid?)", selfTestMovieID)
+		}
+		state.movie = movie
+		return nil
+	}},
+	{Name: "render", Run: func(ctx context.Context, config Config, state *selfTestState) error {
+		if state.movie == nil {
+			return fmt.Errorf("skipped, no movie details")
+		}
+		var page bytes.Buffer
+		return tmpl.standard.Execute(&page, DetailPage{MovieDetail: state.movie})
+	}},
+}
+
+// runSelfTest checks the API key and TMDB connectivity end to end, printing
+// PASS or FAIL with the duration of every step. It reports whether all
+// steps passed.
+func runSelfTest(config Config, out io.Writer) bool {
+	var state selfTestState
+	passed := true
+	for _, step := range selfTestSteps {
+		ctx, cancel := context.WithTimeout(context.Background(), selfTestStepTimeout)
+		start := time.Now()
+		err := step.Run(ctx, config, &state)
+		elapsed := time.Since(start).Milliseconds()
+		cancel()
+
+		if err != nil {
+			passed = false
+			fmt.Fprintf(out, "FAIL %-8s %5dms  %v\n", step.Name, elapsed, err)
+			continue
+		}
+		fmt.Fprintf(out, "PASS %-8s %5dms\n", step.Name, elapsed)
+	}
+	return passed
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// shutdownGracePeriod is how long requests in flight get to finish once
+// the server is told to stop.
+const shutdownGracePeriod = 10 * time.Second
+
+// App is the web app: its HTTP handler and the state behind it that
+// outlives a request, the TMDB client with its caches and the view counts.
+// Apps share nothing, so tests can build one each.
+type App struct {
+	http.Handler
+	config Config
+
+	stopFlushing chan struct{} // closed by Close
+	flushed      chan struct{} // closed once the view count flusher returns
+}
+
+// NewApp builds the app for config, as loaded by LoadConfig: it sets up the
+// TMDB client and the view counter, starts saving the counts every
+// ViewCountsFlushInterval and wires the handler. Close stops it.
+func NewApp(config Config) (*App, error) {
+	transport := &TimingTransport{Log: SamplingLogger(appLogger(config), config.LogSampleRate)}
+	if config.TMDBAuditLog != "" {
+		var err error
+		transport.Audit, err = tmdbAuditLogger(config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TMDB_AUDIT_LOG: %w", err)
+		}
+	}
+	config.TMDB = newTMDB(config, transport)
+
+	app := &App{config: config}
+	if config.ViewCountsFile != "" {
+		counts, err := loadViewCounter(config.ViewCountsFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VIEW_COUNTS_FILE: %w", err)
+		}
+		app.config.ViewCounts = counts
+		app.stopFlushing, app.flushed = make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(app.flushed)
+			counts.flushEvery(config.ViewCountsFlushInterval, app.stopFlushing)
+		}()
+	}
+	app.Handler = newHandler(app.config)
+	return app, nil
+}
+
+// Close stops the periodic view count flush and saves the counts once
+// more. The handler must not be used after Close.
+func (a *App) Close() error {
+	if a.config.ViewCounts == nil {
+		return nil
+	}
+	close(a.stopFlushing)
+	<-a.flushed
+	return a.config.ViewCounts.Flush()
+}
+
+// newHandler wires every route and the middleware chain for config. Routes
+// go on a fresh ServeMux rather than http.DefaultServeMux, so the handler
+// can be built more than once in a process without the registrations
+// colliding.
+func newHandler(config Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		homeHandler(w, r, config)
+	})
+	mux.HandleFunc("/movie/", func(w http.ResponseWriter, r *http.Request) {
+		movieDetailsHandler(w, r, config) // Note the trailing slash for correct routing.
+	})
+	mux.HandleFunc("/person/", func(w http.ResponseWriter, r *http.Request) {
+		personHandler(w, r, config)
+	})
+	mux.HandleFunc("/people", func(w http.ResponseWriter, r *http.Request) {
+		peopleHandler(w, r, config)
+	})
+	mux.HandleFunc("/company/", func(w http.ResponseWriter, r *http.Request) {
+		companyHandler(w, r, config)
+	})
+	mux.HandleFunc("/collection/", func(w http.ResponseWriter, r *http.Request) {
+		collectionHandler(w, r, config)
+	})
+	mux.HandleFunc("/collections", func(w http.ResponseWriter, r *http.Request) {
+		collectionIndexHandler(w, r, config)
+	})
+	mux.HandleFunc("/best/", func(w http.ResponseWriter, r *http.Request) {
+		bestHandler(w, r, config)
+	})
+	mux.HandleFunc("/cinema", func(w http.ResponseWriter, r *http.Request) {
+		cinemaHandler(w, r, config)
+	})
+	mux.HandleFunc("/planner/generate", func(w http.ResponseWriter, r *http.Request) {
+		plannerHandler(w, r, config)
+	})
+	mux.HandleFunc("/tonight", func(w http.ResponseWriter, r *http.Request) {
+		tonightHandler(w, r, config)
+	})
+	mux.HandleFunc("/quiz", func(w http.ResponseWriter, r *http.Request) {
+		quizHandler(w, r, config)
+	})
+	mux.HandleFunc("/settings", func(w http.ResponseWriter, r *http.Request) {
+		settingsHandler(w, r, config)
+	})
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		sitemapHandler(w, r, config)
+	})
+	mux.HandleFunc("/api/widgets/trending", func(w http.ResponseWriter, r *http.Request) {
+		widgetTrendingHandler(w, r, config)
+	})
+	mux.HandleFunc("/oembed", func(w http.ResponseWriter, r *http.Request) {
+		oEmbedHandler(w, r, config)
+	})
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		apiSearchHandler(w, r, config)
+	})
+	mux.HandleFunc("/admin/purge", func(w http.ResponseWriter, r *http.Request) {
+		purgeHandler(w, r, config)
+	})
+
+	logger := appLogger(config)
+	accessLog := SamplingLogger(logger, config.LogSampleRate)
+	handler := timingMiddleware(accessLogMiddleware(MinifyMiddleware(mux), accessLog))
+	if config.MaxConcurrentRequests > 0 {
+		queue := newRequestQueue(config.MaxConcurrentRequests, config.RequestQueueDepth, config.RequestQueueTimeout)
+		handler = queue.middleware(handler)
+	}
+	if config.ErrorWebhookURL != "" {
+		handler = errorReportingMiddleware(handler, newErrorReporter(config.ErrorWebhookURL))
+	}
+	// Outside the error reporter, which re-raises panics once reported.
+	handler = RecoveryMiddleware(logger)(handler)
+	handler = ClientIPMiddleware(handler, config.TrustedProxies)
+	handler = RequestIDMiddleware(handler)
+
+	return handler
+}
+
+// serveUntil runs listen, server's ListenAndServe or ListenAndServeTLS,
+// until ctx is done. It then stops accepting connections and waits up to
+// grace for the requests in flight, closing whatever is still open after
+// that. It returns listen's error if the server stopped on its own.
+func serveUntil(ctx context.Context, server *http.Server, listen func() error, grace time.Duration) error {
+	stopped := make(chan error, 1)
+	go func() { stopped <- listen() }()
+	select {
+	case err := <-stopped:
+		return err
+	case <-ctx.Done():
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+		return err
+	}
+	return nil
+}
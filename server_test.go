@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchThenDetail(t *testing.T) {
+	app := newTestApp(t, newFixtureTMDB(t))
+
+	search := get(app, "/?keyword=matrix")
+	if search.Code != http.StatusOK {
+		t.Fatalf("search: status %d, want 200", search.Code)
+	}
+	for _, want := range []string{"/movie/the-matrix-603", "/movie/the-matrix-reloaded-604"} {
+		if !strings.Contains(search.Body.String(), want) {
+			t.Errorf("search results don't link to %s", want)
+		}
+	}
+
+	detail := get(app, "/movie/the-matrix-603")
+	if detail.Code != http.StatusOK {
+		t.Fatalf("detail: status %d, want 200", detail.Code)
+	}
+	for _, want := range []string{"The Matrix", "1999", "Lana Wachowski", `href="/person/9339"`, `href="/company/174"`} {
+		if !strings.Contains(detail.Body.String(), want) {
+			t.Errorf("detail page doesn't mention %q", want)
+		}
+	}
+}
+
+func TestJourneysFromTheDetailPage(t *testing.T) {
+	app := newTestApp(t, newFixtureTMDB(t))
+
+	for _, tt := range []struct {
+		target string
+		want   []string
+	}{
+		{"/person/6384", []string{"Keanu Reeves", "/movie/john-wick-245891"}},
+		{"/company/174", []string{"Warner Bros. Pictures", "/movie/the-matrix-603"}},
+		{"/collection/2344", []string{"The Matrix Collection", "/movie/the-matrix-reloaded-604"}},
+		{"/people?query=wachowski", []string{"Lana Wachowski", "Lilly Wachowski"}},
+	} {
+		rec := get(app, tt.target)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", tt.target, rec.Code)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("GET %s doesn't mention %q", tt.target, want)
+			}
+		}
+	}
+
+	if rec := get(app, "/movie/tt0133093"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/movie/the-matrix-603" {
+		t.Errorf("IMDb ID: %d to %q, want 301 to /movie/the-matrix-603", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestDetailRedirectsToCanonicalSlug(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{"/movie/603": movieMatrixJSON})
+	app := newTestApp(t, fake)
+
+	for _, path := range []string{"/movie/603", "/movie/matrix-603"} {
+		rec := get(app, path)
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/movie/the-matrix-603" {
+			t.Errorf("GET %s = %d to %q, want 301 to /movie/the-matrix-603", path, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
+
+func TestDetailPageTMDBCalls(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{"/movie/603": movieMatrixJSON})
+	app := newTestApp(t, fake)
+
+	rec := get(app, "/movie/the-matrix-603")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	if calls := fake.calls(""); calls > 2 {
+		t.Errorf("cold detail page made %d TMDB calls, want at most 2", calls)
+	}
+	if got := fake.query("/movie/603").Get("append_to_response"); got != strings.Join(detailPageAppends, ",") {
+		t.Errorf("append_to_response = %q, want %q", got, strings.Join(detailPageAppends, ","))
+	}
+
+	// Every section comes from the one combined response.
+	body := rec.Body.String()
+	for section, want := range map[string]string{
+		"credits":         `Don Davis</a> &ndash; Composer`,
+		"videos":          `<span class="badge">Trailer</span>`,
+		"release_dates":   `Theatrical (R)`,
+		"recommendations": `href="/movie/the-matrix-reloaded-604"`,
+		"watch/providers": `<span dir="auto">Netflix</span>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %s from %s", want, section)
+		}
+	}
+}
+
+func TestDetailNotFound(t *testing.T) {
+	app := newTestApp(t, newFakeTMDB(t, nil))
+
+	if rec := get(app, "/movie/99999999"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown movie: status %d, want 404", rec.Code)
+	}
+	if rec := get(app, "/movie/not-a-number"); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed ID: status %d, want 400", rec.Code)
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	fake := newFakeTMDB(t, nil)
+	fake.handle("/search/movie", fakeResponse{
+		Status: http.StatusServiceUnavailable,
+		Header: http.Header{"Retry-After": {"120"}},
+		Body:   `{"status_code":9,"status_message":"Service offline."}`,
+	})
+	app := newTestApp(t, fake)
+
+	page := get(app, "/?keyword=matrix")
+	if page.Code != http.StatusServiceUnavailable || page.Header().Get("Retry-After") != "120" {
+		t.Errorf("page: status %d, Retry-After %q, want 503 and 120", page.Code, page.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(page.Body.String(), "undergoing maintenance") {
+		t.Error("page doesn't show the maintenance notice")
+	}
+
+	api := get(app, "/api/search?query=matrix")
+	var body apiErrorBody
+	if err := json.NewDecoder(api.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if api.Code != http.StatusServiceUnavailable || body.Error.Code != apiTMDBUnavailable {
+		t.Errorf("API: status %d, code %q, want 503 and %q", api.Code, body.Error.Code, apiTMDBUnavailable)
+	}
+}
+
+func TestAPISearch(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{"/search/movie": searchMatrixJSON})
+	app := newTestApp(t, fake)
+
+	rec := get(app, "/api/search?query=matrix")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	var body apiSearchResults
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Results) != 2 || body.Results[0].ID != 603 {
+		t.Errorf("results = %+v, want The Matrix first of 2", body.Results)
+	}
+}
+
+// startServer runs handler with serveUntil on a free local port until the
+// returned cancel is called. serveUntil's result arrives on done.
+func startServer(t *testing.T, handler http.Handler, grace time.Duration) (url string, cancel context.CancelFunc, done <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: handler}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	result := make(chan error, 1)
+	go func() { result <- serveUntil(ctx, server, func() error { return server.Serve(listener) }, grace) }()
+	return "http://" + listener.Addr().String(), cancel, result
+}
+
+func TestServeUntilFinishesRequestsInFlight(t *testing.T) {
+	started := make(chan struct{})
+	url, cancel, done := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "finished")
+	}), 5*time.Second)
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{string(body), err}
+	}()
+	<-started
+	cancel()
+
+	if got := <-responses; got.err != nil || got.body != "finished" {
+		t.Errorf("request in flight got %q, %v", got.body, got.err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serveUntil = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveUntil did not return after the request finished")
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("server still accepts connections after shutdown")
+	}
+}
+
+func TestServeUntilGracePeriod(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	url, cancel, done := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release // a request stuck on, say, a dead webhook target
+	}), 100*time.Millisecond)
+
+	go http.Get(url)
+	<-started
+	start := time.Now()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("serveUntil = %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("shutdown took %s with a 100ms grace period", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveUntil waited past the grace period")
+	}
+}
+
+func TestServeUntilListenError(t *testing.T) {
+	listenErr := errors.New("address already in use")
+	err := serveUntil(context.Background(), &http.Server{}, func() error { return listenErr }, time.Second)
+	if err != listenErr {
+		t.Errorf("serveUntil = %v, want the listen error", err)
+	}
+}
+
+// The benchmarks below serve the two busiest pages against the fixture
+// TMDB. cmd/helm-render's resource profile is derived from their results;
+// rerun them with
+//
+//	go test -run '^$' -bench 'HomeSearch|Detail$' -benchmem
+//
+// and update the profile when they change much. ns/op includes the fake
+// TMDB answering on loopback, so it overstates the app's own CPU time.
+
+func BenchmarkHomeSearch(b *testing.B) {
+	app := newTestApp(b, newFixtureTMDB(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if rec := get(app, "/?keyword=matrix"); rec.Code != http.StatusOK {
+			b.Fatalf("search: status %d, want 200", rec.Code)
+		}
+	}
+}
+
+func BenchmarkDetail(b *testing.B) {
+	app := newTestApp(b, newFixtureTMDB(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if rec := get(app, "/movie/the-matrix-603"); rec.Code != http.StatusOK {
+			b.Fatalf("detail: status %d, want 200", rec.Code)
+		}
+	}
+}
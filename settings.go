@@ -0,0 +1,202 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// spoilerCookie holds the visitor's spoiler-free preference, "on" or "off".
+const spoilerCookie = "spoiler_mode"
+
+// searchRedirectCookie holds whether a search with one obvious match goes
+// straight to its detail page for the visitor, "on" or "off".
+const searchRedirectCookie = "search_redirect"
+
+// settingsCookieMaxAge is how long preference cookies are kept.
+const settingsCookieMaxAge = 365 * 24 * time.Hour
+
+// SettingsPage is the data rendered by the settings template.
+type SettingsPage struct {
+	SpoilerFree bool
+	Meta        Meta
+	Theme       string // "light" or "dark", "" for automatic
+	TextMode    bool
+	// OriginalPosters shows original-language posters on detail pages.
+	OriginalPosters bool
+	SearchRedirect  bool
+	HomeModules     []HomeModuleSetting
+	Saved           bool
+}
+
+var settingsTmpl = pageTemplate("settings", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>Settings</title>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1>Settings</h1>
+    {{if .Saved}}<p>Settings saved.</p>{{end}}
+    <form action="/settings" method="POST">
+        <fieldset>
+            <legend>Spoiler-free mode</legend>
+            <p>Hide movie overviews until you choose to show them.</p>
+            <label><input type="radio" name="spoiler_mode" value="on"{{if .SpoilerFree}} checked{{end}}> On</label>
+            <label><input type="radio" name="spoiler_mode" value="off"{{if not .SpoilerFree}} checked{{end}}> Off</label>
+        </fieldset>
+        <fieldset>
+            <legend>Theme</legend>
+            <p>Automatic follows your device's light or dark setting.</p>
+            <label><input type="radio" name="theme" value="auto"{{if not .Theme}} checked{{end}}> Automatic</label>
+            <label><input type="radio" name="theme" value="light"{{if eq .Theme "light"}} checked{{end}}> Light</label>
+            <label><input type="radio" name="theme" value="dark"{{if eq .Theme "dark"}} checked{{end}}> Dark</label>
+        </fieldset>
+        <fieldset>
+            <legend>Display</legend>
+            <p>Text only shows lists without images, for text browsers and screen readers.</p>
+            <label><input type="radio" name="view" value="standard"{{if not .TextMode}} checked{{end}}> Standard</label>
+            <label><input type="radio" name="view" value="text"{{if .TextMode}} checked{{end}}> Text only</label>
+        </fieldset>
+        <fieldset>
+            <legend>Posters</legend>
+            <p>Original posters show the one-sheet from the movie's home country instead of one with a translated title, where TMDB has it.</p>
+            <label><input type="radio" name="posters" value="default"{{if not .OriginalPosters}} checked{{end}}> Default</label>
+            <label><input type="radio" name="posters" value="original"{{if .OriginalPosters}} checked{{end}}> Original</label>
+        </fieldset>
+        <fieldset>
+            <legend>Search</legend>
+            <p>Go straight to a movie's page when a search has one obvious match.</p>
+            <label><input type="radio" name="search_redirect" value="on"{{if .SearchRedirect}} checked{{end}}> On</label>
+            <label><input type="radio" name="search_redirect" value="off"{{if not .SearchRedirect}} checked{{end}}> Off</label>
+        </fieldset>
+        <fieldset>
+            <legend>Home page</legend>
+            <p>Pick the sections the home page shows, and number them in the order you want.</p>
+            {{range .HomeModules}}
+            <p>
+                <label><input type="checkbox" name="home_module" value="{{.Name}}"{{if .Enabled}} checked{{end}}> {{.Title}}</label>
+                <label>Position <input type="number" name="home_position_{{.Name}}" value="{{.Position}}" min="1" size="2"></label>
+            </p>
+            {{end}}
+        </fieldset>
+        <button type="submit">Save</button>
+    </form>
+    {{template "footer"}}
+</body>
+</html>
+`, "")
+
+// settingsHandler shows the preferences form on GET and stores the choices
+// in cookies on POST.
+func settingsHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		mode := r.PostFormValue("spoiler_mode")
+		if mode != "on" && mode != "off" {
+			http.Error(w, "spoiler_mode must be on or off", http.StatusBadRequest)
+			return
+		}
+		themeChoice := r.PostFormValue("theme")
+		if themeChoice == "" {
+			themeChoice = "auto"
+		}
+		if !slices.Contains(themes, themeChoice) {
+			http.Error(w, "theme must be auto, light or dark", http.StatusBadRequest)
+			return
+		}
+		view := r.PostFormValue("view")
+		if view == "" {
+			view = "standard"
+		}
+		if view != "standard" && view != "text" {
+			http.Error(w, "view must be standard or text", http.StatusBadRequest)
+			return
+		}
+		posters := r.PostFormValue("posters")
+		if posters == "" {
+			posters = "default"
+		}
+		if posters != "default" && posters != "original" {
+			http.Error(w, "posters must be default or original", http.StatusBadRequest)
+			return
+		}
+		// Left out, the visitor keeps their choice or SEARCH_AUTO_REDIRECT.
+		redirect := r.PostFormValue("search_redirect")
+		if redirect != "" && redirect != "on" && redirect != "off" {
+			http.Error(w, "search_redirect must be on or off", http.StatusBadRequest)
+			return
+		}
+		modules, err := homeModulesFromForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		setPreferenceCookie(w, spoilerCookie, mode)
+		setPreferenceCookie(w, themeCookie, themeChoice)
+		setPreferenceCookie(w, viewCookie, view)
+		setPreferenceCookie(w, posterCookie, posters)
+		if redirect != "" {
+			setPreferenceCookie(w, searchRedirectCookie, redirect)
+		}
+		setPreferenceCookie(w, homeModulesCookie, modules)
+		http.Redirect(w, r, "/settings?saved=1", http.StatusSeeOther)
+		return
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page := SettingsPage{Meta: pageMeta("/settings"), SpoilerFree: spoilerFree(r, config.SpoilerFreeDefault), Theme: theme(r), TextMode: textMode(r), OriginalPosters: preferOriginalPosters(r), SearchRedirect: searchRedirect(r, config.SearchAutoRedirect), HomeModules: homeModuleSettings(chosenHomeModules(r, config.HomeModules)), Saved: r.URL.Query().Get("saved") == "1"}
+	body, err := render(r, settingsTmpl, page)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, searchResponse)
+	body.WriteTo(w)
+}
+
+// setPreferenceCookie stores one of the visitor's settings.
+func setPreferenceCookie(w http.ResponseWriter, name, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(settingsCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// spoilerFree reports whether spoiler-free mode is on for the visitor: what
+// they chose at /settings, or byDefault (SPOILER_FREE_DEFAULT) when they
+// haven't chosen. Pages that depend on it must send Vary: Cookie.
+func spoilerFree(r *http.Request, byDefault bool) bool {
+	cookie, err := r.Cookie(spoilerCookie)
+	if err != nil || (cookie.Value != "on" && cookie.Value != "off") {
+		return byDefault
+	}
+	return cookie.Value == "on"
+}
+
+// searchRedirect reports whether a search with one obvious match goes
+// straight to its detail page for the visitor: what they chose at
+// /settings, or byDefault (SEARCH_AUTO_REDIRECT) when they haven't chosen.
+func searchRedirect(r *http.Request, byDefault bool) bool {
+	cookie, err := r.Cookie(searchRedirectCookie)
+	if err != nil || (cookie.Value != "on" && cookie.Value != "off") {
+		return byDefault
+	}
+	return cookie.Value == "on"
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+)
+
+// sitemapEntry is a route listed in /sitemap.xml.
+type sitemapEntry struct {
+	Path       string
+	ChangeFreq string
+	Priority   string
+}
+
+// sitemapRoutes are the indexable browse pages. Individual movies and
+// people are deliberately left out; there are far too many of them. The
+// "Best of" pages and the featured collections are listed by
+// sitemapHandler.
+var sitemapRoutes = []sitemapEntry{
+	{Path: "/", ChangeFreq: "daily", Priority: "1.0"},
+	{Path: "/collections", ChangeFreq: "weekly", Priority: "0.6"},
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+func sitemapHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	urlSet := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, route := range sitemapRoutes {
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{
+			Loc:        config.URLs.Absolute(route.Path),
+			ChangeFreq: route.ChangeFreq,
+			Priority:   route.Priority,
+		})
+	}
+	for _, id := range config.FeaturedCollections {
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{
+			Loc:        config.URLs.Collection(id),
+			ChangeFreq: "monthly",
+			Priority:   "0.5",
+		})
+	}
+	for year := localNow(config).Year(); year >= bestMinYear; year-- {
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{
+			Loc:        config.URLs.Best(year),
+			ChangeFreq: "monthly",
+			Priority:   "0.5",
+		})
+	}
+
+	output, err := xml.MarshalIndent(urlSet, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding sitemap: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	setCacheControl(w, config, apiResponse)
+	w.Write([]byte(xml.Header))
+	w.Write(output)
+}
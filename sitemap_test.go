@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// sitemapLocs fetches /sitemap.xml from app and returns the listed URLs.
+func sitemapLocs(t *testing.T, app http.Handler) []string {
+	t.Helper()
+	rec := get(app, "/sitemap.xml")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /sitemap.xml = %d", rec.Code)
+	}
+	var urlSet sitemapURLSet
+	if err := xml.Unmarshal(rec.Body.Bytes(), &urlSet); err != nil {
+		t.Fatalf("sitemap is not valid XML: %v", err)
+	}
+	var locs []string
+	for _, u := range urlSet.URLs {
+		locs = append(locs, u.Loc)
+	}
+	return locs
+}
+
+func TestSitemapListsBestYears(t *testing.T) {
+	t.Setenv("BASE_URL", "https://movies.example")
+	locs := sitemapLocs(t, newTestApp(t, newFakeTMDB(t, nil)))
+
+	thisYear := time.Now().UTC().Year()
+	tests := []struct {
+		year int
+		want bool
+	}{
+		{bestMinYear - 1, false},
+		{bestMinYear, true},
+		{2000, true},
+		{thisYear, true},
+		{thisYear + 1, false},
+	}
+	for _, tt := range tests {
+		loc := fmt.Sprintf("https://movies.example/best/%d", tt.year)
+		if got := slices.Contains(locs, loc); got != tt.want {
+			t.Errorf("sitemap lists %s: %v, want %v", loc, got, tt.want)
+		}
+	}
+	if !slices.Contains(locs, "https://movies.example/") {
+		t.Error("sitemap does not list the home page")
+	}
+}
+
+func TestSitemapListsCollections(t *testing.T) {
+	t.Setenv("BASE_URL", "https://movies.example")
+	file := filepath.Join(t.TempDir(), "collections.txt")
+	if err := os.WriteFile(file, []byte("# featured\n2344\n\n10\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("COLLECTIONS_FILE", file)
+	fake := newFakeTMDB(t, nil)
+	locs := sitemapLocs(t, newTestApp(t, fake))
+
+	for _, loc := range []string{
+		"https://movies.example/collections",
+		"https://movies.example/collection/2344",
+		"https://movies.example/collection/10",
+	} {
+		if !slices.Contains(locs, loc) {
+			t.Errorf("sitemap does not list %s", loc)
+		}
+	}
+	if slices.Contains(locs, "https://movies.example/collection/1241") {
+		t.Error("sitemap lists a default collection COLLECTIONS_FILE replaced")
+	}
+	if n := fake.calls(""); n != 0 {
+		t.Errorf("sitemap made %d TMDB calls, want none", n)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Slug builds the canonical path segment for a movie, e.g. "the-dark-knight-603".
+// The title is lowercased, every run of characters that are not letters or
+// digits becomes a single hyphen, and the TMDB ID is appended. Letters outside
+// ASCII are kept, so "Amélie" becomes "amélie-194".
+func Slug(title string, id int) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingHyphen = true
+	}
+	if b.Len() > 0 {
+		b.WriteByte('-')
+	}
+	b.WriteString(strconv.Itoa(id))
+	return b.String()
+}
+
+// movieURL is the canonical detail page path for a movie, escaped for use in
+// links and Location headers.
+func movieURL(id int, title string) string {
+	return "/movie/" + url.PathEscape(Slug(title, id))
+}
+
+// movieIDFromSlug returns the TMDB ID from the last hyphen-delimited part of
+// a slug. A bare ID ("603") is accepted as well.
+func movieIDFromSlug(slug string) (int, error) {
+	idPart := slug[strings.LastIndex(slug, "-")+1:]
+	id, err := strconv.Atoi(idPart)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid movie slug %q", slug)
+	}
+	return id, nil
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// watchProvidersAppend is the append_to_response name of TMDB's
+	// watch/providers sub-resource.
+	watchProvidersAppend = "watch/providers"
+	justWatchBaseURL     = "https://apis.justwatch.com/content"
+
+	// justWatchTimeout bounds each JustWatch call. JustWatch is optional,
+	// TMDB's providers are the fallback, so it gets less time than TMDB.
+	justWatchTimeout = 5 * time.Second
+
+	// justWatchNamesTTL is how long JustWatch's provider names are kept.
+	// They only change when a service launches or is renamed.
+	justWatchNamesTTL = 24 * time.Hour
+
+	// justWatchAttribution credits JustWatch, the source of TMDB's watch
+	// provider data. TMDB's terms require it wherever that data is shown.
+	justWatchAttribution = "Streaming data powered by JustWatch"
+)
+
+// Platform is a single service where a movie can be watched.
+// Type is one of "stream", "rent" or "buy".
+type Platform struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Type string `json:"type"`
+}
+
+// StreamingInfo lists every platform a movie is available on.
+type StreamingInfo struct {
+	ID          int        `json:"id"` // TMDB ID, also when asked by IMDb ID
+	Platforms   []Platform `json:"platforms"`
+	Attribution string     `json:"attribution"` // always justWatchAttribution
+}
+
+// WatchProvider is a single provider entry from TMDB's watch/providers endpoint.
+type WatchProvider struct {
+	ProviderID   int    `json:"provider_id"`
+	ProviderName string `json:"provider_name"`
+	LogoPath     string `json:"logo_path"`
+}
+
+// RegionProviders holds the providers TMDB knows about for one region.
+type RegionProviders struct {
+	Link     string          `json:"link"`
+	Flatrate []WatchProvider `json:"flatrate"`
+	Rent     []WatchProvider `json:"rent"`
+	Buy      []WatchProvider `json:"buy"`
+}
+
+// WatchProvidersResponse wraps the per-region provider lists.
+type WatchProvidersResponse struct {
+	Results map[string]RegionProviders `json:"results"`
+}
+
+// justWatchHTTPClient is used for every JustWatch call.
+var justWatchHTTPClient = &http.Client{Timeout: justWatchTimeout}
+
+// JustWatchClient talks to the public JustWatch content API.
+type JustWatchClient struct {
+	Locale  string
+	BaseURL string // justWatchBaseURL when empty
+
+	// names caches the provider names across clients. Without it they are
+	// fetched on every call to Platforms.
+	names *justWatchNamesCache
+}
+
+// justWatchNamesCache holds JustWatch's provider names for each locale,
+// shared by all requests. Failed lookups aren't cached.
+type justWatchNamesCache struct {
+	mu      sync.Mutex
+	locales map[string]cachedProviderNames
+}
+
+type cachedProviderNames struct {
+	names   map[string]string
+	expires time.Time
+}
+
+type justWatchOffer struct {
+	MonetizationType string `json:"monetization_type"`
+	PackageShortName string `json:"package_short_name"`
+	URLs             struct {
+		StandardWeb string `json:"standard_web"`
+	} `json:"urls"`
+}
+
+type justWatchTitle struct {
+	Scoring []struct {
+		ProviderType string  `json:"provider_type"`
+		Value        float64 `json:"value"`
+	} `json:"scoring"`
+	Offers []justWatchOffer `json:"offers"`
+}
+
+type justWatchProvider struct {
+	ShortName string `json:"short_name"`
+	ClearName string `json:"clear_name"`
+}
+
+func streamingAvailabilityHandler(w http.ResponseWriter, r *http.Request, config Config, movieID string) {
+	tmdbID, err := strconv.Atoi(movieID)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiBadRequest, "Invalid movie ID")
+		return
+	}
+
+	// The TMDB providers come along with the details, saving a request.
+	start := time.Now()
+	movie, err := fetchMovieDetails(r.Context(), movieID, config.TMDB, watchProvidersAppend)
+	RecordTiming(r.Context(), "tmdb_detail", start)
+	if err != nil {
+		log.Printf("Error fetching movie details: %v", err)
+		tmdbAPIFailure(w, err, "Failed to fetch movie details")
+		return
+	}
+	// TMDB answers unknown IDs with an error object, which decodes to an
+	// empty movie.
+	if movie.ID == 0 {
+		writeAPIError(w, http.StatusNotFound, apiNotFound, "Movie not found")
+		return
+	}
+
+	// JustWatch has direct links, so it goes first. TMDB fills in whatever it misses.
+	var platforms []Platform
+	justWatch := JustWatchClient{Locale: justWatchLocale(config.Region), names: &config.TMDB.justWatchNames}
+	start = time.Now()
+	jw, err := justWatch.Platforms(r.Context(), tmdbID, movie.Title)
+	RecordTiming(r.Context(), "justwatch", start)
+	if err != nil {
+		log.Printf("JustWatch unavailable, falling back to TMDB: %v", err)
+	} else {
+		platforms = jw
+	}
+
+	info := StreamingInfo{
+		ID:          movie.ID,
+		Platforms:   mergePlatforms(platforms, watchPlatforms(movie.WatchProviders, config.Region)),
+		Attribution: justWatchAttribution,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setCacheControl(w, config, apiResponse)
+	setSurrogateKeys(w, movieSurrogateKey(movie.ID))
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		log.Printf("Error encoding streaming availability: %v", err)
+	}
+}
+
+// mergePlatforms combines platform lists, keeping the first entry seen for
+// each platform name and type.
+func mergePlatforms(lists ...[]Platform) []Platform {
+	merged := []Platform{}
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, p := range list {
+			key := strings.ToLower(p.Name) + "|" + p.Type
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// ProviderGroup is one kind of offer, such as streaming, on the detail page.
+type ProviderGroup struct {
+	Label     string
+	Providers []WatchProvider
+}
+
+// providerGroups returns the non-empty stream, rent and buy groups of
+// TMDB's watch providers for region, and the TMDB page every provider links
+// through. TMDB's terms ask for that link rather than links straight to
+// each service.
+func providerGroups(providers *WatchProvidersResponse, region string) ([]ProviderGroup, string) {
+	regional := regionProviders(providers, region)
+	if regional == nil {
+		return nil, ""
+	}
+	var groups []ProviderGroup
+	for _, group := range []ProviderGroup{
+		{"Stream", regional.Flatrate},
+		{"Rent", regional.Rent},
+		{"Buy", regional.Buy},
+	} {
+		if len(group.Providers) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, regional.Link
+}
+
+// regionProviders returns TMDB's watch providers for region, or nil when
+// providers is nil or lists none there.
+func regionProviders(providers *WatchProvidersResponse, region string) *RegionProviders {
+	if providers == nil {
+		return nil
+	}
+	regional, ok := providers.Results[region]
+	if !ok || len(regional.Flatrate)+len(regional.Rent)+len(regional.Buy) == 0 {
+		return nil
+	}
+	return &regional
+}
+
+// watchPlatforms returns TMDB's watch providers for a region as platforms,
+// or nil when there are none. TMDB only exposes one link per region, so
+// every platform shares it.
+func watchPlatforms(providers *WatchProvidersResponse, region string) []Platform {
+	regional := regionProviders(providers, region)
+	if regional == nil {
+		return nil
+	}
+
+	var platforms []Platform
+	add := func(list []WatchProvider, kind string) {
+		for _, p := range list {
+			platforms = append(platforms, Platform{Name: p.ProviderName, URL: regional.Link, Type: kind})
+		}
+	}
+	add(regional.Flatrate, "stream")
+	add(regional.Rent, "rent")
+	add(regional.Buy, "buy")
+	return platforms
+}
+
+// Platforms looks the movie up by title and returns the offers of the entry
+// whose TMDB ID matches. The calls stop when ctx is done.
+func (c JustWatchClient) Platforms(ctx context.Context, tmdbID int, title string) ([]Platform, error) {
+	query, err := json.Marshal(map[string]interface{}{
+		"query":         title,
+		"content_types": []string{"movie"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	requestURL := fmt.Sprintf("%s/titles/%s/popular?body=%s", c.baseURL(), c.Locale, url.QueryEscape(string(query)))
+	var results struct {
+		Items []justWatchTitle `json:"items"`
+	}
+	if err := justWatchGet(ctx, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	names, err := c.providerNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range results.Items {
+		if !item.hasTMDBID(tmdbID) {
+			continue
+		}
+
+		var platforms []Platform
+		for _, offer := range item.Offers {
+			kind := justWatchType(offer.MonetizationType)
+			if kind == "" {
+				continue
+			}
+			name := names[offer.PackageShortName]
+			if name == "" {
+				name = offer.PackageShortName
+			}
+			platforms = append(platforms, Platform{Name: name, URL: offer.URLs.StandardWeb, Type: kind})
+		}
+		return platforms, nil
+	}
+
+	return nil, nil
+}
+
+// providerNames maps JustWatch's short provider codes to display names,
+// fetching them at most once per justWatchNamesTTL when c has a cache.
+func (c JustWatchClient) providerNames(ctx context.Context) (map[string]string, error) {
+	if c.names != nil {
+		c.names.mu.Lock()
+		cached, ok := c.names.locales[c.Locale]
+		c.names.mu.Unlock()
+		if ok && time.Now().Before(cached.expires) {
+			return cached.names, nil
+		}
+	}
+
+	var providers []justWatchProvider
+	if err := justWatchGet(ctx, fmt.Sprintf("%s/providers/locale/%s", c.baseURL(), c.Locale), &providers); err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(providers))
+	for _, p := range providers {
+		names[p.ShortName] = p.ClearName
+	}
+
+	if c.names != nil {
+		c.names.mu.Lock()
+		if c.names.locales == nil {
+			c.names.locales = map[string]cachedProviderNames{}
+		}
+		c.names.locales[c.Locale] = cachedProviderNames{names: names, expires: time.Now().Add(justWatchNamesTTL)}
+		c.names.mu.Unlock()
+	}
+	return names, nil
+}
+
+func (c JustWatchClient) baseURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	return justWatchBaseURL
+}
+
+func (t justWatchTitle) hasTMDBID(tmdbID int) bool {
+	for _, s := range t.Scoring {
+		if s.ProviderType == "tmdb:id" && int(s.Value) == tmdbID {
+			return true
+		}
+	}
+	return false
+}
+
+// justWatchType maps JustWatch monetization types onto our platform types.
+func justWatchType(monetization string) string {
+	switch monetization {
+	case "flatrate", "free", "ads":
+		return "stream"
+	case "rent":
+		return "rent"
+	case "buy":
+		return "buy"
+	}
+	return ""
+}
+
+// justWatchLocale turns a region code such as "US" into a JustWatch locale.
+func justWatchLocale(region string) string {
+	return "en_" + strings.ToUpper(region)
+}
+
+// justWatchGet fetches a JustWatch URL and decodes the JSON response into v. The
+// call is abandoned when ctx is done or after justWatchTimeout, whichever
+// comes first.
+func justWatchGet(ctx context.Context, requestURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := justWatchHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
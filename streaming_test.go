@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowServer answers after a minute unless the client gives up first.
+func slowServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Minute):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestJustWatchGetStopsWithContext(t *testing.T) {
+	server := slowServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	var v any
+	if err := justWatchGet(ctx, server.URL, &v); err == nil {
+		t.Fatal("justWatchGet succeeded against a hung server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("justWatchGet returned after %s, want right after the context was done", elapsed)
+	}
+}
+
+func TestJustWatchGetTimesOut(t *testing.T) {
+	server := slowServer(t)
+	saved := justWatchHTTPClient.Timeout
+	justWatchHTTPClient.Timeout = 50 * time.Millisecond
+	t.Cleanup(func() { justWatchHTTPClient.Timeout = saved })
+
+	start := time.Now()
+	var v any
+	if err := justWatchGet(context.Background(), server.URL, &v); err == nil {
+		t.Fatal("justWatchGet succeeded against a hung server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("justWatchGet returned after %s, want right after the client timeout", elapsed)
+	}
+}
+
+func TestProviderGroups(t *testing.T) {
+	providers := &WatchProvidersResponse{Results: map[string]RegionProviders{
+		"US": {
+			Link:     "https://www.themoviedb.org/movie/603/watch?locale=US",
+			Buy:      []WatchProvider{{ProviderName: "Apple TV"}},
+			Flatrate: []WatchProvider{{ProviderName: "Netflix"}, {ProviderName: "Max"}},
+		},
+		"GB": {Link: "https://www.themoviedb.org/movie/603/watch?locale=GB"},
+	}}
+
+	groups, link := providerGroups(providers, "US")
+	var labels []string
+	for _, group := range groups {
+		labels = append(labels, group.Label)
+	}
+	if want := []string{"Stream", "Buy"}; !slices.Equal(labels, want) {
+		t.Errorf("groups %q, want %q", labels, want)
+	}
+	if link != "https://www.themoviedb.org/movie/603/watch?locale=US" {
+		t.Errorf("link %q", link)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		providers *WatchProvidersResponse
+		region    string
+	}{
+		{"not requested", nil, "US"},
+		{"no offers in the region", providers, "GB"},
+		{"region not listed", providers, "FR"},
+	} {
+		if groups, link := providerGroups(tt.providers, tt.region); groups != nil || link != "" {
+			t.Errorf("%s: providerGroups = %v, %q", tt.name, groups, link)
+		}
+	}
+}
+
+func TestWatchProvidersOnDetailPage(t *testing.T) {
+	const link = "https://www.themoviedb.org/movie/603/watch?locale=US"
+	fake := newFakeTMDB(t, map[string]string{"/movie/603": `{"id":603,"title":"The Matrix",
+		"watch/providers":{"results":{"US":{"link":"` + link + `",
+			"flatrate":[{"provider_id":8,"provider_name":"Netflix","logo_path":"/netflix.jpg"},{"provider_id":1899,"provider_name":"Max"}],
+			"rent":[{"provider_id":2,"provider_name":"Apple TV","logo_path":"/apple.jpg"}],
+			"buy":[{"provider_id":2,"provider_name":"Apple TV","logo_path":"/apple.jpg"}]}}}}`})
+
+	for _, tt := range []struct {
+		region    string
+		providers int // links on the page, 0 for no section
+	}{
+		{"US", 4},
+		{"GB", 0},
+	} {
+		t.Setenv("WATCH_REGION", tt.region)
+		app := newTestApp(t, fake)
+		for _, target := range []string{"/movie/the-matrix-603", "/movie/the-matrix-603?view=text"} {
+			body := get(app, target).Body.String()
+			want := min(tt.providers, 1)
+			if n := strings.Count(body, justWatchAttribution); n != want {
+				t.Errorf("%s, %s: JustWatch credited %d times, want %d", tt.region, target, n, want)
+			}
+			if n := strings.Count(body, `<a href="`+html.EscapeString(link)+`" rel="noopener">`); n != tt.providers {
+				t.Errorf("%s, %s: %d providers link through TMDB, want %d", tt.region, target, n, tt.providers)
+			}
+			if strings.Contains(body, "netflix.com") {
+				t.Errorf("%s, %s: a provider links straight to its service", tt.region, target)
+			}
+		}
+	}
+}
+
+// fakeJustWatch serves one title for The Matrix with a Netflix offer, and
+// the provider names. It counts the provider name lookups.
+func fakeJustWatch(t *testing.T, lookups *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/titles/en_US/popular"):
+			io.WriteString(w, `{"items":[{"scoring":[{"provider_type":"tmdb:id","value":603}],
+				"offers":[{"monetization_type":"flatrate","package_short_name":"nfx","urls":{"standard_web":"https://www.netflix.com/title/20557937"}}]}]}`)
+		case r.URL.Path == "/providers/locale/en_US":
+			lookups.Add(1)
+			io.WriteString(w, `[{"short_name":"nfx","clear_name":"Netflix"}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestJustWatchProviderNamesCached(t *testing.T) {
+	var lookups atomic.Int32
+	server := fakeJustWatch(t, &lookups)
+	var names justWatchNamesCache
+	client := JustWatchClient{Locale: "en_US", BaseURL: server.URL, names: &names}
+
+	want := []Platform{{Name: "Netflix", URL: "https://www.netflix.com/title/20557937", Type: "stream"}}
+	for i := 0; i < 3; i++ {
+		platforms, err := client.Platforms(context.Background(), 603, "The Matrix")
+		if err != nil || !slices.Equal(platforms, want) {
+			t.Fatalf("Platforms = %v, %v; want %v", platforms, err, want)
+		}
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("provider names looked up %d times, want 1", n)
+	}
+
+	names.mu.Lock()
+	cached := names.locales["en_US"]
+	cached.expires = time.Now()
+	names.locales["en_US"] = cached
+	names.mu.Unlock()
+	if _, err := client.Platforms(context.Background(), 603, "The Matrix"); err != nil {
+		t.Fatal(err)
+	}
+	if n := lookups.Load(); n != 2 {
+		t.Errorf("provider names looked up %d times after expiring, want 2", n)
+	}
+
+	// Without a cache every call looks them up.
+	uncached := JustWatchClient{Locale: "en_US", BaseURL: server.URL}
+	uncached.Platforms(context.Background(), 603, "The Matrix")
+	if n := lookups.Load(); n != 3 {
+		t.Errorf("uncached client: %d lookups, want 3", n)
+	}
+}
+
+func TestJustWatchProviderNamesFailureNotCached(t *testing.T) {
+	var names justWatchNamesCache
+	client := JustWatchClient{Locale: "en_US", BaseURL: slowServer(t).URL, names: &names}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.providerNames(ctx); err == nil {
+		t.Fatal("providerNames succeeded against a hung server")
+	}
+	if len(names.locales) != 0 {
+		t.Errorf("cached %v after a failed lookup", names.locales)
+	}
+}
+
+func TestStreamingAvailabilityErrors(t *testing.T) {
+	fake := newFakeTMDB(t, nil)
+	fake.handle("/movie/604", fakeResponse{Status: http.StatusNotFound, Body: `{"status_code":34}`})
+	app := newTestApp(t, fake)
+
+	tests := []struct {
+		target string
+		status int
+		code   string // JSON error code, "" for the HTML page's plain text error
+	}{
+		{"/movie/not-a-movie/streaming-availability", http.StatusBadRequest, apiBadRequest},
+		{"/movie/604/streaming-availability", http.StatusNotFound, apiNotFound},
+		// from_search belongs to the page; the JSON endpoint ignores it.
+		{"/movie/604/streaming-availability?from_search=a&from_search=b", http.StatusNotFound, apiNotFound},
+		{"/movie/not-a-movie", http.StatusBadRequest, ""},
+		{"/movie/the-matrix-603?from_search=a&from_search=b", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		rec := get(app, tt.target)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.target, rec.Code, tt.status)
+			continue
+		}
+		if tt.code == "" {
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+				t.Errorf("%s: Content-Type %q, want text/plain", tt.target, ct)
+			}
+			continue
+		}
+		var body apiErrorBody
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error.Code != tt.code {
+			t.Errorf("%s: error code %q (%v), want %q", tt.target, body.Error.Code, err, tt.code)
+		}
+	}
+}
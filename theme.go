@@ -0,0 +1,60 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// themeCookie holds the visitor's color theme: "light", "dark" or "auto".
+const themeCookie = "theme"
+
+// themes are the accepted theme cookie values. "auto" follows the system
+// setting through prefers-color-scheme.
+var themes = []string{"auto", "light", "dark"}
+
+// baseStylesheet is the stylesheet included by every page. The colors are
+// custom properties with light values by default and dark values when the
+// system prefers a dark scheme. A data-theme attribute on <html>, set from
+// the theme cookie, overrides the system setting either way. Lazily loaded
+// posters sit on the border color until they arrive, rather than flashing
+// the page background.
+const baseStylesheet = template.HTML(`<style>
+    :root {
+        --background: #fff; --text: #222; --link: #0645ad; --card-bg: #f6f6f6; --border: #ddd;
+        color-scheme: light;
+    }
+    @media (prefers-color-scheme: dark) {
+        :root:not([data-theme="light"]) {
+            --background: #121212; --text: #e4e4e4; --link: #8ab4f8; --card-bg: #1e1e1e; --border: #3a3a3a;
+            color-scheme: dark;
+        }
+    }
+    [data-theme="dark"] {
+        --background: #121212; --text: #e4e4e4; --link: #8ab4f8; --card-bg: #1e1e1e; --border: #3a3a3a;
+        color-scheme: dark;
+    }
+    body { background: var(--background); color: var(--text); }
+    a { color: var(--link); }
+    article, details, fieldset { background: var(--card-bg); border: 1px solid var(--border); }
+    img[loading="lazy"] { background: var(--border); }
+    .rating { border-left: .3em solid var(--border); padding-left: .4em; }
+    .rating-high { border-left-color: #2e9d4a; }
+    .rating-medium { border-left-color: #e0a800; }
+    .rating-low { border-left-color: #d0342c; }
+</style>`)
+
+// stylesheet renders baseStylesheet; pages include it with {{stylesheet}}.
+func stylesheet() template.HTML {
+	return baseStylesheet
+}
+
+// theme returns the visitor's explicit theme choice, "light" or "dark", or
+// "" to follow the system setting. Pages that depend on it must send
+// Vary: Cookie.
+func theme(r *http.Request) string {
+	cookie, err := r.Cookie(themeCookie)
+	if err != nil || (cookie.Value != "light" && cookie.Value != "dark") {
+		return ""
+	}
+	return cookie.Value
+}
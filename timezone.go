@@ -0,0 +1,28 @@
+package main
+
+import (
+	"time"
+	_ "time/tzdata" // TIMEZONE must load in containers without a zoneinfo database
+)
+
+// loadTimezone returns the IANA time zone named by TIMEZONE, or the server's
+// local zone when name is empty.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// timeNow is the clock localNow reads. Tests set it to a fixed instant.
+var timeNow = time.Now
+
+// localNow returns the current time in config's TIMEZONE. Date boundaries
+// such as "today" and "this year" are taken from it, so they move at
+// midnight there rather than on the server's clock.
+func localNow(config Config) time.Time {
+	if config.Timezone == nil {
+		return timeNow()
+	}
+	return timeNow().In(config.Timezone)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setClock makes localNow read instant until the test ends.
+func setClock(t *testing.T, instant time.Time) {
+	t.Helper()
+	saved := timeNow
+	timeNow = func() time.Time { return instant }
+	t.Cleanup(func() { timeNow = saved })
+}
+
+// mustLoadTimezone loads an IANA zone from the embedded database.
+func mustLoadTimezone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := loadTimezone(name)
+	if err != nil {
+		t.Fatalf("loadTimezone(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestLocalNow(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		instant  string // UTC
+		today    string
+		thisYear int
+	}{
+		{"UTC", "UTC", "2024-12-31T23:59:59Z", "2024-12-31", 2024},
+		{"new year in Sydney before UTC", "Australia/Sydney", "2024-12-31T12:59:59Z", "2024-12-31", 2024},
+		{"midnight in Sydney", "Australia/Sydney", "2024-12-31T13:00:00Z", "2025-01-01", 2025},
+		{"new year in New York after UTC", "America/New_York", "2025-01-01T04:59:59Z", "2024-12-31", 2024},
+		{"midnight in New York", "America/New_York", "2025-01-01T05:00:00Z", "2025-01-01", 2025},
+		// New York springs forward at 02:00 EST on 10 March 2024.
+		{"before spring forward", "America/New_York", "2024-03-10T06:59:59Z", "2024-03-10", 2024},
+		{"after spring forward", "America/New_York", "2024-03-10T07:00:00Z", "2024-03-10", 2024},
+		{"last second of the short day", "America/New_York", "2024-03-11T03:59:59Z", "2024-03-10", 2024},
+		{"midnight after the short day", "America/New_York", "2024-03-11T04:00:00Z", "2024-03-11", 2024},
+		// Sydney falls back at 03:00 AEDT on 7 April 2024.
+		{"last second of the long day", "Australia/Sydney", "2024-04-07T13:59:59Z", "2024-04-07", 2024},
+		{"midnight after the long day", "Australia/Sydney", "2024-04-07T14:00:00Z", "2024-04-08", 2024},
+		// The same instant on both sides of the date line.
+		{"date line, UTC+14", "Pacific/Kiritimati", "2024-12-31T10:00:00Z", "2025-01-01", 2025},
+		{"date line, UTC-11", "Pacific/Pago_Pago", "2024-12-31T10:00:00Z", "2024-12-30", 2024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instant, err := time.Parse(time.RFC3339, tt.instant)
+			if err != nil {
+				t.Fatal(err)
+			}
+			setClock(t, instant)
+
+			now := localNow(Config{Timezone: mustLoadTimezone(t, tt.timezone)})
+			if got := now.Format(releaseDateLayout); got != tt.today {
+				t.Errorf("today = %s, want %s", got, tt.today)
+			}
+			if now.Year() != tt.thisYear {
+				t.Errorf("this year = %d, want %d", now.Year(), tt.thisYear)
+			}
+			if !now.Equal(instant) {
+				t.Errorf("localNow moved the instant to %s", now)
+			}
+		})
+	}
+}
+
+func TestBestYearFollowsTimezone(t *testing.T) {
+	// Already 2025 in Sydney, still 2024 in UTC.
+	setClock(t, time.Date(2024, 12, 31, 13, 30, 0, 0, time.UTC))
+	fake := newFakeTMDB(t, map[string]string{"/discover/movie": searchMatrixJSON})
+
+	tests := []struct {
+		timezone string
+		status   int
+		latest   string // the first "Best of" link on the home page
+	}{
+		{"Australia/Sydney", http.StatusOK, "/best/2024"},
+		{"UTC", http.StatusBadRequest, "/best/2023"},
+	}
+	for _, tt := range tests {
+		t.Setenv("TIMEZONE", tt.timezone)
+		app := newTestApp(t, fake)
+		if rec := get(app, "/best/2025"); rec.Code != tt.status {
+			t.Errorf("%s: GET /best/2025 = %d, want %d", tt.timezone, rec.Code, tt.status)
+		}
+		home := get(app, "/").Body.String()
+		if i := strings.Index(home, `href="/best/`); i < 0 || !strings.HasPrefix(home[i+len(`href="`):], tt.latest+`"`) {
+			t.Errorf("%s: home page does not link %s first", tt.timezone, tt.latest)
+		}
+	}
+}
+
+func TestCalendarTodayFollowsTimezone(t *testing.T) {
+	collection := &Collection{ID: 1, Name: "Saga", Parts: []Movie{
+		{ID: 10, Title: "Yesterday Abroad", ReleaseDate: parseReleaseDate("2024-12-30")},
+		{ID: 11, Title: "New Year's Eve", ReleaseDate: parseReleaseDate("2024-12-31")},
+		{ID: 12, Title: "New Year's Day", ReleaseDate: parseReleaseDate("2025-01-01")},
+	}}
+	instant := time.Date(2024, 12, 31, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		timezone string
+		want     []string
+	}{
+		{"Pacific/Kiritimati", []string{"movie-12@"}},
+		{"UTC", []string{"movie-11@", "movie-12@"}},
+		{"Pacific/Pago_Pago", []string{"movie-10@", "movie-11@", "movie-12@"}},
+	}
+	for _, tt := range tests {
+		var cal bytes.Buffer
+		writeCollectionCalendar(&cal, collection, URLBuilder{}, instant.In(mustLoadTimezone(t, tt.timezone)))
+		if got := strings.Count(cal.String(), "BEGIN:VEVENT"); got != len(tt.want) {
+			t.Errorf("%s: %d events, want %d", tt.timezone, got, len(tt.want))
+		}
+		for _, uid := range tt.want {
+			if !strings.Contains(cal.String(), uid) {
+				t.Errorf("%s: calendar is missing %s", tt.timezone, uid)
+			}
+		}
+	}
+}
+
+func TestLoadTimezone(t *testing.T) {
+	if loc, err := loadTimezone(""); err != nil || loc != time.Local {
+		t.Errorf(`loadTimezone("") = %v, %v, want the server's zone`, loc, err)
+	}
+	for _, name := range []string{"Australia/Sydney", "UTC", "America/Argentina/Buenos_Aires"} {
+		if _, err := loadTimezone(name); err != nil {
+			t.Errorf("loadTimezone(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"Mars/Olympus_Mons", "australia/sydney ", "+10:00"} {
+		if _, err := loadTimezone(name); err == nil {
+			t.Errorf("loadTimezone(%q) accepted an unknown zone", name)
+		}
+	}
+}
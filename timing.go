@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimingRecorder collects named durations for the Server-Timing header.
+type TimingRecorder struct {
+	mu     sync.Mutex
+	events []timingEvent
+}
+
+type timingEvent struct {
+	name     string
+	duration time.Duration
+}
+
+type timingContextKey struct{}
+
+// WithTiming attaches a new TimingRecorder to the context.
+func WithTiming(ctx context.Context) (context.Context, *TimingRecorder) {
+	rec := &TimingRecorder{}
+	return context.WithValue(ctx, timingContextKey{}, rec), rec
+}
+
+// RecordTiming records the time elapsed since start under name on the
+// context's recorder. It does nothing when the context has no recorder.
+func RecordTiming(ctx context.Context, name string, start time.Time) {
+	if rec, ok := ctx.Value(timingContextKey{}).(*TimingRecorder); ok {
+		rec.Record(name, time.Since(start))
+	}
+}
+
+// Record adds a named duration.
+func (t *TimingRecorder) Record(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, timingEvent{name: name, duration: d})
+}
+
+// String formats the recorded events as a Server-Timing header value,
+// e.g. "tmdb_search;dur=142, template_render;dur=8".
+func (t *TimingRecorder) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parts := make([]string, len(t.events))
+	for i, e := range t.events {
+		parts[i] = fmt.Sprintf("%s;dur=%d", e.name, e.duration.Milliseconds())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// timingWriter adds the Server-Timing header just before the response
+// headers are sent, so it includes everything recorded up to that point.
+type timingWriter struct {
+	http.ResponseWriter
+	rec         *TimingRecorder
+	wroteHeader bool
+}
+
+func (tw *timingWriter) WriteHeader(status int) {
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		if timings := tw.rec.String(); timings != "" {
+			tw.Header().Set("Server-Timing", timings)
+		}
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *timingWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// timingMiddleware gives every request a TimingRecorder and emits its
+// events as a Server-Timing header.
+func timingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, rec := WithTiming(r.Context())
+		next.ServeHTTP(&timingWriter{ResponseWriter: w, rec: rec}, r.WithContext(ctx))
+	})
+}
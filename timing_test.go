@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTimingMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string // Server-Timing pattern, "" for no header
+	}{
+		{"no timings", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "ok")
+		}, ""},
+		{"written body", func(w http.ResponseWriter, r *http.Request) {
+			RecordTiming(r.Context(), "tmdb_search", time.Now().Add(-142*time.Millisecond))
+			RecordTiming(r.Context(), "template_render", time.Now())
+			io.WriteString(w, "ok")
+		}, `^tmdb_search;dur=14\d, template_render;dur=0$`},
+		{"explicit status", func(w http.ResponseWriter, r *http.Request) {
+			RecordTiming(r.Context(), "tmdb_movie", time.Now())
+			w.WriteHeader(http.StatusNotFound)
+			RecordTiming(r.Context(), "too_late", time.Now())
+		}, `^tmdb_movie;dur=0$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			timingMiddleware(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			got, ok := rec.Header()["Server-Timing"]
+			if tt.want == "" {
+				if ok {
+					t.Errorf("Server-Timing = %q, want none", got)
+				}
+				return
+			}
+			if len(got) != 1 || !regexp.MustCompile(tt.want).MatchString(got[0]) {
+				t.Errorf("Server-Timing = %q, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordTimingWithoutRecorder(t *testing.T) {
+	// Background jobs call TMDB without a request; that must not panic.
+	RecordTiming(context.Background(), "tmdb_popular", time.Now())
+}
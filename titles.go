@@ -0,0 +1,109 @@
+package main
+
+import (
+	"html/template"
+	"strings"
+	"unicode"
+)
+
+// defaultMaxTitleLength is the number of runes after which titles are
+// shortened in result lists.
+const defaultMaxTitleLength = 60
+
+const (
+	ellipsis         = '…'
+	zeroWidthJoiner  = '‍'
+	skinToneModifier = 0x1F3FB
+)
+
+// displayTitle shortens titles longer than maxRunes by cutting out the
+// middle, keeping the start and the end (which often carries a subtitle or
+// sequel number). The cut never separates a character from its combining
+// marks or splits an emoji sequence, so the result may be a rune or two
+// longer than maxRunes. A maxRunes of zero or less disables truncation.
+func displayTitle(title string, maxRunes int) string {
+	runes := []rune(title)
+	if maxRunes <= 0 || len(runes) <= maxRunes {
+		return title
+	}
+
+	keep := maxRunes - 1 // room for the ellipsis
+	head := (keep + 1) / 2
+	tail := len(runes) - keep/2
+
+	// Pull anything that belongs to the last kept character into the head...
+	for head > 0 && head < tail && (isExtender(runes[head]) || runes[head-1] == zeroWidthJoiner) {
+		head++
+	}
+	// ...and drop anything at the start of the tail that belongs to a cut character.
+	for tail < len(runes) && (isExtender(runes[tail]) || runes[tail-1] == zeroWidthJoiner) {
+		tail++
+	}
+
+	return string(runes[:head]) + string(ellipsis) + string(runes[tail:])
+}
+
+// titleTruncated reports whether displayTitle would shorten the title.
+func titleTruncated(title string, maxRunes int) bool {
+	return displayTitle(title, maxRunes) != title
+}
+
+// isExtender reports whether r attaches to the preceding character rather
+// than standing on its own: combining marks, joiners, variation selectors
+// and emoji skin tone modifiers.
+func isExtender(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me) ||
+		r == zeroWidthJoiner ||
+		unicode.Is(unicode.Variation_Selector, r) ||
+		(r >= skinToneModifier && r <= skinToneModifier+4)
+}
+
+// highlight escapes title and wraps every case-insensitive occurrence of the
+// query's words in <mark> tags. Overlapping or adjacent matches are merged
+// into a single <mark>. Only the <mark> tags are trusted markup; everything
+// from the title is escaped.
+func highlight(title string, query string) template.HTML {
+	text := []rune(title)
+	lower := lowerRunes(title)
+
+	// marked[i] is true for every rune that is part of a match.
+	marked := make([]bool, len(text))
+	for _, term := range strings.Fields(query) {
+		needle := lowerRunes(term)
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == string(needle) {
+				for j := i; j < i+len(needle); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		segment := template.HTMLEscapeString(string(text[i:j]))
+		if marked[i] {
+			b.WriteString("<mark>" + segment + "</mark>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	return template.HTML(b.String())
+}
+
+// lowerRunes lowercases s one rune at a time. Unlike strings.ToLower it
+// never changes the number of runes, so index i of the result is rune i of
+// s, and folding the title and the query the same way means they match
+// whatever their case.
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
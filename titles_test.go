@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDisplayTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		maxRunes int
+		want     string
+	}{
+		{"short", "Heat", 10, "Heat"},
+		{"exact length", "Amélie", 6, "Amélie"},
+		{"exact length multi-byte", "千と千尋の神隠し", 8, "千と千尋の神隠し"},
+		{"disabled", "The Lord of the Rings: The Return of the King", 0, "The Lord of the Rings: The Return of the King"},
+		{"negative disables", "The Lord of the Rings", -1, "The Lord of the Rings"},
+		{"ascii", "The Lord of the Rings: The Return of the King", 10, "The L…King"},
+		{"cyrillic", "Амели с Монмартра", 9, "Амел…ртра"},
+		{"cjk", "千と千尋の神隠し", 5, "千と…隠し"},
+		{"one rune over", "Amélie", 5, "Am…ie"},
+		{"max 1", "Amélie", 1, "…"},
+		{"max 2", "Amélie", 2, "A…"},
+		{"max 2 multi-byte", "千と千尋の神隠し", 2, "千…"},
+		{"combining mark kept with head", "Cafe\u0301 Noir Club", 8, "Cafe\u0301…lub"},
+		{"combining mark kept with tail", "Club Noir Cafe\u0301", 5, "Cl…e\u0301"},
+		{"skin tone kept with emoji", "Thumbs 👍🏽 Up for the Weekend", 16, "Thumbs 👍🏽…Weekend"},
+		{"zwj sequence kept whole", "Family 👨‍👩‍👧‍👦 Reunion", 16, "Family 👨‍👩‍👧‍👦…Reunion"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := displayTitle(tt.title, tt.maxRunes); got != tt.want {
+				t.Errorf("displayTitle(%q, %d) = %q, want %q", tt.title, tt.maxRunes, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDisplayTitleNeverSplits shortens titles full of multi-rune
+// characters to every length and checks each cut lands between whole
+// characters.
+func TestDisplayTitleNeverSplits(t *testing.T) {
+	titles := []string{
+		"Cafe\u0301 Society and the Cre\u0300me Bru\u0302le\u0301e",
+		"Family 👨‍👩‍👧‍👦 Reunion 👍🏽 Special 🏳️‍🌈",
+		"Amélie 千と千尋の神隠し",
+	}
+	for _, title := range titles {
+		for maxRunes := 1; maxRunes <= utf8.RuneCountInString(title); maxRunes++ {
+			got := displayTitle(title, maxRunes)
+			if !utf8.ValidString(got) {
+				t.Fatalf("displayTitle(%q, %d) = %q, not valid UTF-8", title, maxRunes, got)
+			}
+			if got == title {
+				continue
+			}
+			head, tail, ok := strings.Cut(got, string(ellipsis))
+			if !ok {
+				t.Fatalf("displayTitle(%q, %d) = %q, no ellipsis", title, maxRunes, got)
+			}
+			if !strings.HasPrefix(title, head) || !strings.HasSuffix(title, tail) {
+				t.Fatalf("displayTitle(%q, %d) = %q, not a start and end of the title", title, maxRunes, got)
+			}
+			if r, _ := utf8.DecodeLastRuneInString(head); r == zeroWidthJoiner {
+				t.Errorf("displayTitle(%q, %d) = %q, head ends in a joiner", title, maxRunes, got)
+			}
+			if r, _ := utf8.DecodeRuneInString(tail); tail != "" && isExtender(r) {
+				t.Errorf("displayTitle(%q, %d) = %q, tail starts with %U", title, maxRunes, got, r)
+			}
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name, title, query string
+		want               string
+	}{
+		{"no query", "Tom & Jerry", "", "Tom &amp; Jerry"},
+		{"no match", "The Matrix", "dune", "The Matrix"},
+		{"ignores case", "The Matrix", "MATRIX", "The <mark>Matrix</mark>"},
+		{"every word", "The Matrix Reloaded", "matrix the", "<mark>The</mark> <mark>Matrix</mark> Reloaded"},
+		{"every occurrence", "Tora! Tora! Tora!", "tora", "<mark>Tora</mark>! <mark>Tora</mark>! <mark>Tora</mark>!"},
+		{"overlapping matches merge", "Banana", "an", "B<mark>anan</mark>a"},
+		{"metacharacters in the title", `<Script> "Tom's"`, "script", `&lt;<mark>Script</mark>&gt; &#34;Tom&#39;s&#34;`},
+		{"metacharacters in the query", "Tom & Jerry", "& <b>", "Tom <mark>&amp;</mark> Jerry"},
+		{"accents", "Amélie", "AMÉLIE", "<mark>Amélie</mark>"},
+		{"dotted capital I in the title", "İstanbul", "istanbul", "<mark>İstanbul</mark>"},
+		{"dotted capital I in the query", "Istanbul Kırmızısı", "İST", "<mark>Ist</mark>anbul Kırmızısı"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(highlight(tt.title, tt.query)); got != tt.want {
+				t.Errorf("highlight(%q, %q) = %q, want %q", tt.title, tt.query, got, tt.want)
+			}
+		})
+	}
+}
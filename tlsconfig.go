@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// tlsVersions are the TLS versions accepted for TLS_MIN_VERSION. TLS 1.0
+// and 1.1 are deliberately missing.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// defaultTLSMinVersion is used when TLS_MIN_VERSION is unset.
+const defaultTLSMinVersion = "1.2"
+
+// newTLSConfig builds the server's TLS settings from TLS_MIN_VERSION and
+// TLS_CIPHER_SUITES. An empty cipher list keeps Go's defaults, which only
+// include secure suites. Named suites must be ones Go considers secure;
+// they apply to TLS 1.2 only, as TLS 1.3 suites are not configurable.
+func newTLSConfig(minVersion string, cipherSuites string) (*tls.Config, error) {
+	if minVersion == "" {
+		minVersion = defaultTLSMinVersion
+	}
+	version, ok := tlsVersions[minVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported minimum TLS version %q (use 1.2 or 1.3)", minVersion)
+	}
+	config := &tls.Config{MinVersion: version}
+
+	secure := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+	for _, name := range strings.Split(cipherSuites, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := secure[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		config.CipherSuites = append(config.CipherSuites, id)
+	}
+	return config, nil
+}
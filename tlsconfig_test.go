@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	const (
+		ecdheRSA   = "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
+		ecdheECDSA = "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"
+	)
+	tests := []struct {
+		name, minVersion, cipherSuites string
+		wantVersion                    uint16
+		wantSuites                     []uint16
+	}{
+		{"defaults", "", "", tls.VersionTLS12, nil},
+		{"1.2", "1.2", "", tls.VersionTLS12, nil},
+		{"1.3", "1.3", "", tls.VersionTLS13, nil},
+		{"one suite", "", ecdheRSA, tls.VersionTLS12, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}},
+		{"suites keep their order", "", " " + ecdheECDSA + " , " + ecdheRSA + ",",
+			tls.VersionTLS12, []uint16{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := newTLSConfig(tt.minVersion, tt.cipherSuites)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.MinVersion != tt.wantVersion || !slices.Equal(config.CipherSuites, tt.wantSuites) {
+				t.Errorf("newTLSConfig(%q, %q) = version %x, suites %x; want %x, %x",
+					tt.minVersion, tt.cipherSuites, config.MinVersion, config.CipherSuites, tt.wantVersion, tt.wantSuites)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfigRejects(t *testing.T) {
+	tests := []struct {
+		name, minVersion, cipherSuites string
+		wantErr                        string
+	}{
+		{"TLS 1.0", "1.0", "", `"1.0"`},
+		{"TLS 1.1", "1.1", "", `"1.1"`},
+		{"unknown version", "tls1.3", "", `"tls1.3"`},
+		{"unknown suite", "", "TLS_FAST_AND_LOOSE", `"TLS_FAST_AND_LOOSE"`},
+		{"insecure suite", "", "TLS_RSA_WITH_RC4_128_SHA", `"TLS_RSA_WITH_RC4_128_SHA"`},
+		{"lower case suite", "", "tls_ecdhe_rsa_with_aes_128_gcm_sha256", "tls_ecdhe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := newTLSConfig(tt.minVersion, tt.cipherSuites)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newTLSConfig(%q, %q) = %v, %v; want an error naming %s", tt.minVersion, tt.cipherSuites, config, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSConfigRefusesOldClients(t *testing.T) {
+	config, err := newTLSConfig("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = config
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // the refused handshakes
+	server.StartTLS()
+	defer server.Close()
+
+	for _, version := range []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12} {
+		client := server.Client()
+		transport := client.Transport.(*http.Transport)
+		transport.TLSClientConfig.MinVersion = version
+		transport.TLSClientConfig.MaxVersion = version
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if accepted := err == nil; accepted != (version >= tls.VersionTLS12) {
+			t.Errorf("TLS %s client: %v", tls.VersionName(version), err)
+		}
+	}
+}
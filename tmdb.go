@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// version identifies this build in outbound requests. Release builds set it
+// with -ldflags "-X main.version=1.2.3".
+var version = "dev"
+
+// defaultTMDBTimeout bounds a TMDB call unless TMDB_TIMEOUT says otherwise.
+const defaultTMDBTimeout = 10 * time.Second
+
+// TMDB is the app's access to the TMDB API: the key, where the API is, the
+// client every call goes through and the caches in front of them. Each App
+// has its own, so apps in one process, as in tests, share no TMDB data.
+type TMDB struct {
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+
+	missingMovies   negativeCache // movie IDs TMDB answered 404 for
+	emptySearches   negativeCache // searches TMDB had no results for
+	imdb            imdbCache
+	popularStrip    popularStripCache
+	cinema          cinemaCache
+	collectionIndex collectionIndexCache
+	runtimeShelf    runtimeShelfCache
+	runtimes        runtimeCache
+
+	// justWatchNames isn't TMDB data, but lives with the other upstream
+	// caches so the app owns it.
+	justWatchNames justWatchNamesCache
+}
+
+// newTMDB returns a TMDB for config.APIKey at config.TMDBBaseURL, with
+// every call bounded by config.TMDBTimeout and made through transport, and
+// empty caches.
+func newTMDB(config Config, transport http.RoundTripper) *TMDB {
+	return &TMDB{
+		APIKey:        config.APIKey,
+		BaseURL:       config.TMDBBaseURL,
+		Client:        &http.Client{Transport: transport, Timeout: config.TMDBTimeout},
+		missingMovies: negativeCache{name: "negative_cache_movie"},
+		emptySearches: negativeCache{name: "negative_cache_search"},
+	}
+}
+
+// TimingTransport decorates outbound TMDB requests with our User-Agent and
+// the caller's request ID, and logs how long each call took.
+type TimingTransport struct {
+	// Base is the underlying transport; nil means http.DefaultTransport.
+	Base http.RoundTripper
+	// Log gets a Debug record per call with its path, status and duration;
+	// nil logs nothing. NewApp passes the sampled application logger.
+	Log *slog.Logger
+	// Audit, when set, gets one record per call for usage tracking (see
+	// auditTMDBCall).
+	Audit *slog.Logger
+}
+
+func (t *TimingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request.
+	out := req.Clone(req.Context())
+	out.Header.Set("User-Agent", "movie-finder/"+version)
+	if id := requestIDFromContext(req.Context()); id != "" {
+		out.Header.Set("X-Request-ID", id)
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(out)
+	if t.Log != nil {
+		attrs := []slog.Attr{slog.String("path", req.URL.Path), slog.Int64("duration_ms", time.Since(start).Milliseconds())}
+		if resp != nil {
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		}
+		t.Log.LogAttrs(req.Context(), slog.LevelDebug, "tmdb request", attrs...)
+	}
+	if t.Audit != nil {
+		auditTMDBCall(t.Audit, req, resp, err, time.Since(start))
+	}
+	return resp, err
+}
+
+// auditTMDBCall logs one TMDB call to the audit log: the endpoint, its
+// parameters without the API key, the status, the duration and whether
+// TMDB's CDN served it from cache (its X-Cache header). Calls answered by
+// our own caches never reach the transport, so every record is one request
+// counted against the rate limit. Failed calls are logged at Warn level, so
+// TMDB_AUDIT_LOG_LEVEL=warn keeps only those.
+func auditTMDBCall(logger *slog.Logger, req *http.Request, resp *http.Response, err error, duration time.Duration) {
+	params := req.URL.Query()
+	apiKey := params.Get("api_key")
+	params.Del("api_key")
+	attrs := []slog.Attr{
+		slog.String("endpoint", req.URL.Path),
+		slog.String("params", params.Encode()),
+		slog.Int64("duration_ms", duration.Milliseconds()),
+		slog.String("request_id", requestIDFromContext(req.Context())),
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		message := err.Error()
+		if apiKey != "" {
+			// Transport errors may quote the URL, key included.
+			message = strings.ReplaceAll(message, apiKey, "REDACTED")
+		}
+		attrs = append(attrs, slog.String("error", message))
+	}
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode), slog.String("cdn_cache", resp.Header.Get("X-Cache")))
+		if resp.StatusCode >= 400 {
+			level = slog.LevelWarn
+		}
+	}
+	logger.LogAttrs(req.Context(), level, "tmdb call", attrs...)
+}
+
+// withDeadline bounds r's context by timeout, so every TMDB call made while
+// serving it gives up once the handler's latency budget is spent. The
+// budget can only shorten the TMDB client's own per-call timeout, not
+// extend it.
+// A timeout of 0 leaves r unchanged.
+func withDeadline(r *http.Request, timeout time.Duration) (*http.Request, context.CancelFunc) {
+	if timeout <= 0 {
+		return r, func() {}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return r.WithContext(ctx), cancel
+}
+
+// withTMDBTimeout derives the context for one TMDB call, bounded by
+// timeout, the TMDB client's own timeout. Unlike the client's timeout, the
+// deadline is carried by the context, so anything waiting on the call sees
+// it too. An earlier deadline on ctx, such as a handler's withDeadline
+// budget, still wins.
+func withTMDBTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// tmdbGet fetches a TMDB URL with tmdb's client and decodes the JSON
+// response into v. Every fetchX function goes through it, so each call gets
+// its own withTMDBTimeout deadline.
+func tmdbGet(ctx context.Context, tmdb *TMDB, requestURL string, v interface{}) error {
+	ctx, cancel := withTMDBTimeout(ctx, tmdb.Client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := tmdb.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Other error statuses come with a JSON error object, which decodes to
+	// an empty result; 503 means TMDB is down for maintenance. So does a
+	// body that isn't JSON at all, whatever the status: that is the HTML
+	// error page of a CDN in front of TMDB, not an answer from the API.
+	body := bufio.NewReader(resp.Body)
+	if resp.StatusCode == http.StatusServiceUnavailable || !looksLikeJSON(body) {
+		return newTMDBMaintenanceError(resp, body)
+	}
+
+	return json.NewDecoder(body).Decode(v)
+}
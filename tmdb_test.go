@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// waitForCancellations waits up to a few seconds for fake to see n
+// abandoned requests; the server notices a closed connection a moment
+// after the client gives up.
+func waitForCancellations(t *testing.T, fake *fakeTMDB, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if fake.cancellations() >= n {
+			return
+		}
+	}
+	t.Errorf("TMDB saw %d cancelled requests, want %d", fake.cancellations(), n)
+}
+
+func TestTMDBGetStopsWithContext(t *testing.T) {
+	t.Setenv("TMDB_TIMEOUT", "1m")
+	fake := newFakeTMDB(t, nil)
+	fake.handle("/movie/603", fakeResponse{Body: movieMatrixJSON, Delay: time.Minute})
+	newTestApp(t, fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	var movie MovieDetail
+	err := tmdbGet(ctx, fake.tmdb(), fake.URL+"/movie/603", &movie)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("tmdbGet = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("tmdbGet returned after %s, want right after the cancel", elapsed)
+	}
+	waitForCancellations(t, fake, 1)
+}
+
+func TestHandlerStopsWhenClientGoesAway(t *testing.T) {
+	t.Setenv("TMDB_TIMEOUT", "1m")
+	t.Setenv("DETAIL_TIMEOUT", "1m")
+	fake := newFakeTMDB(t, nil)
+	fake.handle("/movie/603", fakeResponse{Body: movieMatrixJSON, Delay: time.Minute})
+	app := newTestApp(t, fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/movie/the-matrix-603", nil).WithContext(ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	done := make(chan struct{})
+	go func() {
+		app.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler still waiting on TMDB after the request was cancelled")
+	}
+	waitForCancellations(t, fake, 1)
+}
+
+func TestWithTMDBTimeout(t *testing.T) {
+	tests := []struct {
+		name          string
+		clientTimeout time.Duration
+		parent        time.Duration // 0 for a parent without deadline
+		want          time.Duration // 0 for no deadline
+	}{
+		{"client timeout", 10 * time.Second, 0, 10 * time.Second},
+		{"earlier parent deadline wins", 10 * time.Second, time.Second, time.Second},
+		{"later parent deadline loses", time.Second, time.Minute, time.Second},
+		{"no client timeout", 0, 0, 0},
+		{"no client timeout keeps parent deadline", 0, time.Second, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := context.Background()
+			if tt.parent > 0 {
+				var cancel context.CancelFunc
+				parent, cancel = context.WithTimeout(parent, tt.parent)
+				defer cancel()
+			}
+
+			ctx, cancel := withTMDBTimeout(parent, tt.clientTimeout)
+			deadline, ok := ctx.Deadline()
+			if ok != (tt.want > 0) {
+				t.Fatalf("deadline set: %v, want %v", ok, tt.want > 0)
+			}
+			if ok {
+				if remaining := time.Until(deadline); remaining > tt.want || remaining < tt.want-time.Second {
+					t.Errorf("deadline in %s, want %s", remaining, tt.want)
+				}
+			}
+
+			cancel()
+			if ctx.Err() == nil {
+				t.Error("cancel did not end the context")
+			}
+			if parent.Err() != nil {
+				t.Error("cancel ended the parent context")
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tmdbNotFoundBody is TMDB's answer for a resource that doesn't exist.
+const tmdbNotFoundBody = `{"success":false,"status_code":34,"status_message":"The resource you requested could not be found."}`
+
+// Responses shared by the end-to-end tests, read from the fixtures.
+var (
+	searchMatrixJSON = readFixture("search_movie")
+	movieMatrixJSON  = readFixture("movie_detail") // with every sub-resource in detailPageAppends
+)
+
+// fixturePaths maps the TMDB paths the fixtures in testdata/tmdb answer to
+// their names. go run ./cmd/seed-testdata/ refreshes the fixtures from the
+// real API. Where several fixtures share a path, such as /discover/movie,
+// the one listed here answers every query.
+var fixturePaths = map[string]string{
+	"/search/movie":                 "search_movie",
+	"/movie/603":                    "movie_detail",
+	"/movie/603/images":             "movie_images",
+	"/find/tt0133093":               "find_imdb",
+	"/search/person":                "search_person",
+	"/person/6384":                  "person",
+	"/person/6384/combined_credits": "person_combined_credits",
+	"/trending/movie/week":          "trending_movie_week",
+	"/movie/popular":                "movie_popular",
+	"/discover/movie":               "discover_cinema",
+	"/collection/2344":              "collection",
+	"/company/174":                  "company",
+	"/movie/top_rated":              "movie_top_rated",
+	"/configuration":                "configuration",
+}
+
+// readFixture returns the TMDB response saved in testdata/tmdb/{name}.json.
+func readFixture(name string) string {
+	data, err := os.ReadFile(filepath.Join("testdata", "tmdb", name+".json"))
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// fakeResponse is what the fake TMDB answers for one path.
+type fakeResponse struct {
+	Status      int    // 200 when zero
+	ContentType string // application/json when empty
+	Header      http.Header
+	Body        string
+	// Delay holds the response back this long, or until the client gives
+	// up on the request.
+	Delay time.Duration
+}
+
+// fakeTMDB is a stand-in for the TMDB API that serves canned responses by
+// path, such as "/search/movie" or "/movie/603", ignoring the query.
+// Unknown paths get TMDB's not-found error object.
+type fakeTMDB struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]fakeResponse
+	requests  []string              // paths served, in order
+	queries   map[string]url.Values // the last query served for each path
+	cancelled int                   // requests the client gave up on during a Delay
+}
+
+// newFakeTMDB starts a fake TMDB answering each path in bodies with its
+// JSON body. It is closed when the test ends.
+func newFakeTMDB(t testing.TB, bodies map[string]string) *fakeTMDB {
+	t.Helper()
+	fake := &fakeTMDB{responses: map[string]fakeResponse{}, queries: map[string]url.Values{}}
+	for path, body := range bodies {
+		fake.responses[path] = fakeResponse{Body: body}
+	}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+// newFixtureTMDB starts a fake TMDB answering every path in fixturePaths
+// with its fixture.
+func newFixtureTMDB(t testing.TB) *fakeTMDB {
+	t.Helper()
+	bodies := make(map[string]string, len(fixturePaths))
+	for path, name := range fixturePaths {
+		bodies[path] = readFixture(name)
+	}
+	return newFakeTMDB(t, bodies)
+}
+
+// handle sets the response for path.
+func (f *fakeTMDB) handle(path string, response fakeResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[path] = response
+}
+
+// calls returns how many requests for path the fake has served, or for
+// every path when path is "".
+func (f *fakeTMDB) calls(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, served := range f.requests {
+		if path == "" || served == path {
+			n++
+		}
+	}
+	return n
+}
+
+// query returns the query of the last request for path, or nil if there
+// was none.
+func (f *fakeTMDB) query(path string) url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.queries[path]
+}
+
+// cancellations returns how many requests were abandoned by the client
+// while the fake was holding them back.
+func (f *fakeTMDB) cancellations() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cancelled
+}
+
+func (f *fakeTMDB) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.URL.Path)
+	f.queries[r.URL.Path] = r.URL.Query()
+	response, ok := f.responses[r.URL.Path]
+	f.mu.Unlock()
+	if !ok {
+		response = fakeResponse{Status: http.StatusNotFound, Body: tmdbNotFoundBody}
+	}
+
+	if response.Delay > 0 {
+		select {
+		case <-time.After(response.Delay):
+		case <-r.Context().Done():
+			f.mu.Lock()
+			f.cancelled++
+			f.mu.Unlock()
+			return
+		}
+	}
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+	if response.ContentType == "" {
+		response.ContentType = "application/json;charset=utf-8"
+	}
+	w.Header().Set("Content-Type", response.ContentType)
+	if response.Status != 0 {
+		w.WriteHeader(response.Status)
+	}
+	fmt.Fprint(w, response.Body)
+}
+
+// newTestApp returns an App configured by LoadConfig from the environment,
+// with its TMDB calls going to fake. Variables the test set beforehand with
+// t.Setenv are kept. The app is closed when the test ends.
+func newTestApp(t testing.TB, fake *fakeTMDB) *App {
+	t.Helper()
+	for name, value := range map[string]string{
+		"TMDB_API_KEY": "test-key",
+		"LOG_LEVEL":    "error",
+		"TIMEZONE":     "UTC",
+	} {
+		if _, set := os.LookupEnv(name); !set {
+			t.Setenv(name, value)
+		}
+	}
+	t.Setenv("TMDB_BASE_URL", fake.URL)
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	app, err := NewApp(config)
+	if err != nil {
+		t.Fatalf("NewApp: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := app.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	})
+	return app
+}
+
+// tmdb returns a TMDB client for the fake with empty caches, for tests of
+// single calls.
+func (f *fakeTMDB) tmdb() *TMDB {
+	return newTMDB(Config{APIKey: "test-key", TMDBBaseURL: f.URL, TMDBTimeout: defaultTMDBTimeout}, nil)
+}
+
+// get serves a GET request for target with h.
+func get(h http.Handler, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
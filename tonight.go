@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// tonightCandidates is how many movies from the chosen source are
+	// considered; each needs a detail request for its runtime.
+	tonightCandidates = 12
+	// tonightFetchConcurrency caps the detail requests in flight at once.
+	tonightFetchConcurrency = 4
+	// tonightShortlist is how many of the best-scoring schedules a seed
+	// picks from, so rerolling varies the plan. Only schedules scoring at
+	// least tonightShortlistRatio of the best one make the list, so a reroll
+	// never drops to a much worse evening.
+	tonightShortlist      = 3
+	tonightShortlistRatio = 0.75
+	// tonightMaxMinutes is the longest evening accepted.
+	tonightMaxMinutes = 12 * 60
+
+	// runtimeCacheSize bounds runtimeCache; it is emptied when full.
+	runtimeCacheSize = 5000
+)
+
+// TonightPage is the data rendered by the tonight template.
+type TonightPage struct {
+	Minutes  int     // 0 until the form is submitted
+	From     string  // "trending" or a genre ID
+	Genres   []Genre // sorted by name
+	Schedule []Movie
+	Runtimes map[int]int
+	Total    int     // minutes
+	Skipped  []Movie // candidates without a known runtime
+	NextSeed uint64
+	Meta     Meta
+	Theme    string
+}
+
+var tonightTmpl = pageTemplate("tonight", `
+<!DOCTYPE html>
+<html{{with .Theme}} data-theme="{{.}}"{{end}}>
+<head>
+    {{stylesheet}}
+    <meta name="robots" content="{{.Meta.Robots}}">
+    <title>Tonight</title>
+</head>
+<body>
+    {{template "header" ""}}
+    <h1>What to watch tonight</h1>
+    <form action="/tonight" method="GET">
+        <label>Minutes available <input type="number" name="minutes" min="1" max="720" value="{{with .Minutes}}{{.}}{{else}}180{{end}}" required></label>
+        <label>From
+            <select name="from">
+                <option value="trending"{{if eq .From "trending"}} selected{{end}}>Trending this week</option>
+                {{range .Genres}}<option value="{{.ID}}"{{if eq $.From (print .ID)}} selected{{end}}>Top rated {{.Name}}</option>
+                {{end}}
+            </select>
+        </label>
+        <button type="submit">Plan</button>
+    </form>
+    {{if .Minutes}}
+    {{with .Schedule}}
+    <h2>Your schedule</h2>
+    {{block "schedule" $}}
+    <div style="display: flex; gap: 1em; flex-wrap: wrap;">
+        {{range .Schedule}}
+        <figure style="margin: 0;">
+            <a href="{{movieURL .ID .Title}}">{{posterImg .PosterPath 185 ""}}</a>
+            <figcaption><a href="{{movieURL .ID .Title}}" dir="auto">{{.Title}}</a> ({{.ReleaseDate.DisplayYear}})<br>{{index $.Runtimes .ID}} min{{if .VoteCount}}, rated {{printf "%.1f" .VoteAverage}}/10{{end}}</figcaption>
+        </figure>
+        {{end}}
+    </div>
+    {{end}}
+    <p>Total: {{$.Total}} of {{$.Minutes}} min &middot; <a href="/tonight?minutes={{$.Minutes}}&from={{$.From}}&seed={{$.NextSeed}}">Reroll</a></p>
+    {{else}}
+    <p>Nothing fits in {{.Minutes}} minutes. Try a longer evening or another source.</p>
+    {{end}}
+    {{with .Skipped}}<p><small>Left out because their runtime isn't known: {{range $i, $movie := .}}{{if $i}}, {{end}}<span dir="auto">{{$movie.Title}}</span>{{end}}.</small></p>{{end}}
+    {{end}}
+    {{template "footer"}}
+</body>
+</html>
+`, `
+{{define "schedule"}}
+<ol>
+    {{range .Schedule}}<li><a href="{{movieURL .ID .Title}}" dir="auto">{{.Title}}</a> ({{.ReleaseDate.DisplayYear}}), {{index $.Runtimes .ID}} min{{if .VoteCount}}, rated {{printf "%.1f" .VoteAverage}}/10{{end}}</li>
+    {{end}}
+</ol>
+{{end}}
+`)
+
+// runtimeCache remembers movie runtimes, in minutes, across requests. A
+// movie's runtime practically never changes once it is known.
+type runtimeCache struct {
+	mu       sync.Mutex
+	runtimes map[int]int
+}
+
+// tonightHandler serves GET /tonight?minutes=N&from=trending|{genre}&seed=S:
+// one or two movies whose combined runtime fits in the minutes available.
+// Without minutes it only shows the form. The same seed always gives the
+// same schedule; the page links to the next seed to reroll.
+func tonightHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	if rejectDuplicateParams(w, r, "minutes", "from", "seed") {
+		return
+	}
+	r, cancel := withDeadline(r, config.DetailTimeout)
+	defer cancel()
+
+	query := r.URL.Query()
+	page := TonightPage{From: query.Get("from"), Genres: movieGenreOptions, Meta: pageMeta("/tonight"), Theme: theme(r)}
+	if page.From == "" {
+		page.From = "trending"
+	}
+	genre := 0
+	if page.From != "trending" {
+		id, err := strconv.Atoi(page.From)
+		if _, known := movieGenres[id]; err != nil || !known {
+			http.Error(w, fmt.Sprintf("unknown genre %q", page.From), http.StatusBadRequest)
+			return
+		}
+		genre = id
+	}
+	if raw := query.Get("minutes"); raw != "" {
+		minutes, err := strconv.Atoi(raw)
+		if err != nil || minutes < 1 || minutes > tonightMaxMinutes {
+			http.Error(w, fmt.Sprintf("minutes must be between 1 and %d", tonightMaxMinutes), http.StatusBadRequest)
+			return
+		}
+		page.Minutes = minutes
+	}
+	seed := rand.Uint64()
+	if raw := query.Get("seed"); raw != "" {
+		var err error
+		seed, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "seed must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+	}
+	page.NextSeed = seed + 1
+
+	if page.Minutes > 0 {
+		candidates, err := tonightCandidateMovies(r.Context(), genre, config.TMDB)
+		if err != nil {
+			log.Printf("Error fetching tonight candidates: %v", err)
+			tmdbFailure(w, r, err, "Failed to fetch movies")
+			return
+		}
+		page.Runtimes = movieRuntimes(r.Context(), candidates, config.TMDB)
+		var known []Movie
+		for _, movie := range candidates {
+			if page.Runtimes[movie.ID] > 0 {
+				known = append(known, movie)
+			} else {
+				page.Skipped = append(page.Skipped, movie)
+			}
+		}
+		page.Schedule = PlanTonight(known, page.Runtimes, page.Minutes, seed)
+		for _, movie := range page.Schedule {
+			page.Total += page.Runtimes[movie.ID]
+		}
+	}
+
+	body, err := render(r, tonightTmpl, page)
+	if err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Cookie")
+	setCacheControl(w, config, searchResponse)
+	body.WriteTo(w)
+}
+
+// tonightCandidateMovies returns the first tonightCandidates movies trending
+// this week, or the top rated of genre when it isn't 0.
+func tonightCandidateMovies(ctx context.Context, genre int, tmdb *TMDB) ([]Movie, error) {
+	start := time.Now()
+	var results *SearchResults
+	var err error
+	if genre == 0 {
+		results, err = fetchTrending(ctx, "week", tmdb)
+		RecordTiming(ctx, "tmdb_trending", start)
+	} else {
+		results, err = fetchTopRatedInGenre(ctx, genre, tmdb)
+		RecordTiming(ctx, "tmdb_discover", start)
+	}
+	if err != nil {
+		return nil, err
+	}
+	movies := results.Results
+	if len(movies) > tonightCandidates {
+		movies = movies[:tonightCandidates]
+	}
+	return movies, nil
+}
+
+// movieRuntimes returns the runtime in minutes of each movie whose runtime
+// is known, from tmdb.runtimes or otherwise from its details, fetched
+// tonightFetchConcurrency at a time. Movies that fail to load are left out.
+func movieRuntimes(ctx context.Context, movies []Movie, tmdb *TMDB) map[int]int {
+	runtimes := make(map[int]int, len(movies))
+	var missing []int
+	tmdb.runtimes.mu.Lock()
+	for _, movie := range movies {
+		if runtime, ok := tmdb.runtimes.runtimes[movie.ID]; ok {
+			runtimes[movie.ID] = runtime
+		} else {
+			missing = append(missing, movie.ID)
+		}
+	}
+	tmdb.runtimes.mu.Unlock()
+
+	start := time.Now()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, tonightFetchConcurrency)
+	for _, id := range missing {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			detail, err := fetchMovieDetails(ctx, strconv.Itoa(id), tmdb)
+			if err != nil {
+				log.Printf("Error fetching runtime for movie %d: %v", id, err)
+				return
+			}
+			mu.Lock()
+			runtimes[id] = detail.Runtime
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(missing) > 0 {
+		RecordTiming(ctx, "tmdb_detail", start)
+	}
+
+	tmdb.runtimes.mu.Lock()
+	defer tmdb.runtimes.mu.Unlock()
+	if tmdb.runtimes.runtimes == nil || len(tmdb.runtimes.runtimes) >= runtimeCacheSize {
+		tmdb.runtimes.runtimes = map[int]int{}
+	}
+	for _, id := range missing {
+		// A runtime of 0 means TMDB doesn't know it yet; don't cache that.
+		if runtime := runtimes[id]; runtime > 0 {
+			tmdb.runtimes.runtimes[id] = runtime
+		}
+	}
+	return runtimes
+}
+
+// PlanTonight picks one or two of the candidates whose runtimes add up to
+// at most minutes. Every fitting single movie and pair is scored by its
+// rated minutes, the sum of rating times runtime, which favors both good
+// movies and a well-filled evening. The seed then picks one of the best
+// schedules (see tonightShortlist), so the same inputs and seed always give
+// the same plan. Candidates without a runtime are ignored; nil means
+// nothing fits.
+func PlanTonight(candidates []Movie, runtimes map[int]int, minutes int, seed uint64) []Movie {
+	type option struct {
+		movies []Movie
+		score  float64
+	}
+	var options []option
+	for i, first := range candidates {
+		firstRuntime := runtimes[first.ID]
+		if firstRuntime <= 0 || firstRuntime > minutes {
+			continue
+		}
+		options = append(options, option{[]Movie{first}, first.VoteAverage * float64(firstRuntime)})
+		for _, second := range candidates[i+1:] {
+			secondRuntime := runtimes[second.ID]
+			if secondRuntime <= 0 || first.ID == second.ID || firstRuntime+secondRuntime > minutes {
+				continue
+			}
+			score := first.VoteAverage*float64(firstRuntime) + second.VoteAverage*float64(secondRuntime)
+			options = append(options, option{[]Movie{first, second}, score})
+		}
+	}
+	if len(options) == 0 {
+		return nil
+	}
+
+	// These are pairs ranked by a score over both movies, so they don't go
+	// through sortMovies. Stable, so equal scores keep candidate order and
+	// the result only depends on the inputs.
+	slices.SortStableFunc(options, func(a, b option) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		}
+		return 0
+	})
+	shortlist := options[:1]
+	for _, o := range options[1:min(len(options), tonightShortlist)] {
+		if o.score < options[0].score*tonightShortlistRatio {
+			break
+		}
+		shortlist = append(shortlist, o)
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	return shortlist[rng.IntN(len(shortlist))].movies
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// scheduleIDs returns the IDs of the movies in a schedule, in order.
+func scheduleIDs(schedule []Movie) []int {
+	ids := make([]int, len(schedule))
+	for i, movie := range schedule {
+		ids[i] = movie.ID
+	}
+	return ids
+}
+
+func TestPlanTonight(t *testing.T) {
+	candidates := []Movie{
+		{ID: 1, VoteAverage: 8}, // 100 min
+		{ID: 2, VoteAverage: 4}, // 100 min
+		{ID: 3, VoteAverage: 10},
+		{ID: 4, VoteAverage: 9}, // 300 min
+	}
+	runtimes := map[int]int{1: 100, 2: 100, 4: 300}
+
+	// Every case has a single schedule within tonightShortlistRatio of the
+	// best, so the seed doesn't matter.
+	tests := []struct {
+		name    string
+		minutes int
+		want    []int
+	}{
+		{"a pair fills the evening", 200, []int{1, 2}},
+		{"one movie fits", 199, []int{1}},
+		{"runtime exactly fits", 100, []int{1}},
+		{"nothing fits", 99, nil},
+		{"a long movie beats a pair", 300, []int{4}},
+	}
+	for _, tt := range tests {
+		for seed := uint64(0); seed < 20; seed++ {
+			if got := scheduleIDs(PlanTonight(candidates, runtimes, tt.minutes, seed)); !slices.Equal(got, tt.want) {
+				t.Errorf("%s: PlanTonight(%d min, seed %d) = %v, want %v", tt.name, tt.minutes, seed, got, tt.want)
+				break
+			}
+		}
+	}
+
+	if got := PlanTonight(nil, runtimes, 200, 0); got != nil {
+		t.Errorf("no candidates: %v", got)
+	}
+	if got := PlanTonight(candidates[2:3], runtimes, 200, 0); got != nil {
+		t.Errorf("only a movie without a runtime: %v", got)
+	}
+}
+
+func TestPlanTonightSeed(t *testing.T) {
+	// Three equally good single movies, and a fourth scoring below
+	// tonightShortlistRatio of them.
+	candidates := []Movie{{ID: 1, VoteAverage: 7}, {ID: 2, VoteAverage: 7}, {ID: 3, VoteAverage: 7}, {ID: 4, VoteAverage: 5}}
+	runtimes := map[int]int{1: 100, 2: 100, 3: 100, 4: 100}
+
+	picked := map[int]bool{}
+	for seed := uint64(0); seed < 100; seed++ {
+		first := scheduleIDs(PlanTonight(candidates, runtimes, 150, seed))
+		for i := 0; i < 3; i++ {
+			if again := scheduleIDs(PlanTonight(candidates, runtimes, 150, seed)); !slices.Equal(again, first) {
+				t.Fatalf("seed %d gave %v, then %v", seed, first, again)
+			}
+		}
+		if len(first) != 1 {
+			t.Fatalf("seed %d gave %v, want one movie", seed, first)
+		}
+		picked[first[0]] = true
+	}
+	if picked[4] {
+		t.Error("a reroll picked a schedule far below the best one")
+	}
+	if len(picked) != 3 {
+		t.Errorf("rerolls picked %v, want each of the three best schedules", picked)
+	}
+}
+
+func TestTonightPage(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/trending/movie/week": `{"page":1,"total_pages":1,"total_results":3,"results":[
+			{"id":603,"title":"The Matrix","release_date":"1999-03-30","vote_average":8.2,"vote_count":25000},
+			{"id":604,"title":"The Matrix Reloaded","release_date":"2003-05-15","vote_average":7.0,"vote_count":10000},
+			{"id":999,"title":"Untitled Sequel","vote_average":0,"vote_count":0}]}`,
+		"/movie/603": `{"id":603,"title":"The Matrix","runtime":136}`,
+		"/movie/604": `{"id":604,"title":"The Matrix Reloaded","runtime":138}`,
+		"/movie/999": `{"id":999,"title":"Untitled Sequel","runtime":0}`,
+	})
+	app := newTestApp(t, fake)
+
+	rec := get(app, "/tonight?minutes=300&seed=7")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"Total: 274 of 300 min",
+		`href="/movie/the-matrix-603"`,
+		`href="/movie/the-matrix-reloaded-604"`,
+		`Left out because their runtime isn't known: <span dir="auto">Untitled Sequel</span>`,
+		"seed=8",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+
+	// Known runtimes are cached; the unknown one is asked for again.
+	if again := get(app, "/tonight?minutes=300&seed=7").Body.String(); again != body {
+		t.Error("the same seed gave a different page")
+	}
+	for path, want := range map[string]int{"/movie/603": 1, "/movie/604": 1, "/movie/999": 2} {
+		if calls := fake.calls(path); calls != want {
+			t.Errorf("%d calls for %s, want %d", calls, path, want)
+		}
+	}
+}
+
+func TestTonightBadRequests(t *testing.T) {
+	app := newTestApp(t, newFakeTMDB(t, nil))
+	for _, target := range []string{
+		"/tonight?minutes=0",
+		"/tonight?minutes=721",
+		"/tonight?minutes=two",
+		"/tonight?minutes=90&from=12345",
+		"/tonight?minutes=90&seed=-1",
+		"/tonight?minutes=90&minutes=120",
+	} {
+		if rec := get(app, target); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLBuilder produces the canonical absolute URLs of our pages, for every
+// place a link leaves the server (sitemap, widget feeds, ...). In-page links
+// stay root-relative. Paths come from the same helpers the router and
+// templates use, such as movieURL, so the URL scheme is defined once.
+type URLBuilder struct {
+	base string // scheme://host[:port][/path], without a trailing slash
+}
+
+// NewURLBuilder validates the external base URL (BASE_URL). It may carry a
+// path when the app is served below a prefix. Default ports are dropped so
+// equivalent settings produce identical URLs.
+func NewURLBuilder(baseURL string) (URLBuilder, error) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil {
+		return URLBuilder{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return URLBuilder{}, fmt.Errorf("%q must start with http:// or https://", baseURL)
+	}
+	if u.Host == "" {
+		return URLBuilder{}, fmt.Errorf("%q has no host", baseURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return URLBuilder{}, fmt.Errorf("%q must not have a query or fragment", baseURL)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	return URLBuilder{base: u.Scheme + "://" + host + strings.TrimSuffix(u.EscapedPath(), "/")}, nil
+}
+
+// Absolute turns a root-relative path such as "/movie/the-matrix-603" into
+// an absolute URL.
+func (b URLBuilder) Absolute(path string) string {
+	return b.base + path
+}
+
+// Movie is the canonical URL of a movie's detail page.
+func (b URLBuilder) Movie(id int, title string) string {
+	return b.Absolute(movieURL(id, title))
+}
+
+// ParseMovie returns the movie ID of rawURL if it is the canonical URL of
+// a movie's detail page, or of a bare-ID or outdated-slug variant that
+// redirects there. A query or fragment is ignored.
+func (b URLBuilder) ParseMovie(rawURL string) (int, bool) {
+	rawURL, _, _ = strings.Cut(rawURL, "#")
+	rawURL, _, _ = strings.Cut(rawURL, "?")
+	slug, ok := strings.CutPrefix(rawURL, b.Absolute("/movie/"))
+	if !ok || slug == "" || strings.Contains(slug, "/") {
+		return 0, false
+	}
+	slug, err := url.PathUnescape(slug)
+	if err != nil {
+		return 0, false
+	}
+	id, err := movieIDFromSlug(slug)
+	return id, err == nil
+}
+
+// Person is the canonical URL of a person page.
+func (b URLBuilder) Person(id int) string {
+	return b.Absolute(fmt.Sprintf("/person/%d", id))
+}
+
+// Collection is the canonical URL of a collection page.
+func (b URLBuilder) Collection(id int) string {
+	return b.Absolute(fmt.Sprintf("/collection/%d", id))
+}
+
+// Best is the canonical URL of a "Best of {year}" page.
+func (b URLBuilder) Best(year int) string {
+	return b.Absolute(fmt.Sprintf("/best/%d", year))
+}
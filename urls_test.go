@@ -0,0 +1,103 @@
+package main
+
+import "testing"
+
+func TestNewURLBuilder(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string // the movie URL of The Matrix, "" for an invalid base
+	}{
+		{"http://localhost:8080", "http://localhost:8080/movie/the-matrix-603"},
+		{"https://movies.example", "https://movies.example/movie/the-matrix-603"},
+		{"https://movies.example/", "https://movies.example/movie/the-matrix-603"},
+		{"  https://movies.example  ", "https://movies.example/movie/the-matrix-603"},
+		{"https://Movies.Example", "https://movies.example/movie/the-matrix-603"},
+		{"https://example.com/films", "https://example.com/films/movie/the-matrix-603"},
+		{"https://example.com/films/", "https://example.com/films/movie/the-matrix-603"},
+		{"https://example.com/my films", "https://example.com/my%20films/movie/the-matrix-603"},
+		{"https://movies.example:443", "https://movies.example/movie/the-matrix-603"},
+		{"http://movies.example:80", "http://movies.example/movie/the-matrix-603"},
+		{"https://movies.example:8443/", "https://movies.example:8443/movie/the-matrix-603"},
+		{"http://movies.example:443", "http://movies.example:443/movie/the-matrix-603"},
+		{"http://[::1]:8080", "http://[::1]:8080/movie/the-matrix-603"},
+		{"movies.example", ""},
+		{"ftp://movies.example", ""},
+		{"https://", ""},
+		{"https://movies.example/?lang=en", ""},
+		{"https://movies.example/#top", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		b, err := NewURLBuilder(tt.baseURL)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("NewURLBuilder(%q) accepted an invalid base URL", tt.baseURL)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewURLBuilder(%q): %v", tt.baseURL, err)
+			continue
+		}
+		if got := b.Movie(603, "The Matrix"); got != tt.want {
+			t.Errorf("NewURLBuilder(%q).Movie = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestURLBuilderPages(t *testing.T) {
+	b, err := NewURLBuilder("https://example.com:8443/films/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		got, want string
+	}{
+		{b.Absolute("/"), "https://example.com:8443/films/"},
+		{b.Movie(194, "Amélie"), "https://example.com:8443/films/movie/am%C3%A9lie-194"},
+		{b.Movie(129, "千と千尋の神隠し"), "https://example.com:8443/films/movie/%E5%8D%83%E3%81%A8%E5%8D%83%E5%B0%8B%E3%81%AE%E7%A5%9E%E9%9A%A0%E3%81%97-129"},
+		{b.Movie(1, "100% Love?/#1"), "https://example.com:8443/films/movie/100-love-1-1"},
+		{b.Movie(2, ""), "https://example.com:8443/films/movie/2"},
+		{b.Person(6384), "https://example.com:8443/films/person/6384"},
+		{b.Collection(2344), "https://example.com:8443/films/collection/2344"},
+		{b.Best(1999), "https://example.com:8443/films/best/1999"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestURLBuilderParseMovie(t *testing.T) {
+	b, err := NewURLBuilder("https://example.com/films")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rawURL string
+		want   int // 0 when not a movie URL
+	}{
+		{"https://example.com/films/movie/the-matrix-603", 603},
+		{"https://example.com/films/movie/603", 603},
+		{"https://example.com/films/movie/old-title-603", 603},
+		{"https://example.com/films/movie/am%C3%A9lie-194", 194},
+		{"https://example.com/films/movie/amélie-194", 194},
+		{"https://example.com/films/movie/the-matrix-603?from_search=a%26b", 603},
+		{"https://example.com/films/movie/the-matrix-603#cast", 603},
+		{"https://example.com/films/movie/the-matrix-603?q=1#x", 603},
+		{"https://example.com/movie/the-matrix-603", 0},
+		{"https://other.example/films/movie/the-matrix-603", 0},
+		{"https://example.com/films/movie/", 0},
+		{"https://example.com/films/movie/the-matrix", 0},
+		{"https://example.com/films/movie/the-matrix-603/credits", 0},
+		{"https://example.com/films/movie/%zz-603", 0},
+		{"https://example.com/films/person/6384", 0},
+	}
+	for _, tt := range tests {
+		id, ok := b.ParseMovie(tt.rawURL)
+		if ok != (tt.want != 0) || id != tt.want {
+			t.Errorf("ParseMovie(%q) = %d, %v, want %d", tt.rawURL, id, ok, tt.want)
+		}
+	}
+}
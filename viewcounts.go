@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultViewCountsFlushInterval is how often view counts are written to
+// VIEW_COUNTS_FILE unless VIEW_COUNTS_FLUSH_INTERVAL says otherwise.
+const defaultViewCountsFlushInterval = time.Minute
+
+// ViewCounter counts detail page views per movie in memory and persists
+// them to a JSON file of movie ID to count. It is safe for concurrent use.
+type ViewCounter struct {
+	path string
+
+	// flushMu makes each flush take its snapshot and write it before the
+	// next flush takes one, so an older snapshot never overwrites a newer.
+	flushMu sync.Mutex
+
+	mu     sync.Mutex
+	counts map[int]int64
+	dirty  bool // counts changed since the last flush
+}
+
+// loadViewCounter reads the counts saved at path. A missing file starts
+// every count at zero.
+func loadViewCounter(path string) (*ViewCounter, error) {
+	counter := &ViewCounter{path: path, counts: map[int]int64{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return counter, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved map[string]int64
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	for key, count := range saved {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, errors.New(path + ": " + strconv.Quote(key) + " is not a movie ID")
+		}
+		counter.counts[id] = count
+	}
+	return counter, nil
+}
+
+// Increment records one more view of the movie and returns its new count.
+func (c *ViewCounter) Increment(movieID int) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[movieID]++
+	c.dirty = true
+	return c.counts[movieID]
+}
+
+// Flush writes the counts to the file if they changed since the last flush.
+// The file is replaced atomically, so a crash mid-write keeps the previous
+// counts.
+func (c *ViewCounter) Flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	saved := make(map[string]int64, len(c.counts))
+	for id, count := range c.counts {
+		saved[strconv.Itoa(id)] = count
+	}
+	c.dirty = false
+	c.mu.Unlock()
+
+	data, err := json.Marshal(saved)
+	if err == nil {
+		err = writeFileAtomic(c.path, data)
+	}
+	if err != nil {
+		// Try again on the next flush.
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// flushEvery flushes the counts every interval until stop is closed.
+// App.Close flushes once more after that; views since the last flush are
+// lost if the process is killed outright.
+func (c *ViewCounter) flushEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(); err != nil {
+				log.Printf("Error saving view counts: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestViewCounterRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+	counter, err := loadViewCounter(path)
+	if err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+	if err := counter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("a flush without views wrote the file")
+	}
+
+	for i, want := range []int64{1, 2, 3} {
+		if got := counter.Increment(603); got != want {
+			t.Errorf("view %d of 603 = %d, want %d", i+1, got, want)
+		}
+	}
+	counter.Increment(604)
+	if err := counter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"603":3,"604":1}` {
+		t.Errorf("saved %s", data)
+	}
+
+	reloaded, err := loadViewCounter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Increment(603); got != 4 {
+		t.Errorf("603 after reloading = %d, want 4", got)
+	}
+}
+
+func TestLoadViewCounterInvalidFile(t *testing.T) {
+	for name, content := range map[string]string{
+		"corrupt":        `{"603":`,
+		"not a movie ID": `{"the-matrix":3}`,
+		"wrong type":     `[603]`,
+	} {
+		path := filepath.Join(t.TempDir(), "views.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadViewCounter(path); err == nil {
+			t.Errorf("%s: loaded %s", name, content)
+		}
+	}
+}
+
+func TestViewCounterFlushRetries(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "later")
+	counter, err := loadViewCounter(filepath.Join(dir, "views.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.Increment(603)
+	if err := counter.Flush(); err == nil {
+		t.Fatal("flush into a missing directory succeeded")
+	}
+
+	// The failed flush leaves the counts to save on the next one.
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := counter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "views.json")); string(data) != `{"603":1}` {
+		t.Errorf("saved %s", data)
+	}
+}
+
+func TestViewCounterConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+	counter, err := loadViewCounter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const viewers, views = 8, 200
+	var wg sync.WaitGroup
+	for i := 0; i < viewers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < views; j++ {
+				counter.Increment(603)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < views/20; j++ {
+				if err := counter.Flush(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := counter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := loadViewCounter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Increment(603) - 1; got != viewers*views {
+		t.Errorf("saved %d views, want %d", got, viewers*views)
+	}
+}
+
+func TestAppCloseSavesViewCounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+	app, err := NewApp(Config{ViewCountsFile: path, ViewCountsFlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.config.ViewCounts.Increment(603)
+	if err := app.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"603":1}` {
+		t.Errorf("saved %s", data)
+	}
+}
+
+func TestDetailPageCountsRevisits(t *testing.T) {
+	t.Setenv("VIEW_COUNTS_FILE", filepath.Join(t.TempDir(), "views.json"))
+	app := newTestApp(t, newFakeTMDB(t, map[string]string{"/movie/603": movieMatrixJSON}))
+
+	first := get(app, "/movie/the-matrix-603")
+	if !strings.Contains(first.Body.String(), "Viewed 1 time on this site") {
+		t.Fatal("first view isn't counted")
+	}
+
+	// The count changed, so the browser's copy is stale.
+	req := httptest.NewRequest(http.MethodGet, "/movie/the-matrix-603", nil)
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	revisit := httptest.NewRecorder()
+	app.ServeHTTP(revisit, req)
+	if revisit.Code != http.StatusOK || !strings.Contains(revisit.Body.String(), "Viewed 2 times on this site") {
+		t.Fatalf("revisit = %d, want 200 with the new count", revisit.Code)
+	}
+	if revisit.Header().Get("ETag") == first.Header().Get("ETag") {
+		t.Error("the ETag didn't change with the count")
+	}
+
+	if third := get(app, "/movie/the-matrix-603").Body.String(); !strings.Contains(third, "Viewed 3 times on this site") {
+		t.Error("the revisit isn't counted")
+	}
+}
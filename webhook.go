@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds each webhook delivery so a slow endpoint can't pile up goroutines.
+	webhookTimeout = 5 * time.Second
+	// webhookMinInterval is the minimum gap between two deliveries; errors in between are
+	// counted and reported with the next delivery instead.
+	webhookMinInterval = 10 * time.Second
+	// maxReportedErrorLength caps how much of the response body is sent as the error text.
+	maxReportedErrorLength = 500
+)
+
+// ErrorReport is the JSON payload POSTed to the error webhook.
+type ErrorReport struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	Error      string    `json:"error"`
+	RequestID  string    `json:"request_id,omitempty"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	Suppressed int       `json:"suppressed,omitempty"` // errors dropped by rate limiting since the last report
+}
+
+// errorReporter sends ErrorReports to a webhook, at most once per webhookMinInterval.
+type errorReporter struct {
+	url    string
+	client *http.Client
+
+	mu         sync.Mutex
+	lastSent   time.Time
+	suppressed int
+}
+
+func newErrorReporter(webhookURL string) *errorReporter {
+	return &errorReporter{
+		url:    webhookURL,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Report sends the report in the background. It never blocks the caller.
+func (e *errorReporter) Report(report ErrorReport) {
+	e.mu.Lock()
+	if time.Since(e.lastSent) < webhookMinInterval {
+		e.suppressed++
+		e.mu.Unlock()
+		return
+	}
+	e.lastSent = time.Now()
+	report.Suppressed = e.suppressed
+	e.suppressed = 0
+	e.mu.Unlock()
+
+	go e.send(report)
+}
+
+func (e *errorReporter) send(report ErrorReport) {
+	payload, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("Error encoding error report: %v", err)
+		return
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Printf("Error sending error report: %v", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		log.Printf("Error webhook responded with %s", resp.Status)
+	}
+}
+
+// statusRecorder remembers the status code and, for server errors, the
+// start of the body so it can be reported.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	if rec.status >= 500 && rec.body.Len() < maxReportedErrorLength {
+		rec.body.Write(b[:min(len(b), maxReportedErrorLength-rec.body.Len())])
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// errorReportingMiddleware reports 5xx responses and panics to the webhook.
+// Panics are re-raised after reporting so RecoveryMiddleware still answers
+// them.
+func errorReportingMiddleware(next http.Handler, reporter *errorReporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			if p := recover(); p != nil {
+				reporter.Report(newErrorReport(r, http.StatusInternalServerError, fmt.Sprintf("panic: %v", p)))
+				panic(p)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= 500 {
+			reporter.Report(newErrorReport(r, rec.status, strings.TrimSpace(rec.body.String())))
+		}
+	})
+}
+
+func newErrorReport(r *http.Request, status int, message string) ErrorReport {
+	return ErrorReport{
+		Timestamp: time.Now().UTC(),
+		Path:      r.URL.Path,
+		Status:    status,
+		Error:     message,
+		RequestID: requestIDFromContext(r.Context()),
+		ClientIP:  clientIPFromContext(r.Context()),
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// webhookReceiver starts a server standing in for the error webhook and
+// returns a reporter pointed at it, with the reports it receives.
+func webhookReceiver(t *testing.T) (*errorReporter, <-chan ErrorReport) {
+	t.Helper()
+	reports := make(chan ErrorReport, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report ErrorReport
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("webhook got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("webhook payload: %v", err)
+		}
+		reports <- report
+	}))
+	t.Cleanup(receiver.Close)
+	return newErrorReporter(receiver.URL), reports
+}
+
+// nextReport waits for the webhook to receive a report.
+func nextReport(t *testing.T, reports <-chan ErrorReport) ErrorReport {
+	t.Helper()
+	select {
+	case report := <-reports:
+		return report
+	case <-time.After(time.Second):
+		t.Fatal("no report reached the webhook")
+		return ErrorReport{}
+	}
+}
+
+func TestErrorReportingMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		error   string // "" when nothing is reported
+	}{
+		{"server error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Bad Gateway: TMDB is down", http.StatusBadGateway)
+		}, http.StatusBadGateway, "Bad Gateway: TMDB is down"},
+		{"long error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, strings.Repeat("x", 2*maxReportedErrorLength), http.StatusInternalServerError)
+		}, http.StatusInternalServerError, strings.Repeat("x", maxReportedErrorLength)},
+		{"panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}, http.StatusInternalServerError, "panic: boom"},
+		{"not found", http.NotFound, http.StatusNotFound, ""},
+		{"ok", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "fine")
+		}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter, reports := webhookReceiver(t)
+			logger, _ := newCountingLogger()
+			// In the order newHandler wraps them.
+			handler := RequestIDMiddleware(ClientIPMiddleware(
+				RecoveryMiddleware(logger)(errorReportingMiddleware(tt.handler, reporter)), nil))
+
+			req := httptest.NewRequest(http.MethodGet, "/movie/the-matrix-603", nil)
+			req.Header.Set("X-Request-ID", "req-123")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			if tt.error == "" {
+				select {
+				case report := <-reports:
+					t.Errorf("reported %+v", report)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			report := nextReport(t, reports)
+			if report.Status != tt.status || report.Error != tt.error || report.Path != "/movie/the-matrix-603" {
+				t.Errorf("reported %d %q for %s, want %d %q", report.Status, report.Error, report.Path, tt.status, tt.error)
+			}
+			if report.RequestID != "req-123" || report.ClientIP != "192.0.2.1" || report.Timestamp.IsZero() {
+				t.Errorf("report = %+v, want the request ID, client IP and time", report)
+			}
+		})
+	}
+}
+
+func TestErrorReportingMiddlewareRepanics(t *testing.T) {
+	reporter, reports := webhookReceiver(t)
+	logger, logged := newCountingLogger()
+	handler := RecoveryMiddleware(logger)(errorReportingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), reporter))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	nextReport(t, reports)
+	if rec.Code != http.StatusInternalServerError || logged.count(slog.LevelError) != 1 {
+		t.Errorf("RecoveryMiddleware answered %d and logged %d errors, want 500 and 1", rec.Code, logged.count(slog.LevelError))
+	}
+}
+
+func TestErrorReporterRateLimit(t *testing.T) {
+	reporter, reports := webhookReceiver(t)
+	report := ErrorReport{Path: "/", Status: http.StatusBadGateway}
+
+	reporter.Report(report)
+	if got := nextReport(t, reports); got.Suppressed != 0 {
+		t.Errorf("first report suppressed %d", got.Suppressed)
+	}
+	reporter.Report(report)
+	reporter.Report(report)
+	select {
+	case got := <-reports:
+		t.Fatalf("reported %+v within webhookMinInterval", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	reporter.mu.Lock()
+	reporter.lastSent = time.Now().Add(-webhookMinInterval)
+	reporter.mu.Unlock()
+	reporter.Report(report)
+	if got := nextReport(t, reports); got.Suppressed != 2 {
+		t.Errorf("next report counts %d suppressed, want 2", got.Suppressed)
+	}
+}
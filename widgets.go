@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	trendingEndpoint = "/trending/movie/"
+
+	defaultWidgetLimit = 10
+	maxWidgetLimit     = 20
+)
+
+// WidgetItem is the compact, stable shape served to dashboard widgets.
+// Fields are only ever added, never renamed or removed.
+type WidgetItem struct {
+	Title  string  `json:"title"`
+	Year   string  `json:"year"`
+	Rating float64 `json:"rating"`
+	URL    string  `json:"url"`
+	Poster string  `json:"poster"`
+}
+
+// widgetTrendingHandler serves GET /api/widgets/trending?window=day|week&limit=N.
+func widgetTrendingHandler(w http.ResponseWriter, r *http.Request, config Config) {
+	// Widgets are embedded on other origins, so CORS is always on here.
+	w.Header().Set("Access-Control-Allow-Origin", config.WidgetCORSOrigin)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if rejectDuplicateAPIParams(w, r, "window", "limit") {
+		return
+	}
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = "day"
+	}
+	if window != "day" && window != "week" {
+		writeAPIError(w, http.StatusBadRequest, apiBadRequest, "window must be day or week")
+		return
+	}
+
+	limit := defaultWidgetLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			writeAPIError(w, http.StatusBadRequest, apiBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = min(n, maxWidgetLimit)
+	}
+
+	start := time.Now()
+	trending, err := fetchTrending(r.Context(), window, config.TMDB)
+	RecordTiming(r.Context(), "tmdb_trending", start)
+	if err != nil {
+		log.Printf("Error fetching trending movies: %v", err)
+		tmdbAPIFailure(w, err, "Failed to fetch trending movies")
+		return
+	}
+
+	items := []WidgetItem{}
+	for _, movie := range trending.Results {
+		if len(items) == limit {
+			break
+		}
+		items = append(items, WidgetItem{
+			Title:  movie.Title,
+			Year:   movie.ReleaseDate.Year(),
+			Rating: movie.VoteAverage,
+			URL:    config.URLs.Movie(movie.ID, movie.Title),
+			Poster: imageURL("w342", movie.PosterPath),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setCacheControl(w, config, widgetResponse)
+	setSurrogateKeys(w, moviesSurrogateKeys("trending-"+window, trending.Results[:len(items)])...)
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		log.Printf("Error encoding widget feed: %v", err)
+	}
+}
+
+func fetchTrending(ctx context.Context, window string, tmdb *TMDB) (*SearchResults, error) {
+	requestURL := fmt.Sprintf("%s%s%s?api_key=%s", tmdb.BaseURL, trendingEndpoint, window, tmdb.APIKey)
+	var results SearchResults
+	if err := tmdbGet(ctx, tmdb, requestURL, &results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}